@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,7 +18,11 @@ type MetricMaker interface {
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- telegraf.Metric
+	route     *route
 	precision time.Duration
+
+	// backpressure pauses tracking metrics while output buffers are full
+	backpressure *backpressure
 }
 
 func NewAccumulator(
@@ -31,6 +37,56 @@ func NewAccumulator(
 	return &acc
 }
 
+// newRoutedAccumulator returns an accumulator writing to the current
+// destination of the given route.
+func newRoutedAccumulator(maker MetricMaker, r *route) telegraf.Accumulator {
+	acc := accumulator{
+		maker:     maker,
+		route:     r,
+		precision: time.Nanosecond,
+	}
+	return &acc
+}
+
+// newServiceAccumulator returns an accumulator for a service input where the
+// delivery of tracking metrics is paused by the given backpressure, if any.
+func newServiceAccumulator(maker MetricMaker, metrics chan<- telegraf.Metric, bp *backpressure) telegraf.Accumulator {
+	acc := accumulator{
+		maker:        maker,
+		metrics:      metrics,
+		precision:    time.Nanosecond,
+		backpressure: bp,
+	}
+	return &acc
+}
+
+// route is a destination channel for metrics that can be changed while
+// metrics are sent to it.
+type route struct {
+	sync.RWMutex
+	dst chan<- telegraf.Metric
+}
+
+// send blocks until the metric is written to the current destination.
+func (r *route) send(m telegraf.Metric) {
+	r.RLock()
+	r.dst <- m
+	r.RUnlock()
+}
+
+func (r *route) get() chan<- telegraf.Metric {
+	r.RLock()
+	defer r.RUnlock()
+	return r.dst
+}
+
+// set changes the destination after all ongoing sends are finished.
+func (r *route) set(dst chan<- telegraf.Metric) {
+	r.Lock()
+	r.dst = dst
+	r.Unlock()
+}
+
 func (ac *accumulator) AddFields(
 	measurement string,
 	fields map[string]interface{},
@@ -79,7 +135,7 @@ func (ac *accumulator) AddHistogram(
 func (ac *accumulator) AddMetric(m telegraf.Metric) {
 	m.SetTime(m.Time().Round(ac.precision))
 	if m := ac.maker.MakeMetric(m); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
@@ -92,10 +148,18 @@ func (ac *accumulator) addMeasurement(
 ) {
 	m := metric.New(measurement, tags, fields, ac.getTime(t), tp)
 	if m := ac.maker.MakeMetric(m); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
+func (ac *accumulator) send(m telegraf.Metric) {
+	if ac.route != nil {
+		ac.route.send(m)
+		return
+	}
+	ac.metrics <- m
+}
+
 // AddError passes a runtime error to the accumulator.
 // The error will be tagged with the plugin name and written to the log.
 func (ac *accumulator) AddError(err error) {
@@ -121,23 +185,29 @@ func (ac *accumulator) getTime(t []time.Time) time.Time {
 
 func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
 	return &trackingAccumulator{
-		Accumulator: ac,
-		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+		Accumulator:  ac,
+		delivered:    make(chan telegraf.DeliveryInfo, maxTracked),
+		maxTracked:   maxTracked,
+		backpressure: ac.backpressure,
 	}
 }
 
 type trackingAccumulator struct {
 	telegraf.Accumulator
-	delivered chan telegraf.DeliveryInfo
+	delivered    chan telegraf.DeliveryInfo
+	maxTracked   int
+	backpressure *backpressure
 }
 
 func (a *trackingAccumulator) AddTrackingMetric(m telegraf.Metric) telegraf.TrackingID {
+	a.backpressure.wait(a.maxTracked)
 	dm, id := metric.WithTracking(m, a.onDelivery)
 	a.AddMetric(dm)
 	return id
 }
 
 func (a *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	a.backpressure.wait(a.maxTracked)
 	db, id := metric.WithGroupTracking(group, a.onDelivery)
 	for _, m := range db {
 		a.AddMetric(m)
@@ -158,3 +228,80 @@ func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
 		panic("channel is full")
 	}
 }
+
+// abandonableAccumulator passes metrics to the underlying accumulator until
+// the context of the gather using it is done. Metrics added by a cancelled or
+// abandoned gather are dropped.
+type abandonableAccumulator struct {
+	telegraf.Accumulator
+	ctx context.Context
+
+	// Held for reading while passing on metrics so abandoning the gather
+	// waits for ongoing sends before the destination might be closed
+	sync.RWMutex
+	abandoned bool
+}
+
+// abandon drops all metrics added from now on, waiting for ongoing sends.
+func (ac *abandonableAccumulator) abandon() {
+	ac.Lock()
+	ac.abandoned = true
+	ac.Unlock()
+}
+
+// pass calls the function adding a metric unless the gather was abandoned
+// and returns if the metric was passed on.
+func (ac *abandonableAccumulator) pass(add func()) bool {
+	ac.RLock()
+	defer ac.RUnlock()
+
+	if ac.abandoned || ac.ctx.Err() != nil {
+		return false
+	}
+	add()
+	return true
+}
+
+func (ac *abandonableAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	ac.pass(func() { ac.Accumulator.AddFields(measurement, fields, tags, t...) })
+}
+
+func (ac *abandonableAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	ac.pass(func() { ac.Accumulator.AddGauge(measurement, fields, tags, t...) })
+}
+
+func (ac *abandonableAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	ac.pass(func() { ac.Accumulator.AddCounter(measurement, fields, tags, t...) })
+}
+
+func (ac *abandonableAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	ac.pass(func() { ac.Accumulator.AddSummary(measurement, fields, tags, t...) })
+}
+
+func (ac *abandonableAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	ac.pass(func() { ac.Accumulator.AddHistogram(measurement, fields, tags, t...) })
+}
+
+func (ac *abandonableAccumulator) AddMetric(m telegraf.Metric) {
+	// Reject the metrics of an abandoned gather so tracking metrics are
+	// reported as undelivered to their source
+	if !ac.pass(func() { ac.Accumulator.AddMetric(m) }) {
+		m.Reject()
+	}
+}
+
+// WithTracking returns a tracking accumulator adding its metrics through the
+// abandonable accumulator, so tracking metrics of an abandoned gather are
+// rejected as well.
+func (ac *abandonableAccumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	var bp *backpressure
+	if inner, ok := ac.Accumulator.(*accumulator); ok {
+		bp = inner.backpressure
+	}
+	return &trackingAccumulator{
+		Accumulator:  ac,
+		delivered:    make(chan telegraf.DeliveryInfo, maxTracked),
+		maxTracked:   maxTracked,
+		backpressure: bp,
+	}
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"os"
 	"testing"
@@ -140,6 +141,70 @@ func TestAddTrackingMetricGroupEmpty(t *testing.T) {
 	}
 }
 
+func TestAbandonableAccumulatorTracking(t *testing.T) {
+	ch := make(chan telegraf.Metric, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	abandonable := &abandonableAccumulator{Accumulator: NewAccumulator(&TestMetricMaker{}, ch), ctx: ctx}
+	acc := abandonable.WithTracking(1)
+
+	// Tracking metrics are passed on until the gather is abandoned
+	acc.AddTrackingMetric(testutil.TestMetric(1))
+	m := <-ch
+	m.Accept()
+	require.True(t, (<-acc.Delivered()).Delivered())
+
+	cancel()
+	abandonable.abandon()
+	id := acc.AddTrackingMetric(testutil.TestMetric(2))
+	require.Empty(t, ch)
+	info := <-acc.Delivered()
+	require.Equal(t, id, info.ID())
+	require.False(t, info.Delivered())
+}
+
+func TestAbandonableAccumulatorWaitsForSend(t *testing.T) {
+	ch := make(chan telegraf.Metric)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	maker := &sendingMetricMaker{making: make(chan struct{})}
+	acc := &abandonableAccumulator{Accumulator: NewAccumulator(maker, ch), ctx: ctx}
+
+	// Start a send blocking on the destination
+	go acc.AddFields("test", map[string]interface{}{"value": 42}, nil)
+	<-maker.making
+
+	// Abandoning waits for the ongoing send to finish
+	abandoned := make(chan struct{})
+	go func() {
+		cancel()
+		acc.abandon()
+		close(abandoned)
+	}()
+	select {
+	case <-abandoned:
+		t.Fatal("abandoned during an ongoing send")
+	case <-time.After(50 * time.Millisecond):
+	}
+	m := <-ch
+	require.Equal(t, "test", m.Name())
+	<-abandoned
+
+	// Closing the destination is safe once abandoned
+	close(ch)
+	acc.AddFields("late", map[string]interface{}{"value": 42}, nil)
+}
+
+// sendingMetricMaker signals each metric made before it is sent.
+type sendingMetricMaker struct {
+	TestMetricMaker
+	making chan struct{}
+}
+
+func (tm *sendingMetricMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	tm.making <- struct{}{}
+	return metric
+}
+
 type TestMetricMaker struct {
 }
 
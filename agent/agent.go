@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"runtime"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -17,14 +17,22 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/clock"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/persister"
 	"github.com/influxdata/telegraf/plugins/common/snmp"
-	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
+// drainRetryInterval is the delay between writes of an output failing to
+// write its remaining metrics on shutdown if the output has no backoff
+const drainRetryInterval = time.Second
+
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// units of the running agent used to apply configuration changes
+	units     *runningUnits
+	unitsLock sync.Mutex
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -35,20 +43,29 @@ func NewAgent(cfg *config.Config) *Agent {
 	return a
 }
 
-// inputUnit is a group of input plugins and the shared channel they write to.
+// inputUnit is a group of input plugins and the router passing their metrics
+// to the pipelines.
 //
 // ┌───────┐
 // │ Input │───┐
 // └───────┘   │
-// ┌───────┐   │     ______
-// │ Input │───┼──▶ ()_____)
-// └───────┘   │
+// ┌───────┐   │    ┌────────┐
+// │ Input │───┼──▶ │ Router │
+// └───────┘   │    └────────┘
 // ┌───────┐   │
 // │ Input │───┘
 // └───────┘
 type inputUnit struct {
-	dst    chan<- telegraf.Metric
+	router *pipelineRouter
 	inputs []*models.RunningInput
+
+	sync.Mutex
+	runners map[*models.RunningInput]*pluginRunner
+	wg      sync.WaitGroup
+
+	// backpressure of the service inputs if pausing on full output buffers
+	// is enabled
+	backpressure map[*models.RunningInput]*backpressure
 }
 
 //  ______     ┌───────────┐     ______
@@ -56,9 +73,37 @@ type inputUnit struct {
 //             └───────────┘
 
 type processorUnit struct {
-	src       <-chan telegraf.Metric
-	dst       chan<- telegraf.Metric
+	src       chan telegraf.Metric
+	dst       *route
 	processor *models.RunningProcessor
+	done      chan struct{}
+
+	// Settings applied when the unit's source channel is closed during a
+	// rebuild of the processor chain. Kept processors are rewired to the
+	// 'next' channel instead of being stopped.
+	keep     bool
+	next     chan<- telegraf.Metric
+	closeDst bool
+}
+
+// processorChain is an ordered list of processor units with a stable source
+// and destination channel. The feeder passes metrics from the chain's source
+// to the first processor and allows to exchange the processors without
+// affecting the units up- or downstream of the chain.
+//
+//  ______     ┌────────┐     ┌───────────┐           ┌───────────┐     ______
+// ()_____)──▶ │ Feeder │──▶ │ Processor │──▶ ... ──▶ │ Processor │──▶ ()_____)
+//             └────────┘     └───────────┘           └───────────┘
+
+type processorChain struct {
+	src   <-chan telegraf.Metric
+	dst   chan<- telegraf.Metric
+	entry chan<- telegraf.Metric
+	units []*processorUnit
+
+	updates chan *chainUpdate
+	done    chan struct{}
+	wg      sync.WaitGroup
 }
 
 // aggregatorUnit is a group of Aggregators and their source and sink channels.
@@ -82,6 +127,16 @@ type aggregatorUnit struct {
 	aggC        chan<- telegraf.Metric
 	outputC     chan<- telegraf.Metric
 	aggregators []*models.RunningAggregator
+
+	sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	runners map[*models.RunningAggregator]*pluginRunner
+	wg      sync.WaitGroup
+
+	// persistent is set if the states of the aggregators are stored on
+	// shutdown
+	persistent bool
 }
 
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
@@ -100,6 +155,36 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	runners map[*models.RunningOutput]*pluginRunner
+	wg      sync.WaitGroup
+}
+
+// pluginRunner controls the goroutine running a single plugin of a unit.
+type pluginRunner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// flush requests an immediate flush of an output
+	flush chan struct{}
+}
+
+// requestFlush triggers a flush of the output without blocking if a flush
+// is already pending.
+func (r *pluginRunner) requestFlush() {
+	select {
+	case r.flush <- struct{}{}:
+	default:
+	}
+}
+
+// stop cancels the runner and waits for the goroutine to finish.
+func (r *pluginRunner) stop() {
+	r.cancel()
+	<-r.done
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -136,76 +221,86 @@ func (a *Agent) Run(ctx context.Context) error {
 		}
 	}
 
-	startTime := time.Now()
-
-	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
-	if err != nil {
-		return err
+	if a.Config.Agent.ManagementServiceAddress != "" {
+		ms, err := a.startManagementServer()
+		if err != nil {
+			return err
+		}
+		defer ms.stop()
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
+	if a.Config.Agent.MetricsServiceAddress != "" {
+		ss, err := a.startSelfMetricsServer()
+		if err != nil {
+			return err
 		}
-
-		next, au = a.startAggregators(aggC, next, a.Config.Aggregators)
+		defer ss.stop()
 	}
 
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
+	if a.Config.Agent.TracingEndpoint != "" {
+		tp, err := a.startTracing()
 		if err != nil {
 			return err
 		}
+		defer stopTracing(tp)
 	}
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
+	startTime := time.Now()
+
+	log.Printf("D! [agent] Connecting outputs")
+	pipelines, err := a.startPipelines(func(p *pipelineUnit) (chan<- telegraf.Metric, error) {
+		src, ou, err := a.startOutputs(ctx, pipelineOutputs(a.Config.Outputs, p.name()))
+		p.outputs = ou
+		return src, err
+	})
+	if err != nil {
+		return err
+	}
+
+	iu, err := a.startInputs(newPipelineRouter(pipelines), a.Config.Inputs)
 	if err != nil {
 		return err
 	}
 
+	secrets := newSecretWatcher(a.Config.SecretStores, time.Duration(a.Config.Agent.SecretRefreshInterval), a.secretPlugins)
+
+	a.setRunningUnits(&runningUnits{
+		ctx:          ctx,
+		startTime:    startTime,
+		inputs:       iu,
+		pipelineUnit: pipelines[0],
+		pipelines:    pipelines[1:],
+		secrets:      secrets,
+	})
+	defer a.setRunningUnits(nil)
+
 	var wg sync.WaitGroup
+	for _, p := range pipelines {
+		a.runPipeline(&wg, startTime, p)
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		a.runOutputs(ou)
+		a.runInputs(ctx, startTime, iu)
 	}()
 
-	if au != nil {
+	if a.Config.Persister != nil && a.Config.Agent.StatefileInterval > 0 {
 		wg.Add(1)
-		go func() {
+		go func(p *persister.Persister, interval time.Duration) {
 			defer wg.Done()
-			a.runProcessors(apu)
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
+			checkpointStates(ctx, p, interval)
+		}(a.Config.Persister, time.Duration(a.Config.Agent.StatefileInterval))
 	}
 
-	if pu != nil {
+	if secrets != nil {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			a.runProcessors(pu)
+			secrets.run(ctx)
 		}()
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runInputs(ctx, startTime, iu)
-	}()
-
 	wg.Wait()
 
 	if a.Config.Persister != nil {
@@ -267,7 +362,7 @@ func (a *Agent) initPersister() error {
 	}
 
 	for _, input := range a.Config.Inputs {
-		plugin, ok := input.Input.(telegraf.StatefulPlugin)
+		plugin, ok := input.StatefulPlugin()
 		if !ok {
 			continue
 		}
@@ -280,17 +375,9 @@ func (a *Agent) initPersister() error {
 	}
 
 	for _, processor := range a.Config.Processors {
-		var plugin telegraf.StatefulPlugin
-		if p, ok := processor.Processor.(processors.HasUnwrap); ok {
-			plugin, ok = p.Unwrap().(telegraf.StatefulPlugin)
-			if !ok {
-				continue
-			}
-		} else {
-			plugin, ok = processor.Processor.(telegraf.StatefulPlugin)
-			if !ok {
-				continue
-			}
+		plugin, ok := processor.StatefulPlugin()
+		if !ok {
+			continue
 		}
 
 		name := processor.LogName()
@@ -301,20 +388,21 @@ func (a *Agent) initPersister() error {
 	}
 
 	for _, aggregator := range a.Config.Aggregators {
-		plugin, ok := aggregator.Aggregator.(telegraf.StatefulPlugin)
-		if !ok {
+		// Register the running aggregator to also persist the current
+		// aggregation window
+		if !aggregator.Stateful() {
 			continue
 		}
 
 		name := aggregator.LogName()
 		id := aggregator.ID()
-		if err := a.Config.Persister.Register(id, plugin); err != nil {
+		if err := a.Config.Persister.Register(id, aggregator); err != nil {
 			return fmt.Errorf("could not register aggregator %s: %w", name, err)
 		}
 	}
 
 	for _, processor := range a.Config.AggProcessors {
-		plugin, ok := processor.Processor.(telegraf.StatefulPlugin)
+		plugin, ok := processor.StatefulPlugin()
 		if !ok {
 			continue
 		}
@@ -327,7 +415,7 @@ func (a *Agent) initPersister() error {
 	}
 
 	for _, output := range a.Config.Outputs {
-		plugin, ok := output.Output.(telegraf.StatefulPlugin)
+		plugin, ok := output.StatefulPlugin()
 		if !ok {
 			continue
 		}
@@ -342,30 +430,36 @@ func (a *Agent) initPersister() error {
 	return nil
 }
 
-func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) (*inputUnit, error) {
+// checkpointStates periodically stores the states of the plugins until the
+// context is done.
+func checkpointStates(ctx context.Context, p *persister.Persister, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Printf("D! [agent] Checkpointing plugin states")
+			if err := p.Store(); err != nil {
+				log.Printf("E! [agent] Checkpointing plugin states failed: %v", err)
+			}
+		}
+	}
+}
+
+func (a *Agent) startInputs(router *pipelineRouter, inputs []*models.RunningInput) (*inputUnit, error) {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		router:       router,
+		runners:      make(map[*models.RunningInput]*pluginRunner, len(inputs)),
+		backpressure: make(map[*models.RunningInput]*backpressure),
 	}
 
 	for _, input := range inputs {
-		// Service input plugins are not normally subject to timestamp
-		// rounding except for when precision is set on the input plugin.
-		//
-		// This only applies to the accumulator passed to Start(), the
-		// Gather() accumulator does apply rounding according to the
-		// precision and interval agent/plugin settings.
-		var interval time.Duration
-		var precision time.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(getPrecision(precision, interval))
-
-		if err := input.Start(acc); err != nil {
+		if err := startInput(router.route(input), a.inputBackpressure(unit, input), input); err != nil {
 			// If the model tells us to remove the plugin we do so without error
 			var fatalErr *internal.FatalError
 			if errors.As(err, &fatalErr) {
@@ -389,12 +483,71 @@ func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningIn
 	return unit, nil
 }
 
+// inputBackpressure returns the backpressure for the given input if the
+// agent pauses inputs on full output buffers and nil otherwise.
+func (a *Agent) inputBackpressure(unit *inputUnit, input *models.RunningInput) *backpressure {
+	if a.Config.Agent.BufferFullBehavior != "pause" {
+		return nil
+	}
+	bp := newBackpressure(input, func() []*models.RunningOutput { return unit.router.outputs(input) })
+	unit.backpressure[input] = bp
+	return bp
+}
+
+// startInput calls Start on the given input writing to the destination. The
+// delivery of tracking metrics is paused by the backpressure, if any.
+func startInput(dst chan<- telegraf.Metric, bp *backpressure, input *models.RunningInput) error {
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := newServiceAccumulator(input, dst, bp)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	return input.Start(acc)
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
 // after all ongoing Gather calls complete.
 func (a *Agent) runInputs(ctx context.Context, startTime time.Time, unit *inputUnit) {
-	var wg sync.WaitGroup
+	unit.Lock()
+	for _, input := range unit.inputs {
+		if _, found := unit.runners[input]; !found {
+			a.runInput(ctx, startTime, unit, input)
+		}
+	}
+	unit.Unlock()
+
+	// Inputs might be added until the context is done so wait for all
+	// gather loops to finish with the unit locked.
+	<-ctx.Done()
+	unit.Lock()
+	unit.wg.Wait()
+
+	log.Printf("D! [agent] Stopping service inputs")
+	for _, bp := range unit.backpressure {
+		bp.release()
+	}
+	stopRunningInputs(unit.inputs)
+	unit.Unlock()
+
+	unit.router.close()
+	log.Printf("D! [agent] Input channel closed")
+}
+
+// runInput starts the periodic gather of a single input in the background.
+// The unit must be locked by the caller.
+func (a *Agent) runInput(ctx context.Context, startTime time.Time, unit *inputUnit, input *models.RunningInput) {
 	var options []clock.Option
 
 	// Initialize time rounding
@@ -402,61 +555,67 @@ func (a *Agent) runInputs(ctx context.Context, startTime time.Time, unit *inputU
 		options = append(options, clock.WithAlignment(startTime))
 	}
 
-	tickers := make([]*clock.Ticker, 0, len(unit.inputs))
-	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
 
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitterSet {
-			jitter = input.Config.CollectionJitter
-		}
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitterSet {
+		jitter = input.Config.CollectionJitter
+	}
 
-		// Overwrite agent collection_offset if this plugin has its own.
-		offset := time.Duration(a.Config.Agent.CollectionOffset)
-		if input.Config.CollectionOffset != 0 {
-			offset = input.Config.CollectionOffset
-		}
+	// Overwrite agent collection_offset if this plugin has its own.
+	offset := time.Duration(a.Config.Agent.CollectionOffset)
+	if input.Config.CollectionOffset != 0 {
+		offset = input.Config.CollectionOffset
+	}
 
-		ticker := clock.NewTicker(interval, jitter, offset, options...)
-		tickers = append(tickers, ticker)
+	// Gather scheduled inputs at the times of the schedule and only warn
+	// about slow gathers exceeding the time between two scheduled gathers
+	if input.Scheduled() {
+		options = []clock.Option{clock.WithSchedule(input.NextGather)}
+		next := input.NextGather(time.Now())
+		if after := input.NextGather(next); !after.IsZero() {
+			interval = after.Sub(next)
+		}
+		log.Printf("D! [agent] Scheduled %s with %q, next gather at %s",
+			input.LogName(), input.Config.Schedule, next.Format(time.RFC3339))
+	}
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+	ticker := clock.NewTicker(interval, jitter, offset, options...)
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
-	}
-	defer stopTickers(tickers)
-	wg.Wait()
+	acc := NewAccumulator(input, unit.router.route(input))
+	acc.SetPrecision(getPrecision(precision, interval))
 
-	log.Printf("D! [agent] Stopping service inputs")
-	stopRunningInputs(unit.inputs)
+	inputCtx, cancel := context.WithCancel(ctx)
+	runner := &pluginRunner{cancel: cancel, done: make(chan struct{})}
+	unit.runners[input] = runner
 
-	close(unit.dst)
-	log.Printf("D! [agent] Input channel closed")
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(runner.done)
+		defer ticker.Stop()
+		a.gatherLoop(inputCtx, acc, input, ticker, interval)
+	}()
 }
 
 // testStartInputs is a variation of startInputs for use in --test and --once mode.
 // It differs by logging Start errors and returning only plugins successfully started.
-func (*Agent) testStartInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) *inputUnit {
+func (*Agent) testStartInputs(router *pipelineRouter, inputs []*models.RunningInput) *inputUnit {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		router: router,
 	}
 
 	for _, input := range inputs {
@@ -464,7 +623,7 @@ func (*Agent) testStartInputs(dst chan<- telegraf.Metric, inputs []*models.Runni
 		// This only applies to the accumulator passed to Start(), the
 		// Gather() accumulator does apply rounding according to the
 		// precision agent setting.
-		acc := NewAccumulator(input, dst)
+		acc := NewAccumulator(input, router.route(input))
 		acc.SetPrecision(time.Nanosecond)
 
 		if err := input.Start(acc); err != nil {
@@ -511,6 +670,12 @@ func (a *Agent) testRunInputs(
 				precision = input.Config.Precision
 			}
 
+			// Scheduled inputs are gathered immediately as well
+			if input.Scheduled() {
+				log.Printf("D! [agent] Ignoring schedule %q of %s for single gather",
+					input.Config.Schedule, input.LogName())
+			}
+
 			// Run plugins that require multiple gathers to calculate rate
 			// and delta metrics twice.
 			switch input.Config.Name {
@@ -524,7 +689,7 @@ func (a *Agent) testRunInputs(
 				time.Sleep(500 * time.Millisecond)
 			}
 
-			acc := NewAccumulator(input, unit.dst)
+			acc := NewAccumulator(input, unit.router.route(input))
 			acc.SetPrecision(getPrecision(precision, interval))
 
 			if err := input.Input.Gather(acc); err != nil {
@@ -541,7 +706,7 @@ func (a *Agent) testRunInputs(
 	log.Printf("D! [agent] Stopping service inputs")
 	stopRunningInputs(unit.inputs)
 
-	close(unit.dst)
+	unit.router.close()
 	log.Printf("D! [agent] Input channel closed")
 }
 
@@ -571,6 +736,9 @@ func (a *Agent) gatherLoop(
 	for {
 		select {
 		case <-ticker.C:
+			if input.Paused() {
+				continue
+			}
 			err := a.gatherOnce(acc, input, ticker, interval)
 			if err != nil {
 				acc.AddError(err)
@@ -581,12 +749,33 @@ func (a *Agent) gatherLoop(
 	}
 }
 
-// gatherOnce runs the input's Gather function once, logging a warning each interval it fails to complete before.
+// gatherOnce runs the input's Gather function once, logging a warning each
+// interval it fails to complete before. A gather exceeding the input's
+// gather timeout is cancelled, or abandoned for inputs not accepting a
+// context, and counted as failed so the next collection can start on time.
+// Collections are skipped as long as an abandoned gather is still running as
+// inputs do not support concurrent gathers.
 func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, ticker *clock.Ticker, interval time.Duration) error {
-	done := make(chan error)
+	if input.Gathering() {
+		log.Printf("W! [%s] Collection abandoned after gather timeout is still running; scheduled collection skipped",
+			input.LogName())
+		return nil
+	}
+
+	ctx := context.Background()
+	var abandonable *abandonableAccumulator
+	if input.Config.GatherTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, input.Config.GatherTimeout)
+		defer cancel()
+		abandonable = &abandonableAccumulator{Accumulator: acc, ctx: ctx}
+		acc = abandonable
+	}
+
+	// Buffered so an abandoned gather does not block once it returns
+	done := make(chan error, 1)
 	go func() {
-		defer panicRecover(input)
-		done <- input.Gather(acc)
+		done <- input.GatherContext(ctx, acc)
 	}()
 
 	// Only warn after interval seconds, even if the interval is started late.
@@ -595,88 +784,178 @@ func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, t
 	slowWarning := time.NewTicker(interval)
 	defer slowWarning.Stop()
 
+	var warned bool
 	for {
 		select {
 		case err := <-done:
-			return err
+			if ctx.Err() == nil {
+				return err
+			}
+		case <-ctx.Done():
 		case <-slowWarning.C:
 			log.Printf("W! [%s] Collection took longer than expected; not complete after interval of %s",
 				input.LogName(), interval)
 			input.IncrGatherTimeouts()
+			warned = true
+			continue
 		case <-ticker.C:
 			log.Printf("D! [%s] Previous collection has not completed; scheduled collection skipped",
 				input.LogName())
+			continue
+		}
+
+		// The gather timed out, make sure it does not send metrics anymore
+		// once we returned as the destination might be closed on shutdown
+		abandonable.abandon()
+		if !warned {
+			input.IncrGatherTimeouts()
 		}
+		input.IncrGatherErrors()
+		if _, ok := input.Input.(telegraf.ContextInput); ok {
+			return fmt.Errorf("collection cancelled after gather timeout of %s", input.Config.GatherTimeout)
+		}
+		return fmt.Errorf("collection abandoned after gather timeout of %s", input.Config.GatherTimeout)
 	}
 }
 
 // startProcessors sets up the processor chain and calls Start on all processors.  If an error occurs any started processors are Stopped.
-func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors models.RunningProcessors) (chan<- telegraf.Metric, []*processorUnit, error) {
-	var src chan telegraf.Metric
-	units := make([]*processorUnit, 0, len(runningProcessors))
+func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors models.RunningProcessors) (chan<- telegraf.Metric, *processorChain, error) {
+	src := make(chan telegraf.Metric, 100)
+	chain := &processorChain{
+		src:     src,
+		dst:     dst,
+		updates: make(chan *chainUpdate),
+		done:    make(chan struct{}),
+	}
+
+	units, err := chain.buildUnits(runningProcessors, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain.units = units
+	chain.entry = dst
+	if len(units) > 0 {
+		chain.entry = units[0].src
+	}
+
+	return src, chain, nil
+}
+
+// buildUnits creates the processor units for the given processors. The
+// processors are started except for the ones found in the running units,
+// those are reused. If an error occurs all processors started by this
+// function are stopped.
+func (c *processorChain) buildUnits(runningProcessors models.RunningProcessors, running map[*models.RunningProcessor]*processorUnit) ([]*processorUnit, error) {
 	// The processor chain is constructed from the output side starting from
 	// the output(s) and walking the way back to the input(s). However, the
 	// processor-list is sorted by order and/or by appearance in the config,
-	// i.e. in input-to-output direction. Therefore, reverse the processor list
-	// to reflect the order/definition order in the processing chain.
+	// i.e. in input-to-output direction. Therefore, walk the processor list
+	// in reverse to reflect the order/definition order in the processing chain.
+	units := make([]*processorUnit, len(runningProcessors))
+	dst := c.dst
 	for i := len(runningProcessors) - 1; i >= 0; i-- {
 		processor := runningProcessors[i]
+		src := make(chan telegraf.Metric, 100)
 
-		src = make(chan telegraf.Metric, 100)
-		acc := NewAccumulator(processor, dst)
-
-		err := processor.Start(acc)
-		if err != nil {
-			for _, u := range units {
-				u.processor.Stop()
-				close(u.dst)
+		var r *route
+		if u, found := running[processor]; found {
+			r = u.dst
+		} else {
+			r = &route{dst: dst}
+			if err := processor.Start(newRoutedAccumulator(processor, r)); err != nil {
+				for _, u := range units[i+1:] {
+					if _, found := running[u.processor]; !found {
+						u.processor.Stop()
+					}
+				}
+				return nil, fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
 			}
-			return nil, nil, fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
 		}
 
-		units = append(units, &processorUnit{
+		units[i] = &processorUnit{
 			src:       src,
-			dst:       dst,
+			dst:       r,
 			processor: processor,
-		})
+			done:      make(chan struct{}),
+			next:      dst,
+			closeDst:  true,
+		}
 
 		dst = src
 	}
 
-	return src, units, nil
+	return units, nil
 }
 
 // runProcessors begins processing metrics and runs until the source channel is closed and all metrics have been written.
-func (*Agent) runProcessors(units []*processorUnit) {
-	var wg sync.WaitGroup
-	for _, unit := range units {
-		wg.Add(1)
-		go func(unit *processorUnit) {
-			defer wg.Done()
+func (*Agent) runProcessors(chain *processorChain) {
+	defer close(chain.done)
+
+	for _, unit := range chain.units {
+		chain.runUnit(unit)
+	}
 
-			acc := NewAccumulator(unit.processor, unit.dst)
-			for m := range unit.src {
-				if err := unit.processor.Add(m, acc); err != nil {
-					acc.AddError(err)
-					m.Drop()
+	for {
+		select {
+		case m, ok := <-chain.src:
+			if !ok {
+				// Close the chain and wait for the processors to finish
+				if len(chain.units) > 0 {
+					close(chain.entry)
+				} else {
+					close(chain.dst)
 				}
+				chain.wg.Wait()
+				return
 			}
+			chain.entry <- m
+		case update := <-chain.updates:
+			update.result <- chain.apply(update.processors)
+		}
+	}
+}
+
+// runUnit processes the metrics of the given unit in the background.
+func (c *processorChain) runUnit(unit *processorUnit) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(unit.done)
+
+		acc := newRoutedAccumulator(unit.processor, unit.dst)
+		for m := range unit.src {
+			if err := unit.processor.Add(m, acc); err != nil {
+				acc.AddError(err)
+				m.Drop()
+			}
+		}
+
+		dst := unit.dst.get()
+		if unit.keep {
+			unit.dst.set(unit.next)
+		} else {
 			unit.processor.Stop()
-			close(unit.dst)
+		}
+		if unit.closeDst {
+			close(dst)
 			log.Printf("D! [agent] Processor channel closed")
-		}(unit)
-	}
-	wg.Wait()
+		}
+	}()
 }
 
 // startAggregators sets up the aggregator unit and returns the source channel.
 func (*Agent) startAggregators(aggC, outputC chan<- telegraf.Metric, aggregators []*models.RunningAggregator) (chan<- telegraf.Metric, *aggregatorUnit) {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	src := make(chan telegraf.Metric, 100)
 	unit := &aggregatorUnit{
 		src:         src,
 		aggC:        aggC,
 		outputC:     outputC,
 		aggregators: aggregators,
+		ctx:         ctx,
+		cancel:      cancel,
+		runners:     make(map[*models.RunningAggregator]*pluginRunner, len(aggregators)),
 	}
 	return src, unit
 }
@@ -687,14 +966,21 @@ func (a *Agent) runAggregators(
 	startTime time.Time,
 	unit *aggregatorUnit,
 ) {
-	ctx, cancel := context.WithCancel(context.Background())
-
 	// Before calling Add, initialize the aggregation window.  This ensures
-	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
-		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
-		agg.UpdateWindow(since, until)
+	// that any metric created after start time will be aggregated.  The
+	// window restored from the statefile is continued instead.
+	unit.Lock()
+	for _, agg := range unit.aggregators {
+		if _, found := unit.runners[agg]; found {
+			continue
+		}
+		if agg.EndPeriod().IsZero() {
+			since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
+			agg.UpdateWindow(since, until)
+		}
+		a.runAggregator(unit, agg)
 	}
+	unit.Unlock()
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -702,11 +988,13 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			unit.RLock()
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
 			}
+			unit.RUnlock()
 
 			if !dropOriginal {
 				unit.outputC <- metric // keep original.
@@ -714,24 +1002,13 @@ func (a *Agent) runAggregators(
 				metric.Drop()
 			}
 		}
-		cancel()
+		unit.Lock()
+		unit.cancel()
+		unit.Unlock()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
-		wg.Add(1)
-		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
-
-			interval := time.Duration(a.Config.Agent.Interval)
-			precision := time.Duration(a.Config.Agent.Precision)
-
-			acc := NewAccumulator(agg, unit.aggC)
-			acc.SetPrecision(getPrecision(precision, interval))
-			a.push(ctx, agg, acc)
-		}(agg)
-	}
-
 	wg.Wait()
+	unit.wg.Wait()
 
 	// In the case that there are no processors, both aggC and outputC are the
 	// same channel.  If there are processors, we close the aggC and the
@@ -740,6 +1017,44 @@ func (a *Agent) runAggregators(
 	log.Printf("D! [agent] Aggregator channel closed")
 }
 
+// runAggregator starts the periodic push of a single aggregator in the
+// background. The unit must be locked by the caller.
+func (a *Agent) runAggregator(unit *aggregatorUnit, agg *models.RunningAggregator) {
+	interval := time.Duration(a.Config.Agent.Interval)
+	precision := time.Duration(a.Config.Agent.Precision)
+
+	acc := NewAccumulator(agg, unit.aggC)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	// Push the aggregates restored for an already elapsed window before any
+	// new metric is added to not discard those metrics as being outside of
+	// the window. The restored window might be several periods old, so
+	// continue with the window containing the current time.
+	if now := time.Now(); agg.EndPeriod().Before(now) {
+		agg.Push(acc)
+		since, until := updateWindow(now, a.Config.Agent.RoundInterval, agg.Period())
+		agg.UpdateWindow(since, until)
+	}
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	runner := &pluginRunner{cancel: cancel, done: make(chan struct{})}
+	unit.runners[agg] = runner
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(runner.done)
+		a.push(ctx, agg, acc)
+
+		// Keep the aggregates of stateful aggregators on shutdown to continue
+		// the aggregation with the persisted state on the next start.
+		if unit.persistent && agg.Stateful() && unit.ctx.Err() != nil {
+			return
+		}
+		agg.Push(acc)
+	}()
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (since, until time.Time) {
 	if roundInterval {
 		until = internal.AlignTime(start, period)
@@ -768,7 +1083,6 @@ func (*Agent) push(ctx context.Context, aggregator *models.RunningAggregator, ac
 		case <-time.After(until):
 			aggregator.Push(acc)
 		case <-ctx.Done():
-			aggregator.Push(acc)
 			return
 		}
 	}
@@ -780,8 +1094,15 @@ func (a *Agent) startOutputs(
 	ctx context.Context,
 	outputs []*models.RunningOutput,
 ) (chan<- telegraf.Metric, *outputUnit, error) {
+	flushCtx, cancel := context.WithCancel(context.Background())
+
 	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	unit := &outputUnit{
+		src:     src,
+		ctx:     flushCtx,
+		cancel:  cancel,
+		runners: make(map[*models.RunningOutput]*pluginRunner, len(outputs)),
+	}
 	for _, output := range outputs {
 		if err := a.connectOutput(ctx, output); err != nil {
 			var fatalErr *internal.FatalError
@@ -795,12 +1116,23 @@ func (a *Agent) startOutputs(
 			for _, unitOutput := range unit.outputs {
 				unitOutput.Close()
 			}
+			cancel()
 			return nil, nil, fmt.Errorf("connecting output %s: %w", output.LogName(), err)
 		}
 
 		unit.outputs = append(unit.outputs, output)
 	}
 
+	if dir := a.Config.Agent.ShutdownSpoolDirectory; dir != "" {
+		unspoolOutputs(unit.outputs, dir)
+	}
+
+	// Link the dead-letter outputs and failover groups of the connected outputs
+	if err := models.LinkDeadLetters(unit.outputs); err != nil {
+		log.Printf("E! [agent] Linking dead-letter outputs failed: %v", err)
+	}
+	models.LinkFailoverGroups(unit.outputs)
+
 	return src, unit, nil
 }
 
@@ -824,43 +1156,22 @@ func (*Agent) connectOutput(ctx context.Context, output *models.RunningOutput) e
 
 // runOutputs begins processing metrics and returns until the source channel is
 // closed and all metrics have been written.  On shutdown metrics will be
-// written one last time and dropped if unsuccessful.
+// written until the drain timeout elapsed and spooled or dropped if
+// unsuccessful.
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
-	var wg sync.WaitGroup
-
 	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
-	ctx, cancel := context.WithCancel(context.Background())
-
+	unit.Lock()
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
+		if _, found := unit.runners[output]; !found {
+			a.runOutput(unit, output)
 		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			timer := clock.NewTimer(interval, jitter)
-			defer timer.Stop()
-
-			a.flushLoop(ctx, output, timer)
-		}(output)
 	}
+	unit.Unlock()
 
 	for metric := range unit.src {
+		unit.RLock()
 		for i, output := range unit.outputs {
 			if i == len(unit.outputs)-1 {
 				output.AddMetricNoCopy(metric)
@@ -868,23 +1179,119 @@ func (a *Agent) runOutputs(
 				output.AddMetric(metric)
 			}
 		}
+		unit.RUnlock()
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
+	unit.Lock()
+	defer unit.Unlock()
+	unit.cancel()
+	unit.wg.Wait()
+
+	if dir := a.Config.Agent.ShutdownSpoolDirectory; dir != "" {
+		spoolOutputs(unit.outputs, dir)
+	}
 
 	log.Println("I! [agent] Stopping running outputs")
 	stopRunningOutputs(unit.outputs)
 }
 
+// spoolOutputs moves the metrics remaining in the buffers of the outputs to
+// the spool directory.
+func spoolOutputs(outputs []*models.RunningOutput, dir string) {
+	for _, output := range outputs {
+		n, err := output.Spool(dir)
+		if err != nil {
+			log.Printf("E! [agent] Spooling metrics of %s failed: %v", output.LogName(), err)
+		}
+		if n > 0 {
+			log.Printf("I! [agent] Spooled %d metrics of %s for the next start", n, output.LogName())
+		}
+	}
+}
+
+// unspoolOutputs re-queues the metrics spooled on the last shutdown into the
+// buffers of the outputs. Spools not belonging to any of the outputs, e.g.
+// due to configuration changes, are kept.
+func unspoolOutputs(outputs []*models.RunningOutput, dir string) {
+	known := make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		known[models.SpoolPath(dir, output.ID())] = true
+		n, err := output.Unspool(dir)
+		if err != nil {
+			log.Printf("E! [agent] Re-queuing spooled metrics of %s failed: %v", output.LogName(), err)
+		}
+		if n > 0 {
+			log.Printf("I! [agent] Re-queued %d spooled metrics of %s", n, output.LogName())
+		}
+	}
+
+	spools, err := filepath.Glob(models.SpoolPath(dir, "*"))
+	if err != nil {
+		return
+	}
+	for _, spool := range spools {
+		if !known[spool] {
+			log.Printf("W! [agent] Spool %q does not belong to any output, keeping it", spool)
+		}
+	}
+}
+
+// runOutput starts the flush loop of a single output in the background.
+// The unit must be locked by the caller.
+func (a *Agent) runOutput(unit *outputUnit, output *models.RunningOutput) {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	runner := &pluginRunner{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		flush:  make(chan struct{}, 1),
+	}
+	unit.runners[output] = runner
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(runner.done)
+
+		timer := clock.NewTimer(interval, jitter)
+		defer timer.Stop()
+
+		a.flushLoop(ctx, output, timer, runner.flush)
+
+		// Write the remaining metrics one last time or keep writing until
+		// the drain timeout elapsed on shutdown
+		var deadline time.Time
+		if unit.ctx.Err() != nil && a.Config.Agent.ShutdownDrainTimeout > 0 {
+			deadline = time.Now().Add(time.Duration(a.Config.Agent.ShutdownDrainTimeout))
+		}
+		a.drainOutput(output, timer, deadline)
+	}()
+}
+
 // flushLoop runs an output's flush function periodically until the context is
 // done.
-func (a *Agent) flushLoop(ctx context.Context, output *models.RunningOutput, timer *clock.Timer) {
+func (a *Agent) flushLoop(ctx context.Context, output *models.RunningOutput, timer *clock.Timer, flush <-chan struct{}) {
+	// Retry failed writes as soon as the output's backoff elapsed instead of
+	// waiting for the next flush interval
+	var retry <-chan time.Time
 	logError := func(err error) {
-		if err != nil {
-			log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
+		retry = nil
+		if next := output.NextRetry(); !next.IsZero() {
+			retry = time.After(time.Until(next))
 		}
+		logWriteError(output, err)
 	}
 
 	// watch for flush requests
@@ -896,25 +1303,97 @@ func (a *Agent) flushLoop(ctx context.Context, output *models.RunningOutput, tim
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, timer, output.Write))
 			return
 		default:
 		}
 
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, timer, output.Write))
 			return
 		case <-timer.C:
 			logError(a.flushOnce(output, timer, output.Write))
 		case <-flushRequested:
 			logError(a.flushOnce(output, timer, output.Write))
+		case <-flush:
+			logError(a.flushOnce(output, timer, output.Write))
+		case <-retry:
+			logError(a.flushOnce(output, timer, output.Write))
 		case <-output.BatchReady:
 			logError(a.flushBatch(output, output.WriteBatch))
 		}
 	}
 }
 
+// drainOutput writes the remaining metrics of the output after the flush loop
+// stopped. Failed writes are retried until the buffer is empty or the deadline
+// is reached, so with a zero deadline the output is written only once. No new
+// batches are started after the deadline and the batches of a write still in
+// flight are handed back to the buffer while the write is left running in the
+// background.
+func (*Agent) drainOutput(output *models.RunningOutput, timer *clock.Timer, deadline time.Time) {
+	ctx := context.Background()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	for {
+		// Buffered so an abandoned write does not block once it returns. The
+		// write must not touch the output after returning as the output might
+		// be closed by then.
+		done := make(chan error, 1)
+		go func() {
+			done <- output.WriteContext(ctx)
+		}()
+
+	wait:
+		for {
+			select {
+			case err := <-done:
+				output.LogBufferStatus()
+				logWriteError(output, err)
+				break wait
+			case <-timer.C:
+				log.Printf("W! [agent] [%q] did not complete within its flush interval", output.LogName())
+				output.LogBufferStatus()
+			case <-ctx.Done():
+				n := output.AbandonWrites()
+				log.Printf("W! [agent] Write to %s did not complete within the drain timeout, handed %d in-flight metrics back to the buffer",
+					output.LogName(), n)
+				return
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if output.BufferLength() == 0 || remaining <= 0 {
+			return
+		}
+
+		// Wait for the output's backoff to elapse before retrying
+		wait := min(remaining, drainRetryInterval)
+		if next := output.NextRetry(); !next.IsZero() {
+			wait = min(remaining, time.Until(next))
+		}
+		log.Printf("I! [agent] Draining %d metrics of %s, retrying in %s", output.BufferLength(), output.LogName(), wait)
+		time.Sleep(wait)
+		if !time.Now().Before(deadline) {
+			return
+		}
+	}
+}
+
+// logWriteError logs the error of a write to the output if any.
+func logWriteError(output *models.RunningOutput, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, models.ErrRetryBackoff), errors.Is(err, models.ErrCircuitOpen):
+		log.Printf("D! [agent] Skipped writing to %s: %v", output.LogName(), err)
+	default:
+		log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
+	}
+}
+
 // flushOnce runs the output's Write function once, logging a warning each interval it fails to complete before the flush interval elapses.
 func (*Agent) flushOnce(output *models.RunningOutput, timer *clock.Timer, writeFunc func() error) error {
 	done := make(chan error)
@@ -994,55 +1473,34 @@ func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<-
 
 	startTime := time.Now()
 
-	next := outputC
-
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			var err error
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(procC, next, a.Config.Aggregators)
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		var err error
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
-	}
-
-	iu := a.testStartInputs(next, a.Config.Inputs)
-
+	// Merge the metrics of all pipelines into the output channel
 	var wg sync.WaitGroup
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
-
-		wg.Add(1)
+	var sinks sync.WaitGroup
+	pipelines, err := a.startPipelines(func(*pipelineUnit) (chan<- telegraf.Metric, error) {
+		dst := make(chan telegraf.Metric, 100)
+		sinks.Add(1)
 		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
+			defer sinks.Done()
+			for m := range dst {
+				outputC <- m
+			}
 		}()
+		return dst, nil
+	})
+	if err != nil {
+		return err
 	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sinks.Wait()
+		close(outputC)
+	}()
 
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(pu)
-		}()
+	iu := a.testStartInputs(newPipelineRouter(pipelines), a.Config.Inputs)
+
+	for _, p := range pipelines {
+		a.runPipeline(&wg, startTime, p)
 	}
 
 	wg.Add(1)
@@ -1100,62 +1558,20 @@ func (a *Agent) runOnce(ctx context.Context, wait time.Duration) error {
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	pipelines, err := a.startPipelines(func(p *pipelineUnit) (chan<- telegraf.Metric, error) {
+		src, ou, err := a.startOutputs(ctx, pipelineOutputs(a.Config.Outputs, p.name()))
+		p.outputs = ou
+		return src, err
+	})
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(procC, next, a.Config.Aggregators)
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
-	}
-
-	iu := a.testStartInputs(next, a.Config.Inputs)
+	iu := a.testStartInputs(newPipelineRouter(pipelines), a.Config.Inputs)
 
 	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runOutputs(ou)
-	}()
-
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(pu)
-		}()
+	for _, p := range pipelines {
+		a.runPipeline(&wg, startTime, p)
 	}
 
 	wg.Add(1)
@@ -1188,23 +1604,3 @@ func getPrecision(precision, interval time.Duration) time.Duration {
 		return time.Nanosecond
 	}
 }
-
-// panicRecover displays an error if an input panics.
-func panicRecover(input *models.RunningInput) {
-	//nolint:revive // recover is called inside a deferred function
-	if err := recover(); err != nil {
-		trace := make([]byte, 2048)
-		runtime.Stack(trace, true)
-		log.Printf("E! FATAL: [%s] panicked: %s, Stack:\n%s",
-			input.LogName(), err, trace)
-		log.Fatalln("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
-			"stack trace, configuration, and OS information: " +
-			"https://github.com/influxdata/telegraf/issues/new/choose")
-	}
-}
-
-func stopTickers(tickers []*clock.Ticker) {
-	for _, ticker := range tickers {
-		ticker.Stop()
-	}
-}
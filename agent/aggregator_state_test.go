@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/persister"
+)
+
+func TestAggregatorStateRestart(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	newConfig := func(output *reloadOutput, agg *countingAggregator) *config.Config {
+		cfg := newReloadConfig(t, output, []*reloadInput{{name: "a"}}, nil)
+		cfg.Agent.Statefile = filename
+		cfg.Persister = &persister.Persister{Filename: filename}
+		cfg.Aggregators = append(cfg.Aggregators, models.NewRunningAggregator(agg, &models.AggregatorConfig{
+			Name:   "count",
+			ID:     "count",
+			Period: time.Hour,
+		}))
+		return cfg
+	}
+	run := func(t *testing.T, cfg *config.Config, output *reloadOutput) {
+		a := NewAgent(cfg)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, a.Run(ctx))
+		}()
+		require.Eventually(t, func() bool {
+			return output.count("a") > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		wg.Wait()
+	}
+
+	// The aggregate of the unfinished window must not be pushed on shutdown
+	// but persisted instead
+	output := &reloadOutput{}
+	agg := &countingAggregator{}
+	cfg := newConfig(output, agg)
+	run(t, cfg, output)
+	require.Zero(t, output.count("count"))
+	count := agg.current()
+	require.Positive(t, count)
+	window := cfg.Aggregators[0].EndPeriod()
+
+	// The next run must continue the aggregation in the same window
+	output = &reloadOutput{}
+	restored := &countingAggregator{}
+	cfg = newConfig(output, restored)
+	run(t, cfg, output)
+	require.Zero(t, output.count("count"))
+	require.Greater(t, restored.current(), count)
+	require.True(t, window.Equal(cfg.Aggregators[0].EndPeriod()))
+}
+
+func TestAggregatorStateOutdated(t *testing.T) {
+	a := NewAgent(config.NewConfig())
+	agg := models.NewRunningAggregator(&countingAggregator{}, &models.AggregatorConfig{
+		Name:   "count",
+		ID:     "count-outdated",
+		Period: time.Minute,
+	})
+	require.NoError(t, agg.Init())
+
+	// Restore a state several periods old
+	now := time.Now()
+	require.NoError(t, agg.SetState(models.AggregatorState{
+		PeriodStart: now.Add(-3 * time.Minute),
+		PeriodEnd:   now.Add(-2*time.Minute - 30*time.Second),
+		State:       []byte("5"),
+	}))
+
+	aggC := make(chan telegraf.Metric, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := &aggregatorUnit{
+		aggC:    aggC,
+		ctx:     ctx,
+		cancel:  cancel,
+		runners: make(map[*models.RunningAggregator]*pluginRunner),
+	}
+	a.runAggregator(unit, agg)
+	defer func() {
+		cancel()
+		unit.wg.Wait()
+	}()
+
+	// The restored aggregate is pushed and new metrics are aggregated in
+	// the current window instead of being dropped as too old
+	m := <-aggC
+	require.Equal(t, int64(5), m.Fields()["count"])
+	require.False(t, agg.EndPeriod().Before(now))
+	require.True(t, agg.EndPeriod().Add(-time.Minute).Before(now.Add(time.Nanosecond)))
+	dropped := agg.MetricsDropped.Get()
+	agg.Add(metric.New("cpu", nil, map[string]interface{}{"value": 1}, time.Now()))
+	require.Equal(t, dropped, agg.MetricsDropped.Get())
+}
+
+type countingAggregator struct {
+	sync.Mutex
+	count int64
+}
+
+func (*countingAggregator) SampleConfig() string {
+	return ""
+}
+
+func (c *countingAggregator) Add(telegraf.Metric) {
+	c.Lock()
+	defer c.Unlock()
+	c.count++
+}
+
+func (c *countingAggregator) Push(acc telegraf.Accumulator) {
+	c.Lock()
+	defer c.Unlock()
+	acc.AddFields("count", map[string]interface{}{"count": c.count}, nil)
+}
+
+func (c *countingAggregator) Reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.count = 0
+}
+
+func (c *countingAggregator) GetState() interface{} {
+	c.Lock()
+	defer c.Unlock()
+	return c.count
+}
+
+func (c *countingAggregator) SetState(state interface{}) error {
+	count, ok := state.(int64)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.count = count
+	return nil
+}
+
+func (c *countingAggregator) current() int64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.count
+}
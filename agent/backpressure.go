@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/models"
+)
+
+// backpressureInterval is the interval for checking if a paused input can
+// resume the delivery of metrics.
+var backpressureInterval = 100 * time.Millisecond
+
+// backpressure pauses the delivery of tracking metrics of a service input
+// while the buffer of any output receiving the input's metrics is full. The
+// input stops consuming new messages once its undelivered messages reach
+// the input's limit, so the backlog is kept by the message broker instead of
+// being dropped by the outputs. To also fit the undelivered metrics still on
+// their way to the outputs, a buffer counts as full if it cannot take the
+// input's maximum number of undelivered metrics.
+type backpressure struct {
+	input   *models.RunningInput
+	outputs func() []*models.RunningOutput
+
+	done chan struct{}
+	once sync.Once
+}
+
+func newBackpressure(input *models.RunningInput, outputs func() []*models.RunningOutput) *backpressure {
+	return &backpressure{
+		input:   input,
+		outputs: outputs,
+		done:    make(chan struct{}),
+	}
+}
+
+// wait blocks while the buffer of any output cannot take the given number of
+// metrics or until released.
+func (b *backpressure) wait(headroom int) {
+	if b == nil {
+		return
+	}
+
+	full := b.fullOutput(headroom)
+	if full == nil {
+		return
+	}
+
+	b.input.Log().Debugf("Buffer of %s is full, pausing delivery", full.LogName())
+	ticker := time.NewTicker(backpressureInterval)
+	defer ticker.Stop()
+	for full != nil {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+		full = b.fullOutput(headroom)
+	}
+	b.input.Log().Debug("Resuming delivery")
+}
+
+// release unblocks all current and future waits, e.g. when stopping the
+// input.
+func (b *backpressure) release() {
+	if b == nil {
+		return
+	}
+	b.once.Do(func() { close(b.done) })
+}
+
+func (b *backpressure) fullOutput(headroom int) *models.RunningOutput {
+	select {
+	case <-b.done:
+		return nil
+	default:
+	}
+
+	for _, output := range b.outputs() {
+		if output.BufferFull(headroom) {
+			return output
+		}
+	}
+	return nil
+}
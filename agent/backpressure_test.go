@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestBackpressure(t *testing.T) {
+	backpressureInterval = 10 * time.Millisecond
+
+	output := &reloadOutput{}
+	ro, err := models.NewRunningOutput(output, &models.OutputConfig{Name: "test", ID: "backpressure"}, 10, 10)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	input := models.NewRunningInput(&reloadInput{name: "consumer"}, &models.InputConfig{Name: "consumer"})
+	bp := newBackpressure(input, func() []*models.RunningOutput { return []*models.RunningOutput{ro} })
+
+	metrics := make(chan telegraf.Metric, 10)
+	acc := newServiceAccumulator(input, metrics, bp).WithTracking(5)
+
+	m := testutil.TestMetric(42)
+	for range 6 {
+		ro.AddMetric(m)
+	}
+
+	// The buffer cannot take the undelivered metrics of the input anymore so
+	// the delivery must block until the output wrote the buffered metrics
+	added := make(chan struct{})
+	go func() {
+		acc.AddTrackingMetric(m)
+		close(added)
+	}()
+	require.Never(t, func() bool {
+		select {
+		case <-added:
+			return true
+		default:
+			return false
+		}
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, ro.Write())
+	require.Eventually(t, func() bool {
+		select {
+		case <-added:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, metrics, 1)
+	require.Equal(t, 6, output.count("test1"))
+
+	// Releasing the backpressure unblocks the delivery, e.g. on shutdown
+	for range 6 {
+		ro.AddMetric(m)
+	}
+	added = make(chan struct{})
+	go func() {
+		acc.AddTrackingMetric(m)
+		close(added)
+	}()
+	bp.release()
+	require.Eventually(t, func() bool {
+		select {
+		case <-added:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, metrics, 2)
+}
+
+func TestBackpressureDisabled(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	acc := newServiceAccumulator(&TestMetricMaker{}, metrics, nil).WithTracking(5)
+	acc.AddTrackingMetric(testutil.TestMetric(42))
+	require.Len(t, metrics, 1)
+}
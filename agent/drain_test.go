@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/clock"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestDrainOutput(t *testing.T) {
+	a := NewAgent(config.NewConfig())
+	timer := clock.NewTimer(time.Hour, 0)
+	defer timer.Stop()
+
+	// Without deadline the output is written only once
+	output := &drainingOutput{failures: 1}
+	ro, err := models.NewRunningOutput(output, &models.OutputConfig{Name: "draining", ID: "drain-once"}, 10, 10)
+	require.NoError(t, err)
+	ro.AddMetric(testutil.TestMetric(1))
+	a.drainOutput(ro, timer, time.Time{})
+	require.Equal(t, 1, output.writes)
+	require.Equal(t, 1, ro.BufferLength())
+
+	// Failed writes are retried until the buffer is empty
+	output = &drainingOutput{failures: 1}
+	ro, err = models.NewRunningOutput(output, &models.OutputConfig{Name: "draining", ID: "drain-deadline"}, 10, 10)
+	require.NoError(t, err)
+	ro.AddMetric(testutil.TestMetric(1))
+	a.drainOutput(ro, timer, time.Now().Add(time.Minute))
+	require.Equal(t, 2, output.writes)
+	require.Zero(t, ro.BufferLength())
+
+	// Writing stops at the deadline
+	output = &drainingOutput{failures: -1}
+	ro, err = models.NewRunningOutput(output, &models.OutputConfig{Name: "draining", ID: "drain-expired"}, 10, 10)
+	require.NoError(t, err)
+	ro.AddMetric(testutil.TestMetric(1))
+	start := time.Now()
+	a.drainOutput(ro, timer, start.Add(100*time.Millisecond))
+	require.Less(t, time.Since(start), drainRetryInterval)
+	require.Equal(t, 1, output.writes)
+	require.Equal(t, 1, ro.BufferLength())
+}
+
+func TestDrainOutputHungWrite(t *testing.T) {
+	for _, strategy := range []string{"memory", "disk_write_through"} {
+		t.Run(strategy, func(t *testing.T) {
+			a := NewAgent(config.NewConfig())
+			timer := clock.NewTimer(time.Hour, 0)
+			defer timer.Stop()
+
+			output := &hungOutput{release: make(chan struct{})}
+			cfg := &models.OutputConfig{
+				Name:            "hung",
+				ID:              "drain-hung-" + strategy,
+				BufferStrategy:  strategy,
+				BufferDirectory: t.TempDir(),
+			}
+			ro, err := models.NewRunningOutput(output, cfg, 1, 10)
+			require.NoError(t, err)
+			ro.AddMetric(testutil.TestMetric(1))
+			ro.AddMetric(testutil.TestMetric(2))
+
+			// Waiting for the hung write stops at the deadline and the
+			// in-flight batch is handed back to the buffer
+			start := time.Now()
+			a.drainOutput(ro, timer, start.Add(100*time.Millisecond))
+			require.Less(t, time.Since(start), time.Second)
+			require.Equal(t, 2, ro.BufferLength())
+			require.True(t, ro.Writing())
+
+			// The in-flight batch is spooled along with the remaining metrics
+			if strategy == "memory" {
+				n, err := ro.Spool(t.TempDir())
+				require.NoError(t, err)
+				require.Equal(t, 2, n)
+			}
+
+			// Neither the output nor the buffer is closed during the write
+			ro.Close()
+			require.False(t, output.closed.Load())
+
+			// No new batches are started once the hung write returns and its
+			// late result does not touch the handed back metrics
+			close(output.release)
+			require.Eventually(t, func() bool {
+				return !ro.Writing()
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, int64(1), output.writes.Load())
+			if strategy == "memory" {
+				require.Zero(t, ro.BufferLength())
+			} else {
+				require.Equal(t, 2, ro.BufferLength())
+			}
+
+			ro.Close()
+			require.True(t, output.closed.Load())
+		})
+	}
+}
+
+type drainingOutput struct {
+	failures int
+	writes   int
+}
+
+func (*drainingOutput) SampleConfig() string { return "" }
+func (*drainingOutput) Connect() error       { return nil }
+func (*drainingOutput) Close() error         { return nil }
+
+func (o *drainingOutput) Write([]telegraf.Metric) error {
+	o.writes++
+	if o.failures != 0 {
+		o.failures--
+		return errors.New("backend unavailable")
+	}
+	return nil
+}
+
+// hungOutput blocks each write until released.
+type hungOutput struct {
+	release chan struct{}
+	writes  atomic.Int64
+	closed  atomic.Bool
+}
+
+func (*hungOutput) SampleConfig() string { return "" }
+func (*hungOutput) Connect() error       { return nil }
+
+func (o *hungOutput) Close() error {
+	o.closed.Store(true)
+	return nil
+}
+
+func (o *hungOutput) Write([]telegraf.Metric) error {
+	o.writes.Add(1)
+	<-o.release
+	return nil
+}
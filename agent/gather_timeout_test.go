@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestGatherTimeoutAbandon(t *testing.T) {
+	output := &reloadOutput{}
+	cfg := newReloadConfig(t, output, nil, nil)
+	hung := &hungInput{release: make(chan struct{}), finished: make(chan struct{})}
+	ri := models.NewRunningInput(hung, &models.InputConfig{
+		Name:          "hung",
+		ID:            "hung-abandon",
+		GatherTimeout: 20 * time.Millisecond,
+	})
+	cfg.Inputs = append(cfg.Inputs, ri)
+	a := NewAgent(cfg)
+	gatherErrors := ri.GatherErrors.Get()
+	timeouts := ri.GatherTimeouts.Get()
+	defer models.GlobalGatherErrors.Set(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	// The hung gather is abandoned and further collections are skipped while
+	// it is still running
+	require.Eventually(t, func() bool {
+		return ri.GatherErrors.Get()-gatherErrors == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Greater(t, ri.GatherTimeouts.Get(), timeouts)
+	require.Never(t, func() bool {
+		return output.count("hung") > 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	// Collections resume once the abandoned gather returned and its late
+	// metrics are dropped
+	close(hung.release)
+	<-hung.finished
+	require.Eventually(t, func() bool {
+		return output.count("hung") >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+	require.Zero(t, output.count("late"))
+	require.Equal(t, int64(1), ri.GatherErrors.Get()-gatherErrors)
+}
+
+func TestGatherTimeoutCancel(t *testing.T) {
+	output := &reloadOutput{}
+	cfg := newReloadConfig(t, output, nil, nil)
+	input := &cancellableInput{}
+	ri := models.NewRunningInput(input, &models.InputConfig{
+		Name:          "cancellable",
+		ID:            "cancellable-timeout",
+		GatherTimeout: 20 * time.Millisecond,
+	})
+	cfg.Inputs = append(cfg.Inputs, ri)
+	a := NewAgent(cfg)
+	gatherErrors := ri.GatherErrors.Get()
+	defer models.GlobalGatherErrors.Set(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	require.Eventually(t, func() bool {
+		return input.cancelled.Load() >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	// Each gather is cancelled and counted as failed exactly once
+	require.Equal(t, input.cancelled.Load(), ri.GatherErrors.Get()-gatherErrors)
+	require.Zero(t, output.count("cancellable"))
+}
+
+// hungInput blocks its first gather until released.
+type hungInput struct {
+	release  chan struct{}
+	finished chan struct{}
+	once     sync.Once
+}
+
+func (*hungInput) SampleConfig() string {
+	return ""
+}
+
+func (i *hungInput) Gather(acc telegraf.Accumulator) error {
+	first := false
+	i.once.Do(func() { first = true })
+	if first {
+		<-i.release
+		acc.AddFields("late", map[string]interface{}{"value": 42}, nil)
+		close(i.finished)
+		return nil
+	}
+	acc.AddFields("hung", map[string]interface{}{"value": 42}, nil)
+	return nil
+}
+
+// cancellableInput blocks each gather until the context is cancelled.
+type cancellableInput struct {
+	cancelled atomic.Int64
+}
+
+func (*cancellableInput) SampleConfig() string {
+	return ""
+}
+
+func (*cancellableInput) Gather(telegraf.Accumulator) error {
+	panic("gather called instead of gather context")
+}
+
+func (i *cancellableInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	<-ctx.Done()
+	i.cancelled.Add(1)
+	acc.AddFields("cancellable", map[string]interface{}{"value": 42}, nil)
+	return ctx.Err()
+}
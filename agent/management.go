@@ -0,0 +1,401 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// managementServer serves the HTTP management API of the running agent.
+type managementServer struct {
+	server   *http.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// pluginInfo describes a running plugin in the management API.
+type pluginInfo struct {
+	Type     string            `json:"type"`
+	Name     string            `json:"name"`
+	ID       string            `json:"id"`
+	Alias    string            `json:"alias,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Pipeline string            `json:"pipeline,omitempty"`
+	LogLevel string            `json:"log_level"`
+	Paused   bool              `json:"paused,omitempty"`
+}
+
+// outputInfo describes the buffer state of a running output in the
+// management API.
+type outputInfo struct {
+	Name            string     `json:"name"`
+	ID              string     `json:"id"`
+	Alias           string     `json:"alias,omitempty"`
+	Pipeline        string     `json:"pipeline,omitempty"`
+	BufferLength    int        `json:"buffer_length"`
+	BufferLimit     int64      `json:"buffer_limit"`
+	MetricsAdded    int64      `json:"metrics_added"`
+	MetricsWritten  int64      `json:"metrics_written"`
+	MetricsRejected int64      `json:"metrics_rejected"`
+	MetricsDropped  int64      `json:"metrics_dropped"`
+	LastError       *time.Time `json:"last_error,omitempty"`
+}
+
+// logLevelRequest is the body of a request to change a plugin's log-level.
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// startManagementServer starts serving the management API on the configured
+// address.
+func (a *Agent) startManagementServer() (*managementServer, error) {
+	serverConfig := &common_tls.ServerConfig{
+		TLSCert:           a.Config.Agent.ManagementTLSCert,
+		TLSKey:            a.Config.Agent.ManagementTLSKey,
+		TLSAllowedCACerts: a.Config.Agent.ManagementTLSAllowedCACerts,
+	}
+	tlsConf, err := serverConfig.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS config for management API failed: %w", err)
+	}
+
+	listener, err := listen(a.Config.Agent.ManagementServiceAddress, tlsConf)
+	if err != nil {
+		return nil, fmt.Errorf("listening for management API failed: %w", err)
+	}
+
+	username := a.Config.Agent.ManagementBasicUsername
+	password := a.Config.Agent.ManagementBasicPassword
+	authHandler := internal.BasicAuthHandler(username, password, "telegraf", func(http.ResponseWriter) {})
+
+	m := &managementServer{
+		server: &http.Server{
+			Handler:           authHandler(a.managementHandler()),
+			ReadHeaderTimeout: 5 * time.Second,
+			TLSConfig:         tlsConf,
+		},
+		listener: listener,
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := m.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! [agent] Serving management API failed: %v", err)
+		}
+	}()
+	log.Printf("I! [agent] Serving management API on %s", listener.Addr())
+
+	return m, nil
+}
+
+// listen creates a listener for the given address in URL form, e.g.
+// "http://localhost:8091" or "unix:///path", using TLS if configured.
+func listen(address string, tlsConf *tls.Config) (net.Listener, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("parsing address failed: %w", err)
+	}
+
+	var network string
+	switch u.Scheme {
+	case "http", "https":
+		network = "tcp"
+		address = u.Host
+	case "unix":
+		network = u.Scheme
+		address = u.Path
+	case "tcp4", "tcp6", "tcp":
+		network = u.Scheme
+		address = u.Host
+	default:
+		return nil, fmt.Errorf("invalid scheme %q in address", u.Scheme)
+	}
+
+	if tlsConf != nil {
+		return tls.Listen(network, address, tlsConf)
+	}
+	return net.Listen(network, address)
+}
+
+// stop shuts down the management API.
+func (m *managementServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := m.server.Shutdown(ctx); err != nil {
+		log.Printf("E! [agent] Shutting down management API failed: %v", err)
+	}
+	m.wg.Wait()
+}
+
+// managementHandler returns the handler serving the management API.
+func (a *Agent) managementHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plugins", a.withUnits(listPlugins))
+	mux.HandleFunc("GET /outputs", a.withUnits(listOutputs))
+	mux.HandleFunc("POST /outputs/{id}/flush", a.withUnits(flushOutput))
+	mux.HandleFunc("POST /inputs/{id}/pause", a.withUnits(pauseInput))
+	mux.HandleFunc("POST /inputs/{id}/resume", a.withUnits(resumeInput))
+	mux.HandleFunc("PUT /plugins/{id}/log_level", a.withUnits(setLogLevel))
+	return mux
+}
+
+// withUnits calls the handler with the units of the running agent locked to
+// prevent concurrent reloads.
+func (a *Agent) withUnits(handler func(http.ResponseWriter, *http.Request, *runningUnits)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", internal.ProductToken())
+
+		a.unitsLock.Lock()
+		defer a.unitsLock.Unlock()
+
+		if a.units == nil {
+			http.Error(w, "agent is not running", http.StatusServiceUnavailable)
+			return
+		}
+		handler(w, r, a.units)
+	}
+}
+
+func listPlugins(w http.ResponseWriter, _ *http.Request, units *runningUnits) {
+	plugins := make([]pluginInfo, 0)
+	for _, input := range units.inputs.runningInputs() {
+		plugins = append(plugins, pluginInfo{
+			Type:     "inputs",
+			Name:     input.Config.Name,
+			ID:       input.ID(),
+			Alias:    input.Config.Alias,
+			Labels:   input.Config.Labels,
+			LogLevel: input.Log().Level().String(),
+			Paused:   input.Paused(),
+		})
+	}
+	pipelines := units.allPipelines()
+	for _, p := range pipelines {
+		processors := p.processors.runningProcessors()
+		if p.aggProcessors != nil {
+			processors = append(processors, p.aggProcessors.runningProcessors()...)
+		}
+		for _, processor := range processors {
+			plugins = append(plugins, pluginInfo{
+				Type:     "processors",
+				Name:     processor.Config.Name,
+				ID:       processor.ID(),
+				Alias:    processor.Config.Alias,
+				Labels:   processor.Config.Labels,
+				Pipeline: p.name(),
+				LogLevel: processor.Log().Level().String(),
+			})
+		}
+	}
+	for _, p := range pipelines {
+		if p.aggregators == nil {
+			continue
+		}
+		for _, aggregator := range p.aggregators.runningAggregators() {
+			plugins = append(plugins, pluginInfo{
+				Type:     "aggregators",
+				Name:     aggregator.Config.Name,
+				ID:       aggregator.ID(),
+				Alias:    aggregator.Config.Alias,
+				Labels:   aggregator.Config.Labels,
+				Pipeline: p.name(),
+				LogLevel: aggregator.Log().Level().String(),
+			})
+		}
+	}
+	for _, p := range pipelines {
+		for _, output := range p.outputs.runningOutputs() {
+			plugins = append(plugins, pluginInfo{
+				Type:     "outputs",
+				Name:     output.Config.Name,
+				ID:       output.ID(),
+				Alias:    output.Config.Alias,
+				Labels:   output.Config.Labels,
+				Pipeline: p.name(),
+				LogLevel: output.Log().Level().String(),
+			})
+		}
+	}
+
+	writeJSON(w, plugins)
+}
+
+func listOutputs(w http.ResponseWriter, _ *http.Request, units *runningUnits) {
+	outputs := make([]outputInfo, 0)
+	for _, p := range units.allPipelines() {
+		for _, output := range p.outputs.runningOutputs() {
+			stats := output.BufferStats()
+			info := outputInfo{
+				Name:            output.Config.Name,
+				ID:              output.ID(),
+				Alias:           output.Config.Alias,
+				Pipeline:        p.name(),
+				BufferLength:    output.BufferLength(),
+				BufferLimit:     stats.BufferLimit.Get(),
+				MetricsAdded:    stats.MetricsAdded.Get(),
+				MetricsWritten:  stats.MetricsWritten.Get(),
+				MetricsRejected: stats.MetricsRejected.Get(),
+				MetricsDropped:  stats.MetricsDropped.Get(),
+			}
+			if ts := output.LastErrorTime(); !ts.IsZero() {
+				info.LastError = &ts
+			}
+			outputs = append(outputs, info)
+		}
+	}
+
+	writeJSON(w, outputs)
+}
+
+func flushOutput(w http.ResponseWriter, r *http.Request, units *runningUnits) {
+	id := r.PathValue("id")
+
+	var found bool
+	for _, p := range units.allPipelines() {
+		if p.outputs.requestFlush(id) {
+			found = true
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("output %q not found", id), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// requestFlush triggers a flush of the outputs with the given ID and returns
+// true if any output was found.
+func (unit *outputUnit) requestFlush(id string) bool {
+	unit.Lock()
+	defer unit.Unlock()
+
+	var found bool
+	for _, output := range unit.outputs {
+		if output.ID() != id {
+			continue
+		}
+		if runner, ok := unit.runners[output]; ok {
+			runner.requestFlush()
+			found = true
+		}
+	}
+	return found
+}
+
+func pauseInput(w http.ResponseWriter, r *http.Request, units *runningUnits) {
+	inputs := findInputs(units, r.PathValue("id"))
+	if len(inputs) == 0 {
+		http.Error(w, fmt.Sprintf("input %q not found", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	for _, input := range inputs {
+		input.Pause()
+		log.Printf("I! [agent] Paused %s", input.LogName())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func resumeInput(w http.ResponseWriter, r *http.Request, units *runningUnits) {
+	inputs := findInputs(units, r.PathValue("id"))
+	if len(inputs) == 0 {
+		http.Error(w, fmt.Sprintf("input %q not found", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	for _, input := range inputs {
+		input.Resume()
+		log.Printf("I! [agent] Resumed %s", input.LogName())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findInputs(units *runningUnits, id string) []*models.RunningInput {
+	var inputs []*models.RunningInput
+	for _, input := range units.inputs.runningInputs() {
+		if input.ID() == id {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs
+}
+
+func setLogLevel(w http.ResponseWriter, r *http.Request, units *runningUnits) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding request failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	level := telegraf.LogLevelFromString(req.Level)
+	if level == telegraf.None {
+		http.Error(w, fmt.Sprintf("invalid log-level %q", req.Level), http.StatusBadRequest)
+		return
+	}
+
+	// Collect the loggers of all plugins with the given ID
+	id := r.PathValue("id")
+	var loggers []telegraf.Logger
+	for _, input := range units.inputs.runningInputs() {
+		if input.ID() == id {
+			loggers = append(loggers, input.Log())
+		}
+	}
+	for _, p := range units.allPipelines() {
+		processors := p.processors.runningProcessors()
+		if p.aggProcessors != nil {
+			processors = append(processors, p.aggProcessors.runningProcessors()...)
+		}
+		for _, processor := range processors {
+			if processor.ID() == id {
+				loggers = append(loggers, processor.Log())
+			}
+		}
+		if p.aggregators != nil {
+			for _, aggregator := range p.aggregators.runningAggregators() {
+				if aggregator.ID() == id {
+					loggers = append(loggers, aggregator.Log())
+				}
+			}
+		}
+		for _, output := range p.outputs.runningOutputs() {
+			if output.ID() == id {
+				loggers = append(loggers, output.Log())
+			}
+		}
+	}
+	if len(loggers) == 0 {
+		http.Error(w, fmt.Sprintf("plugin %q not found", id), http.StatusNotFound)
+		return
+	}
+
+	for _, l := range loggers {
+		setter, ok := l.(interface{ SetLevel(telegraf.LogLevel) })
+		if !ok {
+			http.Error(w, fmt.Sprintf("log-level of plugin %q cannot be changed", id), http.StatusConflict)
+			return
+		}
+		setter.SetLevel(level)
+	}
+	log.Printf("I! [agent] Changed log-level of plugin %q to %q", id, level.String())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! [agent] Encoding management API response failed: %v", err)
+	}
+}
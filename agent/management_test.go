@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestManagementAPI(t *testing.T) {
+	output := &reloadOutput{}
+	input := &reloadInput{name: "a"}
+	cfg := newReloadConfig(t, output, []*reloadInput{input}, &reloadProcessor{})
+	cfg.Inputs[0].Config.Labels = map[string]string{"env": "test"}
+	cfg.Aggregators = append(cfg.Aggregators, models.NewRunningAggregator(&countingAggregator{}, &models.AggregatorConfig{
+		Name:   "count",
+		ID:     "count",
+		Period: time.Hour,
+	}))
+	cfg.AggProcessors = append(cfg.AggProcessors, models.NewRunningProcessor(
+		processors.NewStreamingProcessorFromProcessor(&reloadProcessor{}),
+		&models.ProcessorConfig{Name: "tag", ID: "tag-agg"},
+	))
+	a := NewAgent(cfg)
+
+	server := httptest.NewServer(a.managementHandler())
+	defer server.Close()
+
+	// The API is unavailable until the agent runs
+	resp, err := http.Get(server.URL + "/plugins")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	require.Eventually(t, func() bool {
+		return output.count("a") > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// List the plugins
+	resp, err = http.Get(server.URL + "/plugins")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var plugins []pluginInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&plugins))
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, plugins, 5)
+	require.Equal(t, "inputs", plugins[0].Type)
+	require.Equal(t, "a", plugins[0].ID)
+	require.Equal(t, map[string]string{"env": "test"}, plugins[0].Labels)
+	require.Equal(t, "processors", plugins[1].Type)
+	require.Equal(t, "tag", plugins[1].ID)
+	require.Equal(t, "processors", plugins[2].Type)
+	require.Equal(t, "tag-agg", plugins[2].ID)
+	require.Equal(t, "aggregators", plugins[3].Type)
+	require.Equal(t, "outputs", plugins[4].Type)
+
+	// Check the output buffers
+	resp, err = http.Get(server.URL + "/outputs")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var outputs []outputInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&outputs))
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, outputs, 1)
+	require.Equal(t, "test", outputs[0].ID)
+	require.Positive(t, outputs[0].MetricsWritten)
+	require.Nil(t, outputs[0].LastError)
+
+	// Flush the output
+	resp, err = http.Post(server.URL+"/outputs/test/flush", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/outputs/unknown/flush", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Pause and resume the input
+	resp, err = http.Post(server.URL+"/inputs/a/pause", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.True(t, a.Config.Inputs[0].Paused())
+
+	resp, err = http.Post(server.URL+"/inputs/a/resume", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.False(t, a.Config.Inputs[0].Paused())
+
+	// Change the log-level
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/plugins/test/log_level", strings.NewReader(`{"level": "trace"}`))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, telegraf.Trace, a.Config.Outputs[0].Log().Level())
+
+	req, err = http.NewRequest(http.MethodPut, server.URL+"/plugins/test/log_level", strings.NewReader(`{"level": "foo"}`))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestGatherPausedInput(t *testing.T) {
+	output := &reloadOutput{}
+	input := &reloadInput{name: "a"}
+	cfg := newReloadConfig(t, output, []*reloadInput{input}, nil)
+	cfg.Inputs[0].Pause()
+	a := NewAgent(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	require.Zero(t, input.gathered())
+
+	cfg.Inputs[0].Resume()
+	require.Eventually(t, func() bool {
+		return input.gathered() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	wg.Wait()
+}
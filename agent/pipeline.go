@@ -0,0 +1,365 @@
+package agent
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// pipelineUnit is the chain of processors, aggregators and outputs of a
+// pipeline. The pipeline is fed by the inputs selected for the pipeline and
+// by the upstream pipelines fanning out to it. The default pipeline has no
+// configuration and receives the metrics of all inputs not selected by any
+// named pipeline.
+//
+//  ______     ┌────────────┐     ┌─────────────┐     ┌────────┐     ┌─────────┐
+// ()_____)──▶ │ Processors │──▶ │ Aggregators │──▶ │ Fanout │──▶ │ Outputs │
+//             └────────────┘     └─────────────┘     └────────┘     └─────────┘
+//                                                         │
+//                                                         └──▶ downstream pipelines
+
+type pipelineUnit struct {
+	config *config.Pipeline
+	src    chan<- telegraf.Metric
+
+	processors    *processorChain
+	aggregators   *aggregatorUnit
+	aggProcessors *processorChain
+	outputs       *outputUnit
+
+	// producers are the stages writing to the source channel of the
+	// pipeline, i.e. the inputs and the upstream pipelines. The source
+	// channel is closed after all producers are done.
+	producers sync.WaitGroup
+}
+
+// name returns the name of the pipeline which is empty for the default
+// pipeline.
+func (p *pipelineUnit) name() string {
+	if p.config == nil {
+		return ""
+	}
+	return p.config.Name
+}
+
+// startPipelines starts the default and all named pipelines. The sink
+// function provides the destination of the metrics leaving a pipeline, e.g.
+// the pipeline's outputs. Pipelines are started downstream first as the
+// upstream pipelines fan out to their source.
+func (a *Agent) startPipelines(sink func(*pipelineUnit) (chan<- telegraf.Metric, error)) ([]*pipelineUnit, error) {
+	pipelines := make([]*pipelineUnit, 0, len(a.Config.Pipelines)+1)
+	pipelines = append(pipelines, &pipelineUnit{})
+	for _, cfg := range a.Config.Pipelines {
+		pipelines = append(pipelines, &pipelineUnit{config: cfg})
+	}
+
+	var start func(p *pipelineUnit) error
+	start = func(p *pipelineUnit) error {
+		if p.src != nil {
+			return nil
+		}
+
+		var downstream []*pipelineUnit
+		if p.config != nil {
+			for _, name := range p.config.Fanout {
+				idx := slices.IndexFunc(pipelines, func(d *pipelineUnit) bool { return d.name() == name })
+				if idx < 0 {
+					continue
+				}
+				if err := start(pipelines[idx]); err != nil {
+					return err
+				}
+				downstream = append(downstream, pipelines[idx])
+			}
+		}
+
+		dst, err := sink(p)
+		if err != nil {
+			return err
+		}
+		return a.startPipeline(p, dst, downstream)
+	}
+
+	for _, p := range pipelines {
+		if err := start(p); err != nil {
+			return nil, err
+		}
+	}
+	return pipelines, nil
+}
+
+// startPipeline starts the processors and aggregators of the given pipeline
+// writing to the destination channel and to the downstream pipelines.
+func (a *Agent) startPipeline(p *pipelineUnit, dst chan<- telegraf.Metric, downstream []*pipelineUnit) error {
+	name := p.name()
+
+	next := dst
+	if len(downstream) > 0 {
+		next = startFanout(next, downstream)
+	}
+
+	// The processor chains are always created, even without processors, to
+	// allow adding processors on configuration changes.
+	var aggregators []*models.RunningAggregator
+	for _, agg := range a.Config.Aggregators {
+		if agg.Config.Pipeline == name {
+			aggregators = append(aggregators, agg)
+		}
+	}
+	if len(aggregators) != 0 {
+		aggC := next
+		if !*a.Config.Agent.SkipProcessorsAfterAggregators {
+			var err error
+			aggC, p.aggProcessors, err = a.startProcessors(next, pipelineProcessors(a.Config.AggProcessors, name))
+			if err != nil {
+				return err
+			}
+		}
+
+		next, p.aggregators = a.startAggregators(aggC, next, aggregators)
+		p.aggregators.persistent = a.Config.Persister != nil
+	}
+
+	var err error
+	p.src, p.processors, err = a.startProcessors(next, pipelineProcessors(a.Config.Processors, name))
+	return err
+}
+
+// runPipeline runs the units of the pipeline in the background. The pipeline
+// stops once all producers are done.
+func (a *Agent) runPipeline(wg *sync.WaitGroup, startTime time.Time, p *pipelineUnit) {
+	if p.outputs != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runOutputs(p.outputs)
+		}()
+	}
+
+	if p.aggregators != nil {
+		if p.aggProcessors != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				a.runProcessors(p.aggProcessors)
+			}()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runAggregators(startTime, p.aggregators)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runProcessors(p.processors)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.producers.Wait()
+		close(p.src)
+		if p.config != nil {
+			log.Printf("D! [agent] Pipeline %q source channel closed", p.config.Name)
+		}
+	}()
+}
+
+// pipelineProcessors returns the processors of the pipeline with the given
+// name keeping their order.
+func pipelineProcessors(processors models.RunningProcessors, name string) models.RunningProcessors {
+	selected := make(models.RunningProcessors, 0, len(processors))
+	for _, processor := range processors {
+		if processor.Config.Pipeline == name {
+			selected = append(selected, processor)
+		}
+	}
+	return selected
+}
+
+// pipelineOutputs returns the outputs of the pipeline with the given name.
+func pipelineOutputs(outputs []*models.RunningOutput, name string) []*models.RunningOutput {
+	selected := make([]*models.RunningOutput, 0, len(outputs))
+	for _, output := range outputs {
+		if output.Config.Pipeline == name {
+			selected = append(selected, output)
+		}
+	}
+	return selected
+}
+
+// startFanout returns a channel passing the metrics to the destination and a
+// copy of the metrics to the downstream pipelines. The downstream pipelines
+// are released once the returned channel is closed.
+func startFanout(dst chan<- telegraf.Metric, downstream []*pipelineUnit) chan<- telegraf.Metric {
+	dsts := make([]chan<- telegraf.Metric, 0, len(downstream)+1)
+	for _, d := range downstream {
+		d.producers.Add(1)
+		dsts = append(dsts, d.src)
+	}
+	dsts = append(dsts, dst)
+
+	src := make(chan telegraf.Metric, 100)
+	go func() {
+		fanout(src, dsts)
+		close(dst)
+		for _, d := range downstream {
+			d.producers.Done()
+		}
+	}()
+	return src
+}
+
+// fanout passes the metrics from the source to all destinations until the
+// source is closed.
+func fanout(src <-chan telegraf.Metric, dsts []chan<- telegraf.Metric) {
+	for m := range src {
+		for i, dst := range dsts {
+			if i == len(dsts)-1 {
+				dst <- m
+			} else {
+				dst <- m.Copy()
+			}
+		}
+	}
+}
+
+// pipelineRouter passes the metrics of each input to the pipelines selecting
+// the input. Inputs not selected by any named pipeline feed the default
+// pipeline.
+//
+// ┌───────┐                 ┌──────────┐
+// │ Input │───────────────▶ │ Pipeline │
+// └───────┘                 └──────────┘
+// ┌───────┐     ┌─────┐     ┌──────────┐
+// │ Input │──▶ │ Fan │──▶ │ Pipeline │
+// └───────┘     └─────┘ │   └──────────┘
+//                       │   ┌──────────┐
+//                       └─▶ │ Pipeline │
+//                           └──────────┘
+
+type pipelineRouter struct {
+	pipelines []*pipelineUnit
+
+	sync.Mutex
+	fanouts map[string]chan<- telegraf.Metric
+	wg      sync.WaitGroup
+}
+
+// newPipelineRouter creates a router for the given pipelines where the first
+// pipeline is the default one. The router is a producer of all pipelines.
+func newPipelineRouter(pipelines []*pipelineUnit) *pipelineRouter {
+	for _, p := range pipelines {
+		p.producers.Add(1)
+	}
+	return &pipelineRouter{
+		pipelines: pipelines,
+		fanouts:   make(map[string]chan<- telegraf.Metric),
+	}
+}
+
+// route returns the channel the given input writes to. Inputs feeding more
+// than one pipeline share a fan-out channel per set of pipelines.
+func (r *pipelineRouter) route(input *models.RunningInput) chan<- telegraf.Metric {
+	var selected []*pipelineUnit
+	var names []string
+	for _, p := range r.pipelines[1:] {
+		if p.config.SelectsInput(input) {
+			selected = append(selected, p)
+			names = append(names, p.config.Name)
+		}
+	}
+
+	switch len(selected) {
+	case 0:
+		return r.pipelines[0].src
+	case 1:
+		return selected[0].src
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	key := strings.Join(names, "\x00")
+	if dst, found := r.fanouts[key]; found {
+		return dst
+	}
+
+	dsts := make([]chan<- telegraf.Metric, 0, len(selected))
+	for _, p := range selected {
+		dsts = append(dsts, p.src)
+	}
+	src := make(chan telegraf.Metric, 100)
+	r.fanouts[key] = src
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		fanout(src, dsts)
+	}()
+	return src
+}
+
+// outputs returns the outputs receiving the metrics of the given input
+// including the outputs of downstream pipelines.
+func (r *pipelineRouter) outputs(input *models.RunningInput) []*models.RunningOutput {
+	var queue []*pipelineUnit
+	for _, p := range r.pipelines[1:] {
+		if p.config.SelectsInput(input) {
+			queue = append(queue, p)
+		}
+	}
+	if len(queue) == 0 {
+		queue = append(queue, r.pipelines[0])
+	}
+
+	var outputs []*models.RunningOutput
+	seen := make(map[*pipelineUnit]bool, len(r.pipelines))
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if p.outputs != nil {
+			outputs = append(outputs, p.outputs.runningOutputs()...)
+		}
+		if p.config == nil {
+			continue
+		}
+		for _, name := range p.config.Fanout {
+			idx := slices.IndexFunc(r.pipelines, func(d *pipelineUnit) bool { return d.name() == name })
+			if idx >= 0 {
+				queue = append(queue, r.pipelines[idx])
+			}
+		}
+	}
+	return outputs
+}
+
+// close releases the pipelines after passing on all pending metrics. It must
+// only be called after all inputs are stopped.
+func (r *pipelineRouter) close() {
+	r.Lock()
+	for _, src := range r.fanouts {
+		close(src)
+	}
+	r.fanouts = make(map[string]chan<- telegraf.Metric)
+	r.Unlock()
+
+	r.wg.Wait()
+	for _, p := range r.pipelines {
+		p.producers.Done()
+	}
+}
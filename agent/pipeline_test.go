@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestPipelines(t *testing.T) {
+	defaultOutput := &reloadOutput{}
+	cfg := newReloadConfig(t, defaultOutput, []*reloadInput{{name: "a"}, {name: "b"}, {name: "c"}}, nil)
+	cfg.Pipelines = []*config.Pipeline{
+		{Name: "team", Inputs: []string{"b"}, Fanout: []string{"archive"}},
+		{Name: "archive", Inputs: []string{"a", "b"}},
+	}
+	cfg.Processors = append(cfg.Processors, models.NewRunningProcessor(
+		processors.NewStreamingProcessorFromProcessor(&reloadProcessor{}),
+		&models.ProcessorConfig{Name: "tag", ID: "tag", Pipeline: "team"},
+	))
+
+	teamOutput := &reloadOutput{}
+	ro, err := models.NewRunningOutput(teamOutput, &models.OutputConfig{Name: "test", ID: "team", Pipeline: "team"}, 1000, 10000)
+	require.NoError(t, err)
+	cfg.Outputs = append(cfg.Outputs, ro)
+
+	archiveOutput := &reloadOutput{}
+	ro, err = models.NewRunningOutput(archiveOutput, &models.OutputConfig{Name: "test", ID: "archive", Pipeline: "archive"}, 1000, 10000)
+	require.NoError(t, err)
+	cfg.Outputs = append(cfg.Outputs, ro)
+
+	a := NewAgent(cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	// The archive receives the metrics of input "b" directly and processed
+	// by the team pipeline
+	require.Eventually(t, func() bool {
+		return defaultOutput.count("c") > 0 && teamOutput.tagged("b") &&
+			archiveOutput.count("a") > 0 && archiveOutput.tagged("b") &&
+			archiveOutput.count("b") > 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Pipelines cannot be reloaded
+	require.ErrorIs(t, a.Reload(newReloadConfig(t, &reloadOutput{}, nil, nil)), ErrRestartRequired)
+
+	cancel()
+	wg.Wait()
+
+	// Inputs selected by a pipeline do not feed the default pipeline
+	require.Zero(t, defaultOutput.count("a"))
+	require.Zero(t, defaultOutput.count("b"))
+	require.Zero(t, teamOutput.count("a"))
+	require.Zero(t, teamOutput.count("c"))
+	require.Zero(t, archiveOutput.count("c"))
+	require.False(t, defaultOutput.tagged("c"))
+}
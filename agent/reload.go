@@ -0,0 +1,604 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/snmp"
+)
+
+// ErrRestartRequired is returned by Reload if the configuration cannot be
+// applied to the running agent and a full restart of the agent is required.
+var ErrRestartRequired = errors.New("restart required")
+
+// runningUnits are the units of a running agent.
+type runningUnits struct {
+	ctx       context.Context
+	startTime time.Time
+
+	inputs *inputUnit
+
+	// default pipeline and named pipelines
+	*pipelineUnit
+	pipelines []*pipelineUnit
+
+	// secrets watches the secrets of the plugins for changes, nil if
+	// disabled
+	secrets *secretWatcher
+}
+
+// chainUpdate is a request to exchange the processors of a running chain.
+type chainUpdate struct {
+	processors models.RunningProcessors
+	result     chan error
+}
+
+// allPipelines returns the default pipeline followed by the named pipelines.
+func (u *runningUnits) allPipelines() []*pipelineUnit {
+	return append([]*pipelineUnit{u.pipelineUnit}, u.pipelines...)
+}
+
+func (a *Agent) setRunningUnits(units *runningUnits) {
+	a.unitsLock.Lock()
+	a.units = units
+	a.unitsLock.Unlock()
+}
+
+// plugin is the common interface of all running plugin models.
+type plugin interface {
+	comparable
+	ID() string
+	LogName() string
+}
+
+// pluginDiff are the changes between the running and updated plugins of one
+// plugin type. Plugins are compared by their ID so a plugin with changed
+// settings is removed and added again.
+type pluginDiff[T plugin] struct {
+	// merged is the updated list of plugins, where unchanged plugins are
+	// replaced by their running instance
+	merged []T
+	// added and removed plugins in configuration order
+	added   []T
+	removed []T
+	// unused are the instances of the updated list of unchanged plugins
+	unused []T
+}
+
+func diffPlugins[T plugin](running, updated []T) pluginDiff[T] {
+	available := make(map[string][]T, len(running))
+	for _, p := range running {
+		available[p.ID()] = append(available[p.ID()], p)
+	}
+
+	d := pluginDiff[T]{merged: make([]T, 0, len(updated))}
+	kept := make(map[T]bool, len(running))
+	for _, p := range updated {
+		id := p.ID()
+		if candidates := available[id]; len(candidates) > 0 {
+			available[id] = candidates[1:]
+			kept[candidates[0]] = true
+			d.merged = append(d.merged, candidates[0])
+			d.unused = append(d.unused, p)
+			continue
+		}
+		d.merged = append(d.merged, p)
+		d.added = append(d.added, p)
+	}
+
+	for _, p := range running {
+		if !kept[p] {
+			d.removed = append(d.removed, p)
+		}
+	}
+
+	return d
+}
+
+func (d *pluginDiff[T]) changed() bool {
+	return len(d.added) > 0 || len(d.removed) > 0
+}
+
+// destroySecrets destroys the secrets of the removed plugins and of the
+// unused instances of the unchanged plugins as the agent does not use them
+// anymore.
+func (d *pluginDiff[T]) destroySecrets() {
+	for _, p := range d.removed {
+		config.DestroySecrets(p)
+	}
+	for _, p := range d.unused {
+		config.DestroySecrets(p)
+	}
+}
+
+func (d *pluginDiff[T]) log() {
+	for _, p := range d.removed {
+		log.Printf("I! [agent] Removing %s", p.LogName())
+	}
+	for _, p := range d.added {
+		log.Printf("I! [agent] Adding %s", p.LogName())
+	}
+}
+
+// Reload applies the given configuration to the running agent. Only plugins
+// that were added, removed or changed are started or stopped, all other
+// plugins keep running with their current state. The configuration must be
+// loaded but not initialized. Its plugins are owned by the agent after
+// calling this function.
+//
+// ErrRestartRequired is returned if the configuration cannot be applied to
+// the running agent, e.g. because the agent settings changed, and the agent
+// must be restarted for the configuration to take effect.
+func (a *Agent) Reload(cfg *config.Config) error {
+	a.unitsLock.Lock()
+	defer a.unitsLock.Unlock()
+
+	units := a.units
+	if units == nil || units.ctx.Err() != nil {
+		stopRunningOutputs(cfg.Outputs)
+		return fmt.Errorf("%w: agent is not running", ErrRestartRequired)
+	}
+
+	// Apply the same defaults as the running agent
+	if cfg.Agent.SkipProcessorsAfterAggregators == nil {
+		skipProcessorsAfterAggregators := false
+		cfg.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+	}
+
+	if err := a.Config.ReloadCompatible(cfg); err != nil {
+		stopRunningOutputs(cfg.Outputs)
+		return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+	}
+	if len(units.pipelines) > 0 || len(cfg.Pipelines) > 0 {
+		stopRunningOutputs(cfg.Outputs)
+		return fmt.Errorf("%w: pipelines configured", ErrRestartRequired)
+	}
+	if units.aggregators == nil && len(cfg.Aggregators) > 0 {
+		stopRunningOutputs(cfg.Outputs)
+		return fmt.Errorf("%w: aggregators added", ErrRestartRequired)
+	}
+
+	inputs := diffPlugins(units.inputs.runningInputs(), cfg.Inputs)
+	processors := diffPlugins(units.processors.runningProcessors(), cfg.Processors)
+	outputs := diffPlugins(units.outputs.runningOutputs(), cfg.Outputs)
+	var aggregators pluginDiff[*models.RunningAggregator]
+	if units.aggregators != nil {
+		aggregators = diffPlugins(units.aggregators.runningAggregators(), cfg.Aggregators)
+	}
+	var aggProcessors pluginDiff[*models.RunningProcessor]
+	if units.aggProcessors != nil {
+		aggProcessors = diffPlugins(units.aggProcessors.runningProcessors(), cfg.AggProcessors)
+	}
+
+	if !inputs.changed() && !processors.changed() && !aggregators.changed() &&
+		!aggProcessors.changed() && !outputs.changed() {
+		log.Printf("I! [agent] No plugin changes found in configuration")
+		stopRunningOutputs(cfg.Outputs)
+		destroySecrets(&inputs, &processors, &aggregators, &aggProcessors, &outputs)
+		return nil
+	}
+	inputs.log()
+	processors.log()
+	aggregators.log()
+	aggProcessors.log()
+	outputs.log()
+
+	// Prepare the new plugins. Up to here the running agent is untouched
+	// so we can bail out on errors.
+	if err := a.initAddedPlugins(&inputs, &processors, &aggregators, &aggProcessors, &outputs); err != nil {
+		stopRunningOutputs(cfg.Outputs)
+		return err
+	}
+	if err := a.registerAddedPlugins(&inputs, &processors, &aggregators, &aggProcessors, &outputs); err != nil {
+		stopRunningOutputs(cfg.Outputs)
+		return err
+	}
+	for i, output := range outputs.added {
+		if err := a.connectOutput(units.ctx, output); err != nil {
+			a.unregisterPlugins(inputs.added, processors.added, aggregators.added, aggProcessors.added, outputs.added)
+			stopRunningOutputs(outputs.added[:i+1])
+			stopRunningOutputs(outputs.unused)
+			return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+		}
+	}
+	stopRunningOutputs(outputs.unused)
+
+	// Apply the changes from the outputs towards the inputs. Failures from
+	// here on leave the agent in a partially updated state so the agent must
+	// be restarted.
+	if err := a.reloadOutputs(units.outputs, &outputs); err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+	}
+	if units.aggregators != nil {
+		if err := a.reloadAggregators(units.aggregators, &aggregators); err != nil {
+			return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+		}
+	}
+	if units.aggProcessors != nil {
+		if err := units.aggProcessors.update(aggProcessors.merged); err != nil {
+			return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+		}
+	}
+	if err := units.processors.update(processors.merged); err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+	}
+	if err := a.reloadInputs(units, &inputs); err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartRequired, err)
+	}
+	a.unregisterPlugins(inputs.removed, processors.removed, aggregators.removed, aggProcessors.removed, outputs.removed)
+	destroySecrets(&inputs, &processors, &aggregators, &aggProcessors, &outputs)
+
+	// Update the plugins of the running configuration. All other settings
+	// are equal and are accessed by the running units so we must not
+	// replace the configuration itself.
+	a.Config.Inputs = units.inputs.runningInputs()
+	a.Config.Processors = processors.merged
+	a.Config.Outputs = units.outputs.runningOutputs()
+	if units.aggregators != nil {
+		a.Config.Aggregators = units.aggregators.runningAggregators()
+	}
+	if units.aggProcessors != nil {
+		a.Config.AggProcessors = aggProcessors.merged
+	}
+
+	// Start watching the secrets of added plugins
+	if units.secrets != nil {
+		units.secrets.trigger()
+	}
+
+	log.Printf("I! [agent] Configuration reloaded")
+	return nil
+}
+
+// destroySecrets destroys the secrets of the plugins no longer used after
+// applying the given changes.
+func destroySecrets(
+	inputs *pluginDiff[*models.RunningInput],
+	procs *pluginDiff[*models.RunningProcessor],
+	aggregators *pluginDiff[*models.RunningAggregator],
+	aggProcs *pluginDiff[*models.RunningProcessor],
+	outputs *pluginDiff[*models.RunningOutput],
+) {
+	inputs.destroySecrets()
+	procs.destroySecrets()
+	aggregators.destroySecrets()
+	aggProcs.destroySecrets()
+	outputs.destroySecrets()
+}
+
+// initAddedPlugins runs the Init function on all added plugins.
+func (a *Agent) initAddedPlugins(
+	inputs *pluginDiff[*models.RunningInput],
+	procs *pluginDiff[*models.RunningProcessor],
+	aggregators *pluginDiff[*models.RunningAggregator],
+	aggProcs *pluginDiff[*models.RunningProcessor],
+	outputs *pluginDiff[*models.RunningOutput],
+) error {
+	for _, input := range inputs.added {
+		// Share the snmp translator setting with plugins that need it.
+		if tp, ok := input.Input.(snmp.TranslatorPlugin); ok {
+			tp.SetTranslator(a.Config.Agent.SnmpTranslator)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %w", input.LogName(), err)
+		}
+	}
+	for _, processor := range procs.added {
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+		}
+	}
+	for _, aggregator := range aggregators.added {
+		if err := aggregator.Init(); err != nil {
+			return fmt.Errorf("could not initialize aggregator %s: %w", aggregator.LogName(), err)
+		}
+	}
+	for _, processor := range aggProcs.added {
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
+		}
+	}
+	for _, output := range outputs.added {
+		if err := output.Init(); err != nil {
+			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// registerAddedPlugins registers the added stateful plugins with the
+// persister. On error all plugins registered by this function are removed
+// again.
+func (a *Agent) registerAddedPlugins(
+	inputs *pluginDiff[*models.RunningInput],
+	procs *pluginDiff[*models.RunningProcessor],
+	aggregators *pluginDiff[*models.RunningAggregator],
+	aggProcs *pluginDiff[*models.RunningProcessor],
+	outputs *pluginDiff[*models.RunningOutput],
+) error {
+	if a.Config.Persister == nil {
+		return nil
+	}
+
+	var registered []string
+	register := func(id, name string, p interface {
+		StatefulPlugin() (telegraf.StatefulPlugin, bool)
+	}) error {
+		plugin, ok := p.StatefulPlugin()
+		if !ok {
+			return nil
+		}
+		if err := a.Config.Persister.Register(id, plugin); err != nil {
+			for _, id := range registered {
+				a.Config.Persister.Unregister(id)
+			}
+			return fmt.Errorf("could not register %s: %w", name, err)
+		}
+		registered = append(registered, id)
+		return nil
+	}
+
+	for _, input := range inputs.added {
+		if err := register(input.ID(), input.LogName(), input); err != nil {
+			return err
+		}
+	}
+	for _, processor := range procs.added {
+		if err := register(processor.ID(), processor.LogName(), processor); err != nil {
+			return err
+		}
+	}
+	for _, aggregator := range aggregators.added {
+		if err := register(aggregator.ID(), aggregator.LogName(), aggregator); err != nil {
+			return err
+		}
+	}
+	for _, processor := range aggProcs.added {
+		if err := register(processor.ID(), processor.LogName(), processor); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputs.added {
+		if err := register(output.ID(), output.LogName(), output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unregisterPlugins removes the given stateful plugins from the persister.
+func (a *Agent) unregisterPlugins(
+	inputs []*models.RunningInput,
+	procs []*models.RunningProcessor,
+	aggregators []*models.RunningAggregator,
+	aggProcs []*models.RunningProcessor,
+	outputs []*models.RunningOutput,
+) {
+	if a.Config.Persister == nil {
+		return
+	}
+
+	unregister := func(id string, p interface {
+		StatefulPlugin() (telegraf.StatefulPlugin, bool)
+	}) {
+		if _, ok := p.StatefulPlugin(); ok {
+			a.Config.Persister.Unregister(id)
+		}
+	}
+	for _, input := range inputs {
+		unregister(input.ID(), input)
+	}
+	for _, processor := range slices.Concat(procs, aggProcs) {
+		unregister(processor.ID(), processor)
+	}
+	for _, aggregator := range aggregators {
+		unregister(aggregator.ID(), aggregator)
+	}
+	for _, output := range outputs {
+		unregister(output.ID(), output)
+	}
+}
+
+// reloadOutputs starts the flush loop of the added outputs and flushes and
+// closes the removed outputs.
+func (a *Agent) reloadOutputs(unit *outputUnit, diff *pluginDiff[*models.RunningOutput]) error {
+	unit.Lock()
+	if unit.ctx.Err() != nil {
+		unit.Unlock()
+		stopRunningOutputs(diff.added)
+		return errors.New("outputs stopped")
+	}
+	for _, output := range diff.added {
+		a.runOutput(unit, output)
+	}
+	unit.outputs = slices.DeleteFunc(slices.Concat(unit.outputs, diff.added), func(o *models.RunningOutput) bool {
+		return slices.Contains(diff.removed, o)
+	})
+	if err := models.LinkDeadLetters(unit.outputs); err != nil {
+		log.Printf("E! [agent] Linking dead-letter outputs failed: %v", err)
+	}
+	models.LinkFailoverGroups(unit.outputs)
+	runners := make([]*pluginRunner, 0, len(diff.removed))
+	for _, output := range diff.removed {
+		output.LeaveFailoverGroup()
+		runners = append(runners, unit.runners[output])
+		delete(unit.runners, output)
+	}
+	unit.Unlock()
+
+	// Stopping the runner writes the remaining metrics one last time
+	for i, runner := range runners {
+		if runner != nil {
+			runner.stop()
+		}
+		diff.removed[i].Close()
+	}
+	return nil
+}
+
+// reloadAggregators starts the added aggregators and stops the removed
+// aggregators after pushing their current aggregates.
+func (a *Agent) reloadAggregators(unit *aggregatorUnit, diff *pluginDiff[*models.RunningAggregator]) error {
+	unit.Lock()
+	if unit.ctx.Err() != nil {
+		unit.Unlock()
+		return errors.New("aggregators stopped")
+	}
+	for _, agg := range diff.added {
+		since, until := updateWindow(time.Now(), a.Config.Agent.RoundInterval, agg.Period())
+		agg.UpdateWindow(since, until)
+		a.runAggregator(unit, agg)
+	}
+	unit.aggregators = slices.DeleteFunc(slices.Concat(unit.aggregators, diff.added), func(agg *models.RunningAggregator) bool {
+		return slices.Contains(diff.removed, agg)
+	})
+	runners := make([]*pluginRunner, 0, len(diff.removed))
+	for _, agg := range diff.removed {
+		runners = append(runners, unit.runners[agg])
+		delete(unit.runners, agg)
+	}
+	unit.Unlock()
+
+	// Stopping the runner pushes the aggregates one last time
+	for _, runner := range runners {
+		if runner != nil {
+			runner.stop()
+		}
+	}
+	return nil
+}
+
+// reloadInputs starts the added inputs and stops the removed ones.
+func (a *Agent) reloadInputs(units *runningUnits, diff *pluginDiff[*models.RunningInput]) error {
+	unit := units.inputs
+
+	unit.Lock()
+	defer unit.Unlock()
+
+	if units.ctx.Err() != nil {
+		return errors.New("inputs stopped")
+	}
+
+	for _, input := range diff.removed {
+		if runner, found := unit.runners[input]; found {
+			delete(unit.runners, input)
+			runner.stop()
+		}
+		unit.backpressure[input].release()
+		delete(unit.backpressure, input)
+		input.Stop()
+	}
+	unit.inputs = slices.DeleteFunc(unit.inputs, func(input *models.RunningInput) bool {
+		return slices.Contains(diff.removed, input)
+	})
+
+	for _, input := range diff.added {
+		if err := startInput(unit.router.route(input), a.inputBackpressure(unit, input), input); err != nil {
+			var fatalErr *internal.FatalError
+			if errors.As(err, &fatalErr) {
+				log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
+				continue
+			}
+			return fmt.Errorf("starting input %s: %w", input.LogName(), err)
+		}
+		if err := input.Probe(); err != nil {
+			log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
+			input.Stop()
+			continue
+		}
+		a.runInput(units.ctx, units.startTime, unit, input)
+		unit.inputs = append(unit.inputs, input)
+	}
+	return nil
+}
+
+func (unit *inputUnit) runningInputs() []*models.RunningInput {
+	unit.Lock()
+	defer unit.Unlock()
+	return slices.Clone(unit.inputs)
+}
+
+func (unit *aggregatorUnit) runningAggregators() []*models.RunningAggregator {
+	unit.RLock()
+	defer unit.RUnlock()
+	return slices.Clone(unit.aggregators)
+}
+
+func (unit *outputUnit) runningOutputs() []*models.RunningOutput {
+	unit.RLock()
+	defer unit.RUnlock()
+	return slices.Clone(unit.outputs)
+}
+
+// runningProcessors returns the processors of the chain. Updates are only
+// applied with the agent's unit lock held, so the units are stable here.
+func (c *processorChain) runningProcessors() models.RunningProcessors {
+	procs := make(models.RunningProcessors, 0, len(c.units))
+	for _, unit := range c.units {
+		procs = append(procs, unit.processor)
+	}
+	return procs
+}
+
+// update exchanges the processors of the running chain. Processors already
+// part of the chain keep running, all others are started or stopped.
+func (c *processorChain) update(processors models.RunningProcessors) error {
+	u := &chainUpdate{processors: processors, result: make(chan error, 1)}
+	select {
+	case c.updates <- u:
+	case <-c.done:
+		return errors.New("processor chain stopped")
+	}
+	return <-u.result
+}
+
+// apply rebuilds the chain with the given processors and must only be called
+// by the feeder. The current chain is drained before the new chain is started
+// to preserve the order of the metrics.
+func (c *processorChain) apply(processors models.RunningProcessors) error {
+	running := make(map[*models.RunningProcessor]*processorUnit, len(c.units))
+	for _, unit := range c.units {
+		running[unit.processor] = unit
+	}
+
+	units, err := c.buildUnits(processors, running)
+	if err != nil {
+		return err
+	}
+
+	if len(c.units) > 0 {
+		// Rewire the kept processors to their new destination instead of
+		// stopping them and avoid closing the chain's destination.
+		for _, unit := range units {
+			if old, found := running[unit.processor]; found {
+				old.keep = true
+				old.next = unit.next
+			}
+		}
+		c.units[len(c.units)-1].closeDst = false
+
+		close(c.entry)
+		for _, unit := range c.units {
+			<-unit.done
+		}
+	}
+
+	c.units = units
+	c.entry = c.dst
+	if len(units) > 0 {
+		c.entry = units[0].src
+	}
+	for _, unit := range units {
+		c.runUnit(unit)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestDiffPlugins(t *testing.T) {
+	a := &models.RunningInput{Config: &models.InputConfig{Name: "a", ID: "a"}}
+	b := &models.RunningInput{Config: &models.InputConfig{Name: "b", ID: "b"}}
+	c := &models.RunningInput{Config: &models.InputConfig{Name: "c", ID: "c"}}
+	aNew := &models.RunningInput{Config: &models.InputConfig{Name: "a", ID: "a"}}
+	bNew := &models.RunningInput{Config: &models.InputConfig{Name: "b", ID: "b"}}
+	bChanged := &models.RunningInput{Config: &models.InputConfig{Name: "b", ID: "b2"}}
+
+	diff := diffPlugins([]*models.RunningInput{a, b, c}, []*models.RunningInput{bNew, aNew})
+	require.Equal(t, []*models.RunningInput{b, a}, diff.merged)
+	require.Equal(t, []*models.RunningInput{bNew, aNew}, diff.unused)
+	require.Empty(t, diff.added)
+	require.Equal(t, []*models.RunningInput{c}, diff.removed)
+
+	diff = diffPlugins([]*models.RunningInput{a, b}, []*models.RunningInput{aNew, bChanged})
+	require.Equal(t, []*models.RunningInput{a, bChanged}, diff.merged)
+	require.Equal(t, []*models.RunningInput{aNew}, diff.unused)
+	require.Equal(t, []*models.RunningInput{bChanged}, diff.added)
+	require.Equal(t, []*models.RunningInput{b}, diff.removed)
+
+	// Duplicate plugins are matched one by one
+	diff = diffPlugins([]*models.RunningInput{a}, []*models.RunningInput{aNew, aNew})
+	require.Equal(t, []*models.RunningInput{a, aNew}, diff.merged)
+	require.Equal(t, []*models.RunningInput{aNew}, diff.added)
+	require.Empty(t, diff.removed)
+}
+
+func TestReloadChangedPlugins(t *testing.T) {
+	output := &reloadOutput{}
+	inputA := &reloadInput{name: "a", Password: config.NewSecret([]byte("password"))}
+
+	cfg := newReloadConfig(t, output, []*reloadInput{inputA}, nil)
+	a := NewAgent(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	require.Eventually(t, func() bool {
+		return output.count("a") > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Keep input "a", add input "b" and a processor tagging all metrics
+	outputNew := &reloadOutput{}
+	inputANew := &reloadInput{name: "a", Password: config.NewSecret([]byte("password"))}
+	inputB := &reloadInput{name: "b"}
+	cfgNew := newReloadConfig(t, outputNew, []*reloadInput{inputANew, inputB}, &reloadProcessor{})
+	require.NoError(t, a.Reload(cfgNew))
+
+	require.Eventually(t, func() bool {
+		return output.count("b") > 0 && output.tagged("a")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.True(t, outputNew.isClosed())
+	require.Zero(t, inputANew.gathered())
+	require.Same(t, inputA, a.Config.Inputs[0].Input)
+
+	// Only the secrets of the unused instance are destroyed
+	require.True(t, inputANew.Password.Empty())
+	require.False(t, inputA.Password.Empty())
+
+	// Remove input "a"
+	cfgNew = newReloadConfig(t, &reloadOutput{}, []*reloadInput{{name: "b"}}, &reloadProcessor{})
+	require.NoError(t, a.Reload(cfgNew))
+	require.Len(t, a.Config.Inputs, 1)
+	require.Same(t, inputB, a.Config.Inputs[0].Input)
+	gathered := inputA.gathered()
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, gathered, inputA.gathered())
+	require.True(t, inputA.Password.Empty())
+
+	// Changing the agent settings requires a restart
+	cfgNew = newReloadConfig(t, &reloadOutput{}, []*reloadInput{{name: "b"}}, &reloadProcessor{})
+	cfgNew.Agent.Hostname = "changed"
+	require.ErrorIs(t, a.Reload(cfgNew), ErrRestartRequired)
+
+	cancel()
+	wg.Wait()
+	require.True(t, output.isClosed())
+}
+
+func newReloadConfig(t *testing.T, output *reloadOutput, inputs []*reloadInput, processor *reloadProcessor) *config.Config {
+	cfg := config.NewConfig()
+	cfg.Agent.Interval = config.Duration(10 * time.Millisecond)
+	cfg.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+	cfg.Agent.Hostname = "test"
+
+	for _, input := range inputs {
+		cfg.Inputs = append(cfg.Inputs, models.NewRunningInput(input, &models.InputConfig{Name: input.name, ID: input.name}))
+	}
+
+	if processor != nil {
+		rp := models.NewRunningProcessor(
+			processors.NewStreamingProcessorFromProcessor(processor),
+			&models.ProcessorConfig{Name: "tag", ID: "tag"},
+		)
+		cfg.Processors = append(cfg.Processors, rp)
+	}
+
+	ro, err := models.NewRunningOutput(output, &models.OutputConfig{Name: "test", ID: "test"}, 1000, 10000)
+	require.NoError(t, err)
+	cfg.Outputs = append(cfg.Outputs, ro)
+
+	return cfg
+}
+
+type reloadInput struct {
+	Password config.Secret
+
+	name string
+
+	sync.Mutex
+	count int
+}
+
+func (*reloadInput) SampleConfig() string {
+	return ""
+}
+
+func (i *reloadInput) Gather(acc telegraf.Accumulator) error {
+	i.Lock()
+	i.count++
+	i.Unlock()
+	acc.AddFields(i.name, map[string]interface{}{"value": 42}, nil)
+	return nil
+}
+
+func (i *reloadInput) gathered() int {
+	i.Lock()
+	defer i.Unlock()
+	return i.count
+}
+
+type reloadProcessor struct{}
+
+func (*reloadProcessor) SampleConfig() string {
+	return ""
+}
+
+func (*reloadProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+type reloadOutput struct {
+	sync.Mutex
+	metrics []telegraf.Metric
+	closed  bool
+}
+
+func (*reloadOutput) SampleConfig() string {
+	return ""
+}
+
+func (*reloadOutput) Connect() error {
+	return nil
+}
+
+func (o *reloadOutput) Close() error {
+	o.Lock()
+	defer o.Unlock()
+	o.closed = true
+	return nil
+}
+
+func (o *reloadOutput) isClosed() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.closed
+}
+
+func (o *reloadOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func (o *reloadOutput) count(name string) int {
+	o.Lock()
+	defer o.Unlock()
+	var n int
+	for _, m := range o.metrics {
+		if m.Name() == name {
+			n++
+		}
+	}
+	return n
+}
+
+func (o *reloadOutput) tagged(name string) bool {
+	o.Lock()
+	defer o.Unlock()
+	for _, m := range o.metrics {
+		if m.Name() == name && m.HasTag("processed") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestScheduledInput(t *testing.T) {
+	output := &reloadOutput{}
+	cfg := newReloadConfig(t, output, nil, nil)
+	scheduled := &reloadInput{name: "scheduled"}
+	cfg.Inputs = append(cfg.Inputs, models.NewRunningInput(scheduled, &models.InputConfig{
+		Name:     "scheduled",
+		ID:       "scheduled",
+		Schedule: "* * * * * *",
+	}))
+	a := NewAgent(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	// The input is gathered every second instead of every interval
+	require.Eventually(t, func() bool {
+		return scheduled.gathered() >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+	require.LessOrEqual(t, scheduled.gathered(), 4)
+}
+
+func TestScheduledInputOnce(t *testing.T) {
+	output := &reloadOutput{}
+	cfg := newReloadConfig(t, output, nil, nil)
+	cfg.Inputs = append(cfg.Inputs, models.NewRunningInput(&reloadInput{name: "scheduled"}, &models.InputConfig{
+		Name:     "scheduled",
+		ID:       "scheduled",
+		Schedule: "0 2 1 1 *",
+	}))
+	a := NewAgent(cfg)
+
+	// A single gather ignores the schedule
+	require.NoError(t, a.Once(context.Background(), 0))
+	require.Equal(t, 1, output.count("scheduled"))
+}
@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// secretWatcher detects changes of the secrets referenced by the running
+// inputs and outputs and lets the plugins referencing a changed secret
+// refresh. Secrets are checked once their TTL reported by the store expired,
+// every interval and on change notifications of the store. Only a digest of
+// the secret values is kept.
+type secretWatcher struct {
+	stores   map[string]telegraf.SecretStore
+	interval time.Duration
+	plugins  func() ([]*models.RunningInput, []*models.RunningOutput)
+
+	secrets map[string]*watchedSecret
+
+	// notified are the references of secrets the stores reported as changed
+	// with wake signalling the watcher to handle them
+	notified     map[string]bool
+	notifiedLock sync.Mutex
+	wake         chan struct{}
+
+	rotations     map[string]selfstat.Stat
+	checkErrors   map[string]selfstat.Stat
+	refreshErrors map[string]selfstat.Stat
+}
+
+type watchedSecret struct {
+	known  bool
+	digest [sha256.Size]byte
+	next   time.Time
+}
+
+// newSecretWatcher creates a watcher for the secrets of the given stores. If
+// the secrets are never checked for changes, nil is returned.
+func newSecretWatcher(
+	stores map[string]telegraf.SecretStore,
+	interval time.Duration,
+	plugins func() ([]*models.RunningInput, []*models.RunningOutput),
+) *secretWatcher {
+	w := &secretWatcher{
+		stores:        stores,
+		interval:      interval,
+		plugins:       plugins,
+		secrets:       make(map[string]*watchedSecret),
+		notified:      make(map[string]bool),
+		wake:          make(chan struct{}, 1),
+		rotations:     make(map[string]selfstat.Stat, len(stores)),
+		checkErrors:   make(map[string]selfstat.Stat, len(stores)),
+		refreshErrors: make(map[string]selfstat.Stat, len(stores)),
+	}
+
+	var watched bool
+	for id, store := range stores {
+		tags := map[string]string{"id": id}
+		w.rotations[id] = selfstat.Register("secretstore", "rotations", tags)
+		w.checkErrors[id] = selfstat.Register("secretstore", "check_errors", tags)
+		w.refreshErrors[id] = selfstat.Register("secretstore", "refresh_errors", tags)
+
+		switch store.(type) {
+		case telegraf.SecretStoreWithTTL, telegraf.SecretStoreNotifier:
+			watched = true
+		}
+	}
+	if !watched && interval <= 0 {
+		return nil
+	}
+	return w
+}
+
+// run checks the secrets until the context is done.
+func (w *secretWatcher) run(ctx context.Context) {
+	for id, store := range w.stores {
+		if notifier, ok := store.(telegraf.SecretStoreNotifier); ok {
+			notifier.NotifyOnChange(func(key string) {
+				w.notify("@{" + id + ":" + key + "}")
+			})
+		}
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		var timeout <-chan time.Time
+		if next := w.check(time.Now()); !next.IsZero() {
+			timer.Reset(time.Until(next))
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+		case <-w.wake:
+		}
+	}
+}
+
+// trigger lets the watcher check the secrets of added plugins.
+func (w *secretWatcher) trigger() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notify marks the secret as changed without blocking the store.
+func (w *secretWatcher) notify(ref string) {
+	w.notifiedLock.Lock()
+	w.notified[ref] = true
+	w.notifiedLock.Unlock()
+	w.trigger()
+}
+
+// check checks the secrets of the running plugins being due or notified as
+// changed and returns the time of the next check.
+func (w *secretWatcher) check(now time.Time) time.Time {
+	w.notifiedLock.Lock()
+	notified := w.notified
+	w.notified = make(map[string]bool)
+	w.notifiedLock.Unlock()
+
+	// Collect the secrets used by the running plugins, forgetting the ones
+	// of plugins removed on reload
+	inputs, outputs := w.plugins()
+	refs := make(map[string]bool)
+	for _, input := range inputs {
+		for _, ref := range input.Config.Secrets {
+			refs[ref] = true
+		}
+	}
+	for _, output := range outputs {
+		for _, ref := range output.Config.Secrets {
+			refs[ref] = true
+		}
+	}
+	for ref := range w.secrets {
+		if !refs[ref] {
+			delete(w.secrets, ref)
+		}
+	}
+
+	var next time.Time
+	for ref := range refs {
+		s, found := w.secrets[ref]
+		if !found || notified[ref] || !s.next.IsZero() && !now.Before(s.next) {
+			if w.checkSecret(ref, now, notified[ref]) {
+				w.refresh(ref, inputs, outputs)
+			}
+			s = w.secrets[ref]
+		}
+		if s != nil && !s.next.IsZero() && (next.IsZero() || s.next.Before(next)) {
+			next = s.next
+		}
+	}
+	return next
+}
+
+// checkSecret reads the secret and returns true if the secret changed since
+// the last check. Secrets seen for the first time are only reported as
+// changed if the store notified about the change.
+func (w *secretWatcher) checkSecret(ref string, now time.Time, notified bool) bool {
+	storeID, key := config.SplitSecretReference(ref)
+	store, found := w.stores[storeID]
+	if !found {
+		return false
+	}
+
+	s, found := w.secrets[ref]
+	if !found {
+		s = &watchedSecret{}
+		w.secrets[ref] = s
+	}
+
+	// Retry failed checks after the interval
+	s.next = time.Time{}
+	if w.interval > 0 {
+		s.next = now.Add(w.interval)
+	}
+
+	value, err := store.Get(key)
+	if err != nil {
+		w.checkErrors[storeID].Incr(1)
+		log.Printf("E! [agent] Checking secret %q for changes failed: %v", ref, err)
+		return false
+	}
+	digest := sha256.Sum256(value)
+
+	if ttlStore, ok := store.(telegraf.SecretStoreWithTTL); ok {
+		ttl, err := ttlStore.TTL(key)
+		if err != nil {
+			w.checkErrors[storeID].Incr(1)
+			log.Printf("E! [agent] Getting TTL of secret %q failed: %v", ref, err)
+		} else if ttl > 0 && (s.next.IsZero() || now.Add(ttl).Before(s.next)) {
+			s.next = now.Add(ttl)
+		}
+	}
+
+	changed := s.known && digest != s.digest || !s.known && notified
+	s.known = true
+	s.digest = digest
+	return changed
+}
+
+// refresh lets the plugins referencing the changed secret refresh.
+func (w *secretWatcher) refresh(ref string, inputs []*models.RunningInput, outputs []*models.RunningOutput) {
+	storeID, _ := config.SplitSecretReference(ref)
+	w.rotations[storeID].Incr(1)
+
+	var refreshers []interface {
+		LogName() string
+		RefreshSecrets() error
+	}
+	for _, input := range inputs {
+		if slices.Contains(input.Config.Secrets, ref) {
+			refreshers = append(refreshers, input)
+		}
+	}
+	for _, output := range outputs {
+		if slices.Contains(output.Config.Secrets, ref) {
+			refreshers = append(refreshers, output)
+		}
+	}
+
+	log.Printf("I! [agent] Secret %q changed, refreshing %d plugin(s)", ref, len(refreshers))
+	for _, r := range refreshers {
+		if err := r.RefreshSecrets(); err != nil {
+			w.refreshErrors[storeID].Incr(1)
+			log.Printf("E! [agent] Refreshing secrets of %s failed: %v", r.LogName(), err)
+		}
+	}
+}
+
+// secretPlugins returns the running inputs and outputs.
+func (a *Agent) secretPlugins() ([]*models.RunningInput, []*models.RunningOutput) {
+	a.unitsLock.Lock()
+	defer a.unitsLock.Unlock()
+
+	if a.units == nil {
+		return nil, nil
+	}
+	var outputs []*models.RunningOutput
+	for _, p := range a.units.allPipelines() {
+		outputs = append(outputs, p.outputs.runningOutputs()...)
+	}
+	return a.units.inputs.runningInputs(), outputs
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+func TestSecretWatcher(t *testing.T) {
+	store := &rotatingStore{secrets: map[string]string{"token": "a", "password": "x"}, ttl: time.Minute}
+	input := &refreshingInput{}
+	output := &refreshingOutput{}
+	ri := models.NewRunningInput(input, &models.InputConfig{
+		Name:    "refreshing",
+		Secrets: []string{"@{vault:password}"},
+	})
+	ro, err := models.NewRunningOutput(output, &models.OutputConfig{
+		Name:    "refreshing",
+		Secrets: []string{"@{vault:password}", "@{vault:token}"},
+	}, 10, 10)
+	require.NoError(t, err)
+
+	w := newSecretWatcher(map[string]telegraf.SecretStore{"vault": store}, 0, func() ([]*models.RunningInput, []*models.RunningOutput) {
+		return []*models.RunningInput{ri}, []*models.RunningOutput{ro}
+	})
+	require.NotNil(t, w)
+	rotations := w.rotations["vault"].Get()
+	checkErrors := w.checkErrors["vault"].Get()
+
+	// The first check only remembers the secrets and schedules the next check
+	// after the TTL
+	now := time.Now()
+	require.Equal(t, now.Add(time.Minute), w.check(now))
+	require.Zero(t, input.refreshes)
+	require.Zero(t, output.refreshes)
+
+	// Unchanged secrets do not refresh the plugins
+	now = now.Add(time.Minute)
+	w.check(now)
+	require.Zero(t, output.refreshes)
+
+	// Changed secrets refresh the plugins referencing them once due
+	store.secrets["token"] = "b"
+	require.Equal(t, now.Add(time.Minute), w.check(now.Add(time.Second)))
+	require.Zero(t, output.refreshes)
+	now = now.Add(time.Minute)
+	w.check(now)
+	require.Zero(t, input.refreshes)
+	require.Equal(t, 1, output.refreshes)
+	require.Equal(t, int64(1), w.rotations["vault"].Get()-rotations)
+
+	// Notified changes are checked immediately
+	store.secrets["password"] = "y"
+	w.notify("@{vault:password}")
+	w.check(now.Add(time.Second))
+	require.Equal(t, 1, input.refreshes)
+	require.Equal(t, 2, output.refreshes)
+	require.Equal(t, int64(2), w.rotations["vault"].Get()-rotations)
+
+	// Failing checks are counted and keep the plugins untouched
+	store.err = errors.New("sealed")
+	now = now.Add(2 * time.Minute)
+	w.check(now)
+	require.Equal(t, int64(2), w.checkErrors["vault"].Get()-checkErrors)
+	require.Equal(t, 2, output.refreshes)
+}
+
+func TestSecretWatcherDisabled(t *testing.T) {
+	stores := map[string]telegraf.SecretStore{"static": &staticStore{}}
+	require.Nil(t, newSecretWatcher(stores, 0, nil))
+	require.NotNil(t, newSecretWatcher(stores, time.Minute, nil))
+}
+
+type staticStore struct{}
+
+func (*staticStore) Init() error                { return nil }
+func (*staticStore) SampleConfig() string       { return "" }
+func (*staticStore) Get(string) ([]byte, error) { return []byte("static"), nil }
+func (*staticStore) Set(string, string) error   { return nil }
+func (*staticStore) List() ([]string, error)    { return nil, nil }
+func (s *staticStore) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	return func() ([]byte, bool, error) {
+		v, err := s.Get(key)
+		return v, false, err
+	}, nil
+}
+
+type rotatingStore struct {
+	staticStore
+	secrets map[string]string
+	ttl     time.Duration
+	err     error
+}
+
+func (s *rotatingStore) Get(key string) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []byte(s.secrets[key]), nil
+}
+
+func (s *rotatingStore) TTL(string) (time.Duration, error) {
+	return s.ttl, nil
+}
+
+func (*rotatingStore) NotifyOnChange(func(string)) {}
+
+type refreshingInput struct {
+	refreshes int
+}
+
+func (*refreshingInput) SampleConfig() string              { return "" }
+func (*refreshingInput) Gather(telegraf.Accumulator) error { return nil }
+
+func (i *refreshingInput) RefreshSecrets() error {
+	i.refreshes++
+	return nil
+}
+
+type refreshingOutput struct {
+	refreshes int
+}
+
+func (*refreshingOutput) SampleConfig() string          { return "" }
+func (*refreshingOutput) Connect() error                { return nil }
+func (*refreshingOutput) Close() error                  { return nil }
+func (*refreshingOutput) Write([]telegraf.Metric) error { return nil }
+
+func (o *refreshingOutput) RefreshSecrets() error {
+	o.refreshes++
+	return nil
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// invalidNameChars matches characters not allowed in Prometheus metric and
+// label names.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// selfMetricsServer serves the agent's self-metrics in the Prometheus and
+// OpenMetrics exposition formats. It only reads the selfstat registry and
+// does not depend on the metric pipeline so it keeps working even if all
+// outputs are stuck.
+type selfMetricsServer struct {
+	server   *http.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// startSelfMetricsServer starts serving the self-metrics on the configured
+// address.
+func (a *Agent) startSelfMetricsServer() (*selfMetricsServer, error) {
+	listener, err := listen(a.Config.Agent.MetricsServiceAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf("listening for self-metrics failed: %w", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		&selfstatCollector{},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: true,
+	}))
+
+	s := &selfMetricsServer{
+		server: &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", internal.ProductToken())
+				mux.ServeHTTP(w, r)
+			}),
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		listener: listener,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! [agent] Serving self-metrics failed: %v", err)
+		}
+	}()
+	log.Printf("I! [agent] Serving self-metrics on %s", listener.Addr())
+
+	return s, nil
+}
+
+// stop shuts down the self-metrics server.
+func (s *selfMetricsServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Printf("E! [agent] Shutting down self-metrics server failed: %v", err)
+	}
+	s.wg.Wait()
+}
+
+// selfstatCollector exposes the stats of the selfstat registry as Prometheus
+// metrics named after the measurement and field of the stat. Timing stats are
+// exposed as histograms in seconds, all other stats as untyped values.
+type selfstatCollector struct{}
+
+// selfstatFamily groups the stats exposed with the same metric name.
+type selfstatFamily struct {
+	name   string
+	help   string
+	labels []string
+	stats  []selfstat.Stat
+}
+
+// Describe sends no descriptors as the set of stats changes at runtime which
+// makes the collector unchecked.
+func (*selfstatCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (*selfstatCollector) Collect(ch chan<- prometheus.Metric) {
+	families := make(map[string]*selfstatFamily)
+	for _, s := range selfstat.Stats() {
+		name := sanitizeName(s.Name() + "_" + s.FieldName())
+		if _, ok := s.(selfstat.HistogramStat); ok {
+			name = strings.TrimSuffix(name, "_ns") + "_seconds"
+		}
+
+		f, found := families[name]
+		if !found {
+			f = &selfstatFamily{
+				name: name,
+				help: fmt.Sprintf("Telegraf self-metric %s of %s", s.FieldName(), s.Name()),
+			}
+			families[name] = f
+		}
+		for key := range s.Tags() {
+			if label := sanitizeName(key); !slices.Contains(f.labels, label) {
+				f.labels = append(f.labels, label)
+			}
+		}
+		f.stats = append(f.stats, s)
+	}
+
+	// All metrics of a family must have the same labels so fill the labels
+	// missing for some of the stats with empty values
+	for _, f := range families {
+		slices.Sort(f.labels)
+		desc := prometheus.NewDesc(f.name, f.help, f.labels, nil)
+		for _, s := range f.stats {
+			tags := make(map[string]string)
+			for k, v := range s.Tags() {
+				tags[sanitizeName(k)] = v
+			}
+			values := make([]string, 0, len(f.labels))
+			for _, label := range f.labels {
+				values = append(values, tags[label])
+			}
+
+			var m prometheus.Metric
+			var err error
+			if hs, ok := s.(selfstat.HistogramStat); ok {
+				h := hs.Histogram()
+				buckets := make(map[float64]uint64, len(h.Bounds))
+				for i, bound := range h.Bounds {
+					buckets[time.Duration(bound).Seconds()] = h.Counts[i]
+				}
+				m, err = prometheus.NewConstHistogram(desc, h.Count, time.Duration(h.Sum).Seconds(), buckets, values...)
+			} else {
+				m, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, float64(s.Get()), values...)
+			}
+			if err != nil {
+				ch <- prometheus.NewInvalidMetric(desc, err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+func sanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
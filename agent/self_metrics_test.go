@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+func TestSelfMetricsServer(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Agent.MetricsServiceAddress = "http://127.0.0.1:0"
+	a := NewAgent(cfg)
+
+	s, err := a.startSelfMetricsServer()
+	require.NoError(t, err)
+	defer s.stop()
+
+	t.Cleanup(func() {
+		selfstat.Unregister("self_metrics_test", "metrics_written", map[string]string{"output": "file", "alias": "a"})
+		selfstat.Unregister("self_metrics_test", "metrics_written", map[string]string{"output": "file"})
+		selfstat.Unregister("self_metrics_test", "write_time_ns", map[string]string{"output": "file"})
+	})
+	selfstat.Register("self_metrics_test", "metrics_written", map[string]string{"output": "file", "alias": "a"}).Set(42)
+	selfstat.Register("self_metrics_test", "metrics_written", map[string]string{"output": "file"}).Set(7)
+	timing := selfstat.RegisterTiming("self_metrics_test", "write_time_ns", map[string]string{"output": "file"})
+	timing.Incr(int64(2 * time.Millisecond))
+	timing.Incr(int64(20 * time.Second))
+
+	// Reading the timing through the registry must not affect the histogram
+	require.Equal(t, int64(10001*time.Millisecond), timing.Get())
+
+	get := func(accept string) string {
+		req, err := http.NewRequest(http.MethodGet, "http://"+s.listener.Addr().String()+"/metrics", nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	body := get("")
+	require.Contains(t, body, `internal_self_metrics_test_metrics_written{alias="a",output="file"} 42`)
+	require.Contains(t, body, `internal_self_metrics_test_metrics_written{alias="",output="file"} 7`)
+	require.Contains(t, body, "# TYPE internal_self_metrics_test_write_time_seconds histogram")
+	require.Contains(t, body, `internal_self_metrics_test_write_time_seconds_bucket{output="file",le="0.005"} 1`)
+	require.Contains(t, body, `internal_self_metrics_test_write_time_seconds_bucket{output="file",le="+Inf"} 2`)
+	require.Contains(t, body, `internal_self_metrics_test_write_time_seconds_count{output="file"} 2`)
+	require.Contains(t, body, "go_goroutines")
+
+	body = get("application/openmetrics-text; version=1.0.0")
+	require.Contains(t, body, "# EOF")
+	require.Contains(t, body, `internal_self_metrics_test_write_time_seconds_count{output="file"} 2`)
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+)
+
+// DefaultTracingSampleRate is the ratio of traced operations if not
+// configured otherwise.
+const DefaultTracingSampleRate = 0.1
+
+// startTracing enables tracing the plugin operations and exporting the
+// spans via OTLP to the configured endpoint.
+func (a *Agent) startTracing() (*sdktrace.TracerProvider, error) {
+	exporter, err := newOTLPExporter(a.Config.Agent.TracingEndpoint, a.Config.Agent.TracingProtocol)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := DefaultTracingSampleRate
+	if a.Config.Agent.TracingSampleRate > 0 {
+		rate = a.Config.Agent.TracingSampleRate
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", "telegraf"),
+		attribute.String("service.version", internal.FormatFullVersion()),
+	}
+	if a.Config.Agent.Hostname != "" {
+		attrs = append(attrs, attribute.String("host.name", a.Config.Agent.Hostname))
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(rate))),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+	)
+	models.SetTracerProvider(provider)
+	log.Printf("I! [agent] Exporting traces of %.0f%% of the plugin operations to %s", rate*100, a.Config.Agent.TracingEndpoint)
+
+	return provider, nil
+}
+
+// stopTracing disables tracing and exports the remaining spans.
+func stopTracing(provider *sdktrace.TracerProvider) {
+	models.SetTracerProvider(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("E! [agent] Exporting remaining traces failed: %v", err)
+	}
+}
+
+// otlpExporter exports spans to an OTLP endpoint via gRPC or HTTP using
+// protobuf encoding.
+type otlpExporter struct {
+	endpoint string
+
+	conn   *grpc.ClientConn
+	client ptraceotlp.GRPCClient
+
+	httpClient *http.Client
+}
+
+func newOTLPExporter(endpoint, protocol string) (*otlpExporter, error) {
+	e := &otlpExporter{endpoint: endpoint}
+	switch protocol {
+	case "", "grpc":
+		conn, err := grpc.NewClient(endpoint,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUserAgent(internal.ProductToken()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("creating gRPC client for traces failed: %w", err)
+		}
+		e.conn = conn
+		e.client = ptraceotlp.NewGRPCClient(conn)
+	case "http":
+		e.httpClient = &http.Client{Timeout: 10 * time.Second}
+	default:
+		return nil, fmt.Errorf("invalid tracing protocol %q", protocol)
+	}
+	return e, nil
+}
+
+// ExportSpans sends the given spans to the endpoint.
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	request := ptraceotlp.NewExportRequestFromTraces(convertSpans(spans))
+
+	if e.client != nil {
+		_, err := e.client.Export(ctx, request)
+		return err
+	}
+
+	body, err := request.MarshalProto()
+	if err != nil {
+		return fmt.Errorf("encoding traces failed: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("exporting traces failed with status %q: %s", resp.Status, msg)
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// Shutdown closes the connection to the endpoint.
+func (e *otlpExporter) Shutdown(context.Context) error {
+	if e.conn != nil {
+		return e.conn.Close()
+	}
+	return nil
+}
+
+// convertSpans converts the spans to OTLP traces grouped by resource and
+// instrumentation scope.
+func convertSpans(spans []sdktrace.ReadOnlySpan) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	resources := make(map[*resource.Resource]ptrace.ResourceSpans)
+	scopes := make(map[*resource.Resource]map[string]ptrace.ScopeSpans)
+
+	for _, span := range spans {
+		res := span.Resource()
+		rs, found := resources[res]
+		if !found {
+			rs = traces.ResourceSpans().AppendEmpty()
+			if res != nil {
+				putAttributes(rs.Resource().Attributes(), res.Attributes())
+			}
+			resources[res] = rs
+			scopes[res] = make(map[string]ptrace.ScopeSpans)
+		}
+
+		scope := span.InstrumentationScope()
+		ss, found := scopes[res][scope.Name]
+		if !found {
+			ss = rs.ScopeSpans().AppendEmpty()
+			ss.Scope().SetName(scope.Name)
+			ss.Scope().SetVersion(scope.Version)
+			scopes[res][scope.Name] = ss
+		}
+
+		s := ss.Spans().AppendEmpty()
+		s.SetTraceID(pcommon.TraceID(span.SpanContext().TraceID()))
+		s.SetSpanID(pcommon.SpanID(span.SpanContext().SpanID()))
+		if parent := span.Parent(); parent.IsValid() {
+			s.SetParentSpanID(pcommon.SpanID(parent.SpanID()))
+		}
+		s.SetName(span.Name())
+		s.SetKind(convertSpanKind(span.SpanKind()))
+		s.SetStartTimestamp(pcommon.NewTimestampFromTime(span.StartTime()))
+		s.SetEndTimestamp(pcommon.NewTimestampFromTime(span.EndTime()))
+		putAttributes(s.Attributes(), span.Attributes())
+
+		for _, event := range span.Events() {
+			e := s.Events().AppendEmpty()
+			e.SetName(event.Name)
+			e.SetTimestamp(pcommon.NewTimestampFromTime(event.Time))
+			putAttributes(e.Attributes(), event.Attributes)
+		}
+
+		switch span.Status().Code {
+		case codes.Error:
+			s.Status().SetCode(ptrace.StatusCodeError)
+			s.Status().SetMessage(span.Status().Description)
+		case codes.Ok:
+			s.Status().SetCode(ptrace.StatusCodeOk)
+		}
+	}
+	return traces
+}
+
+func convertSpanKind(kind trace.SpanKind) ptrace.SpanKind {
+	switch kind {
+	case trace.SpanKindInternal:
+		return ptrace.SpanKindInternal
+	case trace.SpanKindServer:
+		return ptrace.SpanKindServer
+	case trace.SpanKindClient:
+		return ptrace.SpanKindClient
+	case trace.SpanKindProducer:
+		return ptrace.SpanKindProducer
+	case trace.SpanKindConsumer:
+		return ptrace.SpanKindConsumer
+	}
+	return ptrace.SpanKindUnspecified
+}
+
+func putAttributes(dst pcommon.Map, attrs []attribute.KeyValue) {
+	for _, attr := range attrs {
+		key := string(attr.Key)
+		switch attr.Value.Type() {
+		case attribute.BOOL:
+			dst.PutBool(key, attr.Value.AsBool())
+		case attribute.INT64:
+			dst.PutInt(key, attr.Value.AsInt64())
+		case attribute.FLOAT64:
+			dst.PutDouble(key, attr.Value.AsFloat64())
+		default:
+			dst.PutStr(key, attr.Value.Emit())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+)
+
+func TestTracingHTTP(t *testing.T) {
+	collector := &traceCollector{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		request := ptraceotlp.NewExportRequest()
+		if err := request.UnmarshalProto(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		collector.add(request.Traces())
+	}))
+	defer server.Close()
+
+	runTracedAgent(t, server.URL+"/v1/traces", "http")
+	collector.check(t)
+}
+
+func TestTracingGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &traceCollector{}
+	server := grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(server, collector)
+	go server.Serve(listener) //nolint:errcheck // test server
+	defer server.Stop()
+
+	runTracedAgent(t, listener.Addr().String(), "grpc")
+	collector.check(t)
+}
+
+func TestTracingInvalidProtocol(t *testing.T) {
+	_, err := newOTLPExporter("localhost:4317", "udp")
+	require.ErrorContains(t, err, "invalid tracing protocol")
+}
+
+// runTracedAgent runs the agent with tracing of all operations until the
+// output received metrics.
+func runTracedAgent(t *testing.T, endpoint, protocol string) {
+	output := &reloadOutput{}
+	cfg := newReloadConfig(t, output, []*reloadInput{{name: "a"}}, &reloadProcessor{})
+	cfg.Agent.TracingEndpoint = endpoint
+	cfg.Agent.TracingProtocol = protocol
+	cfg.Agent.TracingSampleRate = 1
+	a := NewAgent(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+
+	require.Eventually(t, func() bool {
+		return output.count("a") > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Stopping the agent exports the remaining spans
+	cancel()
+	wg.Wait()
+}
+
+// traceCollector is a stand-in for an OTLP collector recording the received
+// spans.
+type traceCollector struct {
+	ptraceotlp.UnimplementedGRPCServer
+
+	sync.Mutex
+	spans     map[string]map[string]any
+	resources []map[string]any
+}
+
+func (c *traceCollector) Export(_ context.Context, request ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	c.add(request.Traces())
+	return ptraceotlp.NewExportResponse(), nil
+}
+
+func (c *traceCollector) add(traces ptrace.Traces) {
+	c.Lock()
+	defer c.Unlock()
+	if c.spans == nil {
+		c.spans = make(map[string]map[string]any)
+	}
+	for _, rs := range traces.ResourceSpans().All() {
+		c.resources = append(c.resources, rs.Resource().Attributes().AsRaw())
+		for _, ss := range rs.ScopeSpans().All() {
+			for _, span := range ss.Spans().All() {
+				c.spans[span.Name()] = span.Attributes().AsRaw()
+			}
+		}
+	}
+}
+
+func (c *traceCollector) check(t *testing.T) {
+	c.Lock()
+	defer c.Unlock()
+
+	require.NotEmpty(t, c.resources)
+	require.Equal(t, "telegraf", c.resources[0]["service.name"])
+	require.Equal(t, "test", c.resources[0]["host.name"])
+
+	require.Contains(t, c.spans, "inputs.a gather")
+	require.Equal(t, "inputs", c.spans["inputs.a gather"]["telegraf.plugin.type"])
+	require.Equal(t, "a", c.spans["inputs.a gather"]["telegraf.plugin.id"])
+	require.EqualValues(t, 1, c.spans["inputs.a gather"]["telegraf.metrics.count"])
+
+	require.Contains(t, c.spans, "processors.tag process")
+	require.Equal(t, "tag", c.spans["processors.tag process"]["telegraf.plugin.name"])
+
+	require.Contains(t, c.spans, "outputs.test write")
+	require.Equal(t, "test", c.spans["outputs.test write"]["telegraf.plugin.id"])
+	require.Positive(t, c.spans["outputs.test write"]["telegraf.metrics.count"])
+}
@@ -23,7 +23,7 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 	return []*cli.Command{
 		{
 			Name:  "config",
-			Usage: "commands for generating and migrating configurations",
+			Usage: "commands for generating, converting and migrating configurations",
 			Flags: configHandlingFlags,
 			Action: func(cCtx *cli.Context) error {
 				// The sub_Filters are populated when the filter flags are set after the subcommand config
@@ -39,9 +39,11 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 					Usage: "check configuration file(s) for issues",
 					Description: `
 		The 'check' command reads the configuration files specified via '--config' or
-		'--config-directory' and tries to initialize, but not start, the plugins.
-		Syntax and semantic errors detectable without starting the plugins will
-		be reported.
+		'--config-directory', validates them against the schema of the available
+		plugins and tries to initialize, but not start, the plugins.
+		Unknown options, with suggestions for misspelled ones, options of the
+		wrong type as well as syntax and semantic errors detectable without
+		starting the plugins will be reported.
 		If no configuration file is	explicitly specified the command reads the
 		default locations and uses those configuration files.
 
@@ -58,36 +60,35 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 						}
 
 						// Set the environment variables handling mode
-						if cCtx.Bool("strict-env-handling") && cCtx.Bool("non-strict-env-handling") {
-							return errors.New("flags --strict-env-handling and --non-strict-env-handling cannot be used together")
-						}
-						if !cCtx.Bool("strict-env-handling") && !cCtx.Bool("non-strict-env-handling") {
-							msg := "Strict environment variable handling will be the new default starting with v1.38.0! " +
-								"If your configuration works with strict handling or you don't use environment variables it is safe " +
-								"to ignore this warning. Otherwise please explicitly add the --non-strict-env-handling flag!"
-							log.Println("W! " + color.YellowString(msg))
+						if err := setEnvVarHandling(cCtx); err != nil {
+							return err
 						}
-						config.NonStrictEnvVarHandling = !cCtx.Bool("strict-env-handling")
 
 						// Collect the given configuration files
-						configFiles := cCtx.StringSlice("config")
-						configDir := cCtx.StringSlice("config-directory")
-						for _, fConfigDirectory := range configDir {
-							files, err := config.WalkDirectory(fConfigDirectory)
-							if err != nil {
-								return err
-							}
-							configFiles = append(configFiles, files...)
+						configFiles, err := collectConfigFiles(cCtx)
+						if err != nil {
+							return err
 						}
 
-						// If no "config" or "config-directory" flag(s) was
-						// provided we should load default configuration files
-						if len(configFiles) == 0 {
-							paths, err := config.GetDefaultConfigPath()
+						// Validate the files against the schema of the available
+						// plugins to report all unknown options and wrong types
+						var violations int
+						for _, fn := range configFiles {
+							data, _, err := config.LoadConfigFile(fn)
 							if err != nil {
-								return err
+								return fmt.Errorf("loading config file %q failed: %w", fn, err)
 							}
-							configFiles = paths
+							found, err := config.ValidateConfigData(data, fn)
+							if err != nil {
+								return fmt.Errorf("validating config file %q failed: %w", fn, err)
+							}
+							for _, v := range found {
+								log.Printf("E! [config] %s: %s", fn, v)
+							}
+							violations += len(found)
+						}
+						if violations > 0 {
+							return fmt.Errorf("found %d schema violation(s) in the configuration", violations)
 						}
 
 						// Load the config and try to initialize the plugins
@@ -111,6 +112,64 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 						return ag.InitPlugins()
 					},
 				},
+				{
+					Name:  "lint",
+					Usage: "report probable mistakes in the pipelines of the configuration(s)",
+					Description: `
+The 'lint' command reads the configuration files specified via '--config' or
+'--config-directory', assembles the pipelines and reports settings that are
+valid but most likely a mistake. Those are filters that can never match,
+'namepass' or 'namedrop' settings of processors, aggregators and outputs
+matching none of the metrics reaching the plugin, processors running before
+the processors they depend on due to their 'order' setting, outputs starved
+by aggregators with 'drop_original' and aliases used more than once.
+The names of the metrics are estimated from the plugin names and settings and
+plugins able to produce arbitrary names are assumed to match everything, so
+the command errs on the side of missing an issue. Each issue is printed with
+the file and line of the plugin.
+If no configuration file is explicitly specified the command reads the
+default locations and uses those configuration files.
+
+To lint the file 'mysettings.conf' use
+
+> telegraf config lint --config mysettings.conf
+`,
+					Flags: configHandlingFlags,
+					Action: func(cCtx *cli.Context) error {
+						// Setup logging
+						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
+						if err := logger.SetupLogging(logConfig); err != nil {
+							return err
+						}
+
+						// Set the environment variables handling mode
+						if err := setEnvVarHandling(cCtx); err != nil {
+							return err
+						}
+
+						// Collect the given configuration files
+						configFiles, err := collectConfigFiles(cCtx)
+						if err != nil {
+							return err
+						}
+
+						// Load the config and analyse the assembled pipelines
+						c := config.NewConfig()
+						c.Agent.Quiet = cCtx.Bool("quiet")
+						if err := c.LoadAll(configFiles...); err != nil {
+							return err
+						}
+
+						issues := c.Lint()
+						for _, issue := range issues {
+							fmt.Fprintln(outputBuffer, issue)
+						}
+						if len(issues) > 0 {
+							return fmt.Errorf("found %d issue(s) in the configuration", len(issues))
+						}
+						return nil
+					},
+				},
 				{
 					Name:  "create",
 					Usage: "create a full sample configuration and show it",
@@ -136,6 +195,90 @@ InfluxDB v2 output plugin use
 						return nil
 					},
 				},
+				{
+					Name:  "convert",
+					Usage: "convert a configuration file between TOML, YAML and JSON",
+					Description: `
+The 'convert' command reads the configuration file specified via '--config' and
+prints it in the format given by '--format' which is one of 'toml', 'yaml' or
+'json'. The format of the input is determined by the file extension with
+'.yaml' or '.yml' denoting YAML, '.json' denoting JSON and TOML otherwise.
+Environment variables and secret references are kept as they are while
+comments are lost during conversion.
+
+To convert the file 'telegraf.conf' to YAML use
+
+> telegraf config convert --config telegraf.conf --format yaml > telegraf.yaml
+`,
+					Flags: append(configHandlingFlags,
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "format of the output, one of 'toml', 'yaml' or 'json'",
+							Value: config.FormatTOML,
+						},
+					),
+					Action: func(cCtx *cli.Context) error {
+						configFiles := cCtx.StringSlice("config")
+						if len(configFiles) != 1 {
+							return errors.New("exactly one configuration file must be specified via --config")
+						}
+						fn := configFiles[0]
+
+						data, _, err := config.LoadConfigFile(fn)
+						if err != nil {
+							return fmt.Errorf("opening input %q failed: %w", fn, err)
+						}
+
+						out, err := config.ConvertConfig(data, config.FormatFromPath(fn), cCtx.String("format"))
+						if err != nil {
+							return fmt.Errorf("converting %q failed: %w", fn, err)
+						}
+						_, err = outputBuffer.Write(out)
+						return err
+					},
+				},
+				{
+					Name:  "print",
+					Usage: "print the merged configuration including all included files",
+					Description: `
+The 'print' command reads the configuration files specified via '--config' or
+'--config-directory', resolves their 'include' directives, applies the
+overlays and prints the resulting configuration in the format given by
+'--format' which is one of 'toml', 'yaml' or 'json'. Plugins of all files are
+combined as done when running Telegraf while the settings of the 'agent'
+and 'global_tags' tables of later files take precedence.
+Environment variables and secret references are kept as they are while
+comments are lost.
+If no configuration file is explicitly specified the command reads the
+default locations and uses those configuration files.
+
+To print the configuration of the vessel 'mysettings.conf' including its base
+configurations use
+
+> telegraf config print --config mysettings.conf
+`,
+					Flags: append(configHandlingFlags,
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "format of the output, one of 'toml', 'yaml' or 'json'",
+							Value: config.FormatTOML,
+						},
+					),
+					Action: func(cCtx *cli.Context) error {
+						// Collect the given configuration files
+						configFiles, err := collectConfigFiles(cCtx)
+						if err != nil {
+							return err
+						}
+
+						out, err := config.MergedConfig(cCtx.String("format"), configFiles...)
+						if err != nil {
+							return err
+						}
+						_, err = outputBuffer.Write(out)
+						return err
+					},
+				},
 				{
 					Name:  "migrate",
 					Usage: "migrate deprecated plugins and options of the configuration(s)",
@@ -256,3 +399,39 @@ To migrate the file 'mysettings.conf' use
 		},
 	}
 }
+
+// setEnvVarHandling sets the environment variable handling mode according to
+// the given flags.
+func setEnvVarHandling(cCtx *cli.Context) error {
+	if cCtx.Bool("strict-env-handling") && cCtx.Bool("non-strict-env-handling") {
+		return errors.New("flags --strict-env-handling and --non-strict-env-handling cannot be used together")
+	}
+	if !cCtx.Bool("strict-env-handling") && !cCtx.Bool("non-strict-env-handling") {
+		msg := "Strict environment variable handling will be the new default starting with v1.38.0! " +
+			"If your configuration works with strict handling or you don't use environment variables it is safe " +
+			"to ignore this warning. Otherwise please explicitly add the --non-strict-env-handling flag!"
+		log.Println("W! " + color.YellowString(msg))
+	}
+	config.NonStrictEnvVarHandling = !cCtx.Bool("strict-env-handling")
+	return nil
+}
+
+// collectConfigFiles returns the configuration files given via the flags or
+// the default configuration files if none are given.
+func collectConfigFiles(cCtx *cli.Context) ([]string, error) {
+	configFiles := cCtx.StringSlice("config")
+	for _, fConfigDirectory := range cCtx.StringSlice("config-directory") {
+		files, err := config.WalkDirectory(fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+		configFiles = append(configFiles, files...)
+	}
+
+	// If no "config" or "config-directory" flag(s) was
+	// provided we should load default configuration files
+	if len(configFiles) == 0 {
+		return config.GetDefaultConfigPath()
+	}
+	return configFiles, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -186,6 +187,24 @@ func getPluginCommands(outputBuffer io.Writer) []*cli.Command {
 						return nil
 					},
 				},
+				{
+					Name:  "schema",
+					Usage: "Print the JSON Schema of the configuration including all available plugins",
+					Description: `
+The 'schema' command prints a JSON Schema describing the configuration
+including the options of all available plugins, parsers and serializers. The
+options are derived from the plugin structures and the defaults from the
+sample configurations.
+`,
+					Action: func(*cli.Context) error {
+						schema, err := config.JSONSchema()
+						if err != nil {
+							return err
+						}
+						_, err = outputBuffer.Write(append(schema, '\n'))
+						return err
+					},
+				},
 			},
 		},
 	}
@@ -246,6 +246,10 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 			return err
 		}
 
+		if err := config.SetSignatureKeys(cCtx.StringSlice("config-url-signature-key")); err != nil {
+			return err
+		}
+
 		filters := processFilterFlags(cCtx)
 
 		g := GlobalFlags{
@@ -367,6 +371,12 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 					Usage: "enable test mode: gather metrics, print them out, and exit. " +
 						"Note: Test mode only runs inputs, processors, and aggregators, but not outputs",
 				},
+				&cli.StringSliceFlag{
+					Name: "config-url-signature-key",
+					Usage: "PEM encoded public key for verifying the detached signature of URL based configuration " +
+						"files, the signature is fetched from the configuration URL with '.sig' appended to the path. " +
+						"Configurations without a valid signature are rejected.",
+				},
 				&cli.StringSliceFlag{
 					Name: "select",
 					Usage: "enable only plugins with labels matching the given key-value selection. " +
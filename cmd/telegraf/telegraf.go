@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -83,6 +84,9 @@ type Telegraf struct {
 
 	cfg *config.Config
 
+	// agent currently running used to apply configuration changes
+	runningAgent atomic.Pointer[agent.Agent]
+
 	GlobalFlags
 	WindowFlags
 }
@@ -151,57 +155,40 @@ func (t *Telegraf) reloadLoop() error {
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		if t.watchConfig != "" {
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					continue
-				}
-
-				if _, err := os.Stat(fConfig); err != nil {
-					log.Printf("W! Cannot watch config %s: %s", fConfig, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfig)
-				}
-			}
-			for _, fConfigDirectory := range t.configDir {
-				if _, err := os.Stat(fConfigDirectory); err != nil {
-					log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
-				} else {
-					go t.watchLocalConfig(ctx, signals, fConfigDirectory)
-				}
-			}
-		}
-		if t.configURLWatchInterval > 0 {
-			remoteConfigs := make([]string, 0)
-			for _, fConfig := range t.configFiles {
-				if isURL(fConfig) {
-					remoteConfigs = append(remoteConfigs, fConfig)
-				}
-			}
-			if len(remoteConfigs) > 0 {
-				go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
-			}
-		}
+		watchCtx, stopWatchers := context.WithCancel(ctx)
+		t.startWatchers(watchCtx, signals)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Println("I! Reloading Telegraf config")
-					// May need to update the list of known config files
-					// if a delete or create occurred. That way on the reload
-					// we ensure we watch the correct files.
-					if err := t.getConfigFiles(); err != nil {
-						log.Println("E! Error loading config files: ", err)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Println("I! Reloading Telegraf config")
+						// May need to update the list of known config files
+						// if a delete or create occurred. That way on the reload
+						// we ensure we watch the correct files.
+						if err := t.getConfigFiles(); err != nil {
+							log.Println("E! Error loading config files: ", err)
+						}
+						// Try to only exchange the changed plugins and keep
+						// the agent running. The watchers are restarted to
+						// pick up added, removed or replaced files.
+						if t.reloadPlugins() {
+							stopWatchers()
+							watchCtx, stopWatchers = context.WithCancel(ctx)
+							t.startWatchers(watchCtx, signals)
+							continue
+						}
+						<-reload
+						reload <- true
 					}
-					<-reload
-					reload <- true
+					cancel()
+				case err := <-t.pprofErr:
+					log.Printf("E! pprof server failed: %v", err)
+					cancel()
+				case <-stop:
+					cancel()
 				}
-				cancel()
-			case err := <-t.pprofErr:
-				log.Printf("E! pprof server failed: %v", err)
-				cancel()
-			case <-stop:
-				cancel()
+				return
 			}
 		}()
 
@@ -215,6 +202,42 @@ func (t *Telegraf) reloadLoop() error {
 	return nil
 }
 
+// startWatchers starts watching the local and remote configuration files for
+// changes until the context is done, signaling a reload on changes.
+func (t *Telegraf) startWatchers(ctx context.Context, signals chan os.Signal) {
+	if t.watchConfig != "" {
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				continue
+			}
+
+			if _, err := os.Stat(fConfig); err != nil {
+				log.Printf("W! Cannot watch config %s: %s", fConfig, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfig)
+			}
+		}
+		for _, fConfigDirectory := range t.configDir {
+			if _, err := os.Stat(fConfigDirectory); err != nil {
+				log.Printf("W! Cannot watch config directory %s: %s", fConfigDirectory, err)
+			} else {
+				go t.watchLocalConfig(ctx, signals, fConfigDirectory)
+			}
+		}
+	}
+	if t.configURLWatchInterval > 0 {
+		remoteConfigs := make([]string, 0)
+		for _, fConfig := range t.configFiles {
+			if isURL(fConfig) {
+				remoteConfigs = append(remoteConfigs, fConfig)
+			}
+		}
+		if len(remoteConfigs) > 0 {
+			go t.watchRemoteConfigs(ctx, signals, t.configURLWatchInterval, remoteConfigs)
+		}
+	}
+}
+
 func (t *Telegraf) watchLocalConfig(ctx context.Context, signals chan os.Signal, fConfig string) {
 	var mytomb tomb.Tomb
 	var watcher watch.FileWatcher
@@ -349,8 +372,6 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 		select {
 		case <-ctx.Done():
 			return
-		case <-signals:
-			return
 		case <-ticker.C:
 			for _, configURL := range remoteConfigs {
 				req, err := http.NewRequest("HEAD", configURL, nil)
@@ -383,8 +404,12 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 					lastModified[configURL] = modified
 				} else if lastModified[configURL] != modified {
 					log.Printf("I! Remote config modified: %s\n", configURL)
-					signals <- syscall.SIGHUP
-					return
+					lastModified[configURL] = modified
+					select {
+					case signals <- syscall.SIGHUP:
+					case <-ctx.Done():
+						return
+					}
 				}
 			}
 		}
@@ -395,6 +420,13 @@ func (t *Telegraf) loadConfiguration() (*config.Config, error) {
 	// Make sure secrets are cleared
 	config.ResetSecrets()
 
+	return t.readConfiguration()
+}
+
+// readConfiguration loads the configuration without clearing the secrets, so
+// the secrets of the plugins of a running agent stay intact. The agent
+// destroys the secrets of the plugins it does not use after a reload.
+func (t *Telegraf) readConfiguration() (*config.Config, error) {
 	// If no other options are specified, load the config file and run.
 	c := config.NewConfig()
 	c.Agent.Quiet = t.quiet
@@ -569,9 +601,47 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 
+	t.runningAgent.Store(ag)
+	defer t.runningAgent.Store(nil)
+
 	return ag.Run(ctx)
 }
 
+// reloadPlugins applies the current configuration to the running agent by
+// only exchanging the plugins that changed. It returns false if the agent
+// must be restarted instead.
+func (t *Telegraf) reloadPlugins() bool {
+	ag := t.runningAgent.Load()
+	if ag == nil {
+		return false
+	}
+
+	c, err := t.readConfiguration()
+	if err != nil {
+		// Keep the last known good configuration running instead of
+		// restarting with a configuration not published by us
+		if errors.Is(err, config.ErrInvalidSignature) {
+			log.Printf("E! Rejecting config, keeping the current one: %v", err)
+			return true
+		}
+		log.Printf("E! Loading config failed: %v", err)
+		return false
+	}
+	if len(c.Outputs) == 0 || (t.plugindDir == "" && len(c.Inputs) == 0) {
+		return false
+	}
+
+	if err := ag.Reload(c); err != nil {
+		if errors.Is(err, agent.ErrRestartRequired) {
+			log.Printf("I! Restarting agent: %v", err)
+		} else {
+			log.Printf("E! Reloading plugins failed, restarting agent: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
 // isURL checks if string is valid url
 func isURL(str string) bool {
 	u, err := url.Parse(str)
@@ -94,6 +94,7 @@ type Config struct {
 
 	SecretStores      map[string]telegraf.SecretStore
 	secretStoreSource map[string][]string
+	secretStoreHashes map[string]string
 
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
@@ -105,6 +106,13 @@ type Config struct {
 	fileProcessors    OrderedPlugins
 	fileAggProcessors OrderedPlugins
 
+	// tableOrigins are the files the plugin tables of the currently loading
+	// configuration are defined in, including the included files
+	tableOrigins map[*ast.Table]tableOrigin
+
+	// Pipelines are the named pipelines in definition order
+	Pipelines []*Pipeline
+
 	// Parsers are created by their inputs during gather. Config doesn't keep track of them
 	// like the other plugins because they need to be garbage collected (See issue #11809)
 
@@ -119,15 +127,22 @@ type Config struct {
 }
 
 // OrderedPlugin is used to keep the order in which they appear in a file
+// including the position of the (included) file the plugin is defined in
 type OrderedPlugin struct {
 	Line   int
+	file   int
 	plugin any
 }
 type OrderedPlugins []*OrderedPlugin
 
-func (op OrderedPlugins) Len() int           { return len(op) }
-func (op OrderedPlugins) Swap(i, j int)      { op[i], op[j] = op[j], op[i] }
-func (op OrderedPlugins) Less(i, j int) bool { return op[i].Line < op[j].Line }
+func (op OrderedPlugins) Len() int      { return len(op) }
+func (op OrderedPlugins) Swap(i, j int) { op[i], op[j] = op[j], op[i] }
+func (op OrderedPlugins) Less(i, j int) bool {
+	if op[i].file != op[j].file {
+		return op[i].file < op[j].file
+	}
+	return op[i].Line < op[j].Line
+}
 
 // NewConfig creates a new struct to hold the Telegraf config.
 // For historical reasons, It holds the actual instances of the running plugins
@@ -143,6 +158,7 @@ func NewConfig() *Config {
 			RoundInterval:              true,
 			FlushInterval:              Duration(10 * time.Second),
 			LogfileRotationMaxArchives: 5,
+			StatefileGenerations:       1,
 		},
 
 		Tags:               make(map[string]string),
@@ -152,6 +168,7 @@ func NewConfig() *Config {
 		AggProcessors:      make([]*models.RunningProcessor, 0),
 		SecretStores:       make(map[string]telegraf.SecretStore),
 		secretStoreSource:  make(map[string][]string),
+		secretStoreHashes:  make(map[string]string),
 		fileProcessors:     make([]*OrderedPlugin, 0),
 		fileAggProcessors:  make([]*OrderedPlugin, 0),
 		InputFilters:       make([]string, 0),
@@ -213,6 +230,11 @@ type AgentConfig struct {
 	// at the same time by manually scheduling them in time.
 	CollectionOffset Duration
 
+	// GatherTimeout is the maximum time a gather of an input may take before
+	// it is cancelled or abandoned and counted as failed. A timeout of zero
+	// disables the limit.
+	GatherTimeout Duration
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval Duration
 
@@ -283,6 +305,16 @@ type AgentConfig struct {
 	// the state in the file will be restored for the plugins.
 	Statefile string `toml:"statefile"`
 
+	// Interval for periodically writing the state of plugins to the
+	// statefile in addition to storing the state on shutdown. When set to
+	// zero, the state is only stored on shutdown.
+	StatefileInterval Duration `toml:"statefile_interval"`
+
+	// Number of previous statefiles to keep. If the statefile is corrupt on
+	// startup, the newest valid previous statefile is used instead. Defaults
+	// to one generation, zero disables the fallback.
+	StatefileGenerations int `toml:"statefile_generations"`
+
 	// Flag to always keep tags explicitly defined in the plugin itself and
 	// ensure those tags always pass filtering.
 	AlwaysIncludeLocalTags bool `toml:"always_include_local_tags"`
@@ -301,11 +333,13 @@ type AgentConfig struct {
 	ConfigURLRetryAttempts int `toml:"config_url_retry_attempts"`
 
 	// BufferStrategy is the metric buffer type to use for a given output plugin.
-	// Supported types currently are "memory" and "disk_write_through" (alias: "disk").
+	// Supported types currently are "memory", "disk_write_through" (alias: "disk")
+	// and "hybrid".
 	BufferStrategy string `toml:"buffer_strategy"`
 
 	// BufferDirectory is the directory to store buffer files for serialized
-	// to disk metrics when using the "disk_write_through" buffer strategy.
+	// to disk metrics when using the "disk_write_through" or "hybrid" buffer
+	// strategy.
 	BufferDirectory string `toml:"buffer_directory"`
 
 	// BufferDiskSync controls writes durability when "disk" buffer strategy
@@ -313,6 +347,74 @@ type AgentConfig struct {
 	// metrics buffered in the last `flush_interval` in the event of a power
 	// cut.
 	BufferDiskSync *bool `toml:"buffer_disk_sync"`
+
+	// BufferMemoryLimit is the estimated size of the metrics kept in memory
+	// by the "hybrid" buffers of all outputs together. Metrics exceeding the
+	// limit are spilled to disk. BufferSpillAfter is the duration of failing
+	// writes of an output after which its metrics in memory are spilled to
+	// disk, zero disables spilling on failures.
+	BufferMemoryLimit Size     `toml:"buffer_memory_limit"`
+	BufferSpillAfter  Duration `toml:"buffer_spill_after"`
+
+	// BufferFullBehavior controls the handling of new metrics when the buffer
+	// of an output is full. With "drop", the default, the oldest metrics are
+	// overwritten. With "pause" the delivery of service inputs using metric
+	// tracking is paused until there is room in the buffer again.
+	BufferFullBehavior string `toml:"buffer_full_behavior"`
+
+	// ShutdownDrainTimeout is the maximum time to keep writing the buffered
+	// metrics of the outputs on shutdown until the buffers are empty. With a
+	// zero timeout the outputs are written only once. Metrics still buffered
+	// afterwards are moved to the ShutdownSpoolDirectory, if set, and
+	// re-queued on the next start.
+	ShutdownDrainTimeout   Duration `toml:"shutdown_drain_timeout"`
+	ShutdownSpoolDirectory string   `toml:"shutdown_spool_directory"`
+
+	// CardinalityLimit is the maximum number of distinct series of each
+	// measurement of an input within CardinalityWindow. New series exceeding
+	// the limit are handled according to CardinalityAction being "drop",
+	// "sample" or "strip_tag". CardinalitySampleRatio is the ratio of new
+	// series accepted with the "sample" action. A limit of zero disables the
+	// cardinality guard.
+	CardinalityLimit       int      `toml:"cardinality_limit"`
+	CardinalityWindow      Duration `toml:"cardinality_window"`
+	CardinalityAction      string   `toml:"cardinality_action"`
+	CardinalitySampleRatio float64  `toml:"cardinality_sample_ratio"`
+
+	// ManagementServiceAddress is the address to serve the HTTP management
+	// API of the agent on, e.g. "http://localhost:8091" or "unix:///path".
+	// The API is disabled if empty.
+	ManagementServiceAddress string `toml:"management_service_address"`
+
+	// Credentials for basic authentication against the management API.
+	ManagementBasicUsername string `toml:"management_basic_username"`
+	ManagementBasicPassword string `toml:"management_basic_password"`
+
+	// TLS certificate and key for serving the management API via HTTPS and
+	// CA certificates for requiring and verifying client certificates.
+	ManagementTLSCert           string   `toml:"management_tls_cert"`
+	ManagementTLSKey            string   `toml:"management_tls_key"`
+	ManagementTLSAllowedCACerts []string `toml:"management_tls_allowed_cacerts"`
+
+	// MetricsServiceAddress is the address to serve the agent's self-metrics
+	// in the Prometheus and OpenMetrics formats on, e.g. "http://:9273". The
+	// metrics are served on the "/metrics" path. Disabled if empty.
+	MetricsServiceAddress string `toml:"metrics_service_address"`
+
+	// TracingEndpoint is the OTLP endpoint to export traces of the plugin
+	// operations to, e.g. "localhost:4317" for the "grpc" or
+	// "http://localhost:4318/v1/traces" for the "http" TracingProtocol.
+	// TracingSampleRate is the ratio of traced operations between 0 and 1.
+	// Tracing is disabled if the endpoint is empty.
+	TracingEndpoint   string  `toml:"tracing_endpoint"`
+	TracingProtocol   string  `toml:"tracing_protocol"`
+	TracingSampleRate float64 `toml:"tracing_sample_rate"`
+
+	// SecretRefreshInterval is the interval for checking the secrets used by
+	// the plugins for changes. Secrets of stores reporting a TTL are checked
+	// once expired and stores notifying about changes are watched regardless
+	// of the interval. Zero disables the periodic check.
+	SecretRefreshInterval Duration `toml:"secret_refresh_interval"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -597,6 +699,16 @@ func (c *Config) LoadAll(configFiles ...string) error {
 	}
 	c.NumberSecrets = uint64(count)
 
+	// Check the dead-letter settings of the outputs
+	if err := models.LinkDeadLetters(c.Outputs); err != nil {
+		return err
+	}
+
+	// Check the pipelines and the pipeline settings of the plugins
+	if err := c.checkPipelines(); err != nil {
+		return err
+	}
+
 	// Let's link all secrets to their secret stores
 	return c.LinkSecrets()
 }
@@ -613,12 +725,17 @@ func WithSourcePath(path string) cfgDataOption {
 	}
 }
 
-// LoadConfigData loads TOML-formatted config data
+// LoadConfigData loads TOML-formatted config data or YAML and JSON data
+// depending on the extension of the given path
 func (c *Config) LoadConfigData(data []byte, path string) error {
-	tbl, err := parseConfig(data)
+	// Resolve the included files and apply the overlays
+	loader := newDocumentLoader(parseConfig, c.Agent.ConfigURLRetryAttempts)
+	tbl, err := loader.load(data, path)
 	if err != nil {
-		return fmt.Errorf("error parsing data: %w", err)
+		return err
 	}
+	c.tableOrigins = loader.origins
+	defer func() { c.tableOrigins = nil }()
 
 	// Parse tags tables first:
 	for _, tableName := range []string{"tags", "global_tags"} {
@@ -652,6 +769,33 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 		if c.Agent.CollectionOffset < 0 {
 			return fmt.Errorf("agent collection_offset must not be negative, found %v", c.Agent.CollectionOffset)
 		}
+
+		if c.Agent.BufferMemoryLimit < 0 {
+			return fmt.Errorf("agent buffer_memory_limit must not be negative, found %d", c.Agent.BufferMemoryLimit)
+		}
+		if c.Agent.BufferSpillAfter < 0 {
+			return fmt.Errorf("agent buffer_spill_after must not be negative, found %v", c.Agent.BufferSpillAfter)
+		}
+
+		switch c.Agent.BufferFullBehavior {
+		case "", "drop", "pause":
+		default:
+			return fmt.Errorf("invalid agent buffer_full_behavior %q", c.Agent.BufferFullBehavior)
+		}
+		if err := models.CheckCardinalityAction(c.Agent.CardinalityAction); err != nil {
+			return fmt.Errorf("invalid agent cardinality_action: %w", err)
+		}
+		if c.Agent.CardinalitySampleRatio < 0 || c.Agent.CardinalitySampleRatio > 1 {
+			return fmt.Errorf("agent cardinality_sample_ratio must be between 0 and 1, found %v", c.Agent.CardinalitySampleRatio)
+		}
+		switch c.Agent.TracingProtocol {
+		case "", "grpc", "http":
+		default:
+			return fmt.Errorf("invalid agent tracing_protocol %q", c.Agent.TracingProtocol)
+		}
+		if c.Agent.TracingSampleRate < 0 || c.Agent.TracingSampleRate > 1 {
+			return fmt.Errorf("agent tracing_sample_rate must be between 0 and 1, found %v", c.Agent.TracingSampleRate)
+		}
 	}
 
 	if !c.Agent.OmitHostname {
@@ -679,7 +823,8 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 	// Set up the persister if requested
 	if c.Agent.Statefile != "" {
 		c.Persister = &persister.Persister{
-			Filename: c.Agent.Statefile,
+			Filename:    c.Agent.Statefile,
+			Generations: c.Agent.StatefileGenerations,
 		}
 	}
 
@@ -696,6 +841,13 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
+		if name == "pipelines" {
+			if err := c.addPipelines(val); err != nil {
+				return err
+			}
+			continue
+		}
+
 		subTable, ok := val.(*ast.Table)
 		if !ok {
 			return fmt.Errorf("invalid configuration, error parsing field %q as table", name)
@@ -708,12 +860,12 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
-					if err = c.addOutput(pluginName, path, pluginSubTable); err != nil {
+					if err = c.addOutput(pluginName, c.tableSource(pluginSubTable, path), pluginSubTable); err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addOutput(pluginName, path, t); err != nil {
+						if err = c.addOutput(pluginName, c.tableSource(t, path), t); err != nil {
 							return fmt.Errorf("error parsing %s array, %w", pluginName, err)
 						}
 					}
@@ -733,12 +885,12 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
-					if err = c.addInput(pluginName, path, pluginSubTable); err != nil {
+					if err = c.addInput(pluginName, c.tableSource(pluginSubTable, path), pluginSubTable); err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, path, t); err != nil {
+						if err = c.addInput(pluginName, c.tableSource(t, path), t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -758,7 +910,7 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addProcessor(pluginName, path, t); err != nil {
+						if err = c.addProcessor(pluginName, c.tableSource(t, path), t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -782,7 +934,7 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addAggregator(pluginName, path, t); err != nil {
+						if err = c.addAggregator(pluginName, c.tableSource(t, path), t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -802,7 +954,7 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 				switch pluginSubTable := pluginVal.(type) {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
-						if err = c.addSecretStore(pluginName, path, t); err != nil {
+						if err = c.addSecretStore(pluginName, c.tableSource(t, path), t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
 					}
@@ -840,6 +992,15 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 	return nil
 }
 
+// tableSource returns the file the plugin table is defined in which might be
+// an included file of the given configuration file
+func (c *Config) tableSource(table *ast.Table, path string) string {
+	if origin, found := c.tableOrigins[table]; found {
+		return origin.source
+	}
+	return path
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatibility only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -867,6 +1028,15 @@ func LoadConfigFileWithRetries(config string, urlRetryAttempts int) ([]byte, boo
 			if err != nil {
 				return nil, true, err
 			}
+			if len(signatureKeys) > 0 {
+				sig, err := fetchConfig(signatureURL(u), urlRetryAttempts)
+				if err != nil {
+					return nil, true, fmt.Errorf("%w: fetching signature failed: %w", ErrInvalidSignature, err)
+				}
+				if err := verifySignature(data, sig, signatureKeys); err != nil {
+					return nil, true, fmt.Errorf("verifying %s failed: %w", u.Redacted(), err)
+				}
+			}
 			sourcesMu.Lock()
 			sources = append(sources, u.Redacted())
 			sourcesMu.Unlock()
@@ -1079,7 +1249,12 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 	if _, found := c.SecretStores[storeID]; found {
 		return fmt.Errorf("duplicate ID %q for secretstore %q", storeID, name)
 	}
+	hash, err := generatePluginID("secretstores."+name, table)
+	if err != nil {
+		return fmt.Errorf("generating hash for secret store %q failed: %w", storeID, err)
+	}
 	c.SecretStores[storeID] = store
+	c.secretStoreHashes[storeID] = hash
 	if _, found := c.secretStoreSource[name]; !found {
 		c.secretStoreSource[name] = make([]string, 0)
 	}
@@ -1087,8 +1262,14 @@ func (c *Config) addSecretStore(name, source string, table *ast.Table) error {
 	return nil
 }
 
+// LinkSecrets links the secrets not linked yet to the secret stores of the
+// config. Secrets of plugins kept running on a reload are already linked and
+// are left untouched. Linked secrets are no longer tracked.
 func (c *Config) LinkSecrets() error {
 	for _, s := range unlinkedSecrets {
+		if len(s.GetUnlinked()) == 0 {
+			continue
+		}
 		resolvers := make(map[string]telegraf.ResolveFunc)
 		for _, ref := range s.GetUnlinked() {
 			// Split the reference and lookup the resolver
@@ -1108,6 +1289,7 @@ func (c *Config) LinkSecrets() error {
 			return fmt.Errorf("retrieving resolver failed: %w", err)
 		}
 	}
+	unlinkedSecrets = make([]*Secret, 0)
 	return nil
 }
 
@@ -1252,7 +1434,11 @@ func (c *Config) addProcessor(name, source string, table *ast.Table) error {
 		return err
 	}
 	rf := models.NewRunningProcessor(processorBefore, processorBeforeConfig)
-	c.fileProcessors = append(c.fileProcessors, &OrderedPlugin{table.Line, rf})
+	c.fileProcessors = append(c.fileProcessors, &OrderedPlugin{
+		Line:   table.Line,
+		file:   c.tableOrigins[table].file,
+		plugin: rf,
+	})
 
 	// Setup another (new) processor instance running after the aggregator
 	processorAfterConfig, err := c.buildProcessor("aggprocessors", name, source, table)
@@ -1264,7 +1450,11 @@ func (c *Config) addProcessor(name, source string, table *ast.Table) error {
 		return err
 	}
 	rf = models.NewRunningProcessor(processorAfter, processorAfterConfig)
-	c.fileAggProcessors = append(c.fileAggProcessors, &OrderedPlugin{table.Line, rf})
+	c.fileAggProcessors = append(c.fileAggProcessors, &OrderedPlugin{
+		Line:   table.Line,
+		file:   c.tableOrigins[table].file,
+		plugin: rf,
+	})
 
 	// Check the number of misses against the threshold. We need to double
 	// the count as the processor setup is executed twice.
@@ -1367,6 +1557,9 @@ func (c *Config) addOutput(name, source string, table *ast.Table) error {
 	c.setLocalMissingTomlFieldTracker(missCount)
 	defer c.resetMissingTomlFieldTracker()
 
+	// Remember the secrets of the plugin to refresh it on secret rotation
+	secretsStart := len(unlinkedSecrets)
+
 	creator, ok := outputs.Outputs[name]
 	if !ok {
 		// Handle removed, deprecated plugins
@@ -1407,6 +1600,7 @@ func (c *Config) addOutput(name, source string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
+	outputConfig.Secrets = secretReferencesSince(secretsStart)
 
 	if err := c.printUserDeprecation("outputs", name, output); err != nil {
 		return err
@@ -1428,6 +1622,21 @@ func (c *Config) addOutput(name, source string, table *ast.Table) error {
 		}
 	}
 
+	// Use a dedicated serializer for estimating the size of metrics as the
+	// serializer of the output might not be safe for concurrent use. The
+	// underlying serializer is used to not count the estimates in the
+	// serializer statistics.
+	if outputConfig.MetricBatchBytes > 0 || outputConfig.MetricBufferBytes > 0 {
+		switch output.(type) {
+		case telegraf.SerializerPlugin, telegraf.SerializerFuncPlugin:
+			serializer, err := c.addSerializer(name, table)
+			if err != nil {
+				return err
+			}
+			outputConfig.SizeSerializer = serializer.Serializer
+		}
+	}
+
 	ro, err := models.NewRunningOutput(output, outputConfig, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
 	if err != nil {
 		return err
@@ -1461,6 +1670,9 @@ func (c *Config) addInput(name, source string, table *ast.Table) error {
 	c.setLocalMissingTomlFieldTracker(missCount)
 	defer c.resetMissingTomlFieldTracker()
 
+	// Remember the secrets of the plugin to refresh it on secret rotation
+	secretsStart := len(unlinkedSecrets)
+
 	creator, ok := inputs.Inputs[name]
 	if !ok {
 		// Handle removed, deprecated plugins
@@ -1502,6 +1714,7 @@ func (c *Config) addInput(name, source string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
+	pluginConfig.Secrets = secretReferencesSince(secretsStart)
 
 	if err := c.printUserDeprecation("inputs", name, input); err != nil {
 		return err
@@ -1537,6 +1750,7 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 	conf := &models.AggregatorConfig{
 		Name:   name,
 		Source: source,
+		Line:   tbl.Line,
 		Delay:  time.Millisecond * 100,
 		Period: time.Second * 30,
 		Grace:  time.Second * 0,
@@ -1557,7 +1771,11 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 	conf.MeasurementSuffix = c.getFieldString(tbl, "name_suffix")
 	conf.NameOverride = c.getFieldString(tbl, "name_override")
 	conf.Alias = c.getFieldString(tbl, "alias")
+	if labels := c.getFieldMap(tbl, "labels"); len(labels) > 0 {
+		conf.Labels = labels
+	}
 	conf.LogLevel = c.getFieldString(tbl, "log_level")
+	conf.Pipeline = c.getFieldString(tbl, "pipeline")
 
 	conf.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1590,11 +1808,16 @@ func (c *Config) buildProcessor(category, name, source string, tbl *ast.Table) (
 	conf := &models.ProcessorConfig{
 		Name:   name,
 		Source: source,
+		Line:   tbl.Line,
 	}
 
 	conf.Order = c.getFieldInt64(tbl, "order")
 	conf.Alias = c.getFieldString(tbl, "alias")
+	if labels := c.getFieldMap(tbl, "labels"); len(labels) > 0 {
+		conf.Labels = labels
+	}
 	conf.LogLevel = c.getFieldString(tbl, "log_level")
+	conf.Pipeline = c.getFieldString(tbl, "pipeline")
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
@@ -1699,8 +1922,14 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	cp := &models.InputConfig{
 		Name:                    name,
 		Source:                  source,
+		Line:                    tbl.Line,
 		AlwaysIncludeLocalTags:  c.Agent.AlwaysIncludeLocalTags,
 		AlwaysIncludeGlobalTags: c.Agent.AlwaysIncludeGlobalTags,
+		CardinalityLimit:        c.Agent.CardinalityLimit,
+		CardinalityWindow:       time.Duration(c.Agent.CardinalityWindow),
+		CardinalityAction:       c.Agent.CardinalityAction,
+		CardinalitySampleRatio:  c.Agent.CardinalitySampleRatio,
+		GatherTimeout:           time.Duration(c.Agent.GatherTimeout),
 	}
 	cp.Interval, _ = c.getFieldDuration(tbl, "interval")
 	cp.Precision, _ = c.getFieldDuration(tbl, "precision")
@@ -1709,6 +1938,19 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	if cp.CollectionOffset < 0 {
 		return nil, fmt.Errorf("negative collection_offset %q is not allowed", cp.CollectionOffset)
 	}
+	cp.Schedule = c.getFieldString(tbl, "schedule")
+	cp.ScheduleTimezone = c.getFieldString(tbl, "schedule_timezone")
+	if cp.Schedule != "" {
+		if _, err := models.ParseSchedule(cp.Schedule, cp.ScheduleTimezone); err != nil {
+			return nil, fmt.Errorf("plugin inputs.%s: %w", name, err)
+		}
+	}
+	if timeout, found := c.getFieldDuration(tbl, "gather_timeout"); found {
+		cp.GatherTimeout = timeout
+	}
+	if cp.GatherTimeout < 0 {
+		return nil, fmt.Errorf("negative gather_timeout %q is not allowed", cp.GatherTimeout)
+	}
 	cp.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
 	cp.TimeSource = c.getFieldString(tbl, "time_source")
 
@@ -1716,6 +1958,9 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	cp.MeasurementSuffix = c.getFieldString(tbl, "name_suffix")
 	cp.NameOverride = c.getFieldString(tbl, "name_override")
 	cp.Alias = c.getFieldString(tbl, "alias")
+	if labels := c.getFieldMap(tbl, "labels"); len(labels) > 0 {
+		cp.Labels = labels
+	}
 	cp.LogLevel = c.getFieldString(tbl, "log_level")
 
 	cp.Tags = make(map[string]string)
@@ -1764,10 +2009,14 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc := &models.OutputConfig{
 		Name:            name,
 		Source:          source,
+		Line:            tbl.Line,
 		Filter:          filter,
 		BufferStrategy:  bufferStrategy,
 		BufferDirectory: c.Agent.BufferDirectory,
 		BufferDiskSync:  bufferDiskSync,
+
+		BufferMemoryLimit: int64(c.Agent.BufferMemoryLimit),
+		BufferSpillAfter:  time.Duration(c.Agent.BufferSpillAfter),
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1776,11 +2025,29 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc.FlushJitter, _ = c.getFieldDuration(tbl, "flush_jitter")
 	oc.MetricBufferLimit = c.getFieldInt(tbl, "metric_buffer_limit")
 	oc.MetricBatchSize = c.getFieldInt(tbl, "metric_batch_size")
+	oc.MetricBatchBytes = c.getFieldSize(tbl, "metric_batch_bytes")
+	oc.MetricBufferBytes = c.getFieldSize(tbl, "metric_buffer_bytes")
+	oc.BatchAutoTune = c.getFieldBool(tbl, "metric_batch_auto_tune")
+	oc.BatchTargetLatency, _ = c.getFieldDuration(tbl, "metric_batch_target_latency")
+	oc.InFlightBatches = c.getFieldInt(tbl, "in_flight_batches")
+	oc.StrictSeriesOrdering = c.getFieldBool(tbl, "strict_series_ordering")
+	oc.RetryInitialInterval, _ = c.getFieldDuration(tbl, "retry_initial_interval")
+	oc.RetryMaxInterval, _ = c.getFieldDuration(tbl, "retry_max_interval")
+	oc.RetryMultiplier = c.getFieldFloat(tbl, "retry_multiplier")
+	oc.CircuitBreakerFailures = c.getFieldInt(tbl, "circuit_breaker_failures")
+	oc.CircuitBreakerTimeout, _ = c.getFieldDuration(tbl, "circuit_breaker_timeout")
 	oc.Alias = c.getFieldString(tbl, "alias")
+	if labels := c.getFieldMap(tbl, "labels"); len(labels) > 0 {
+		oc.Labels = labels
+	}
 	oc.NameOverride = c.getFieldString(tbl, "name_override")
 	oc.NameSuffix = c.getFieldString(tbl, "name_suffix")
 	oc.NamePrefix = c.getFieldString(tbl, "name_prefix")
 	oc.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
+	oc.DeadLetter = c.getFieldString(tbl, "dead_letter")
+	oc.FailoverGroup = c.getFieldString(tbl, "failover_group")
+	oc.FailoverMaxFailures = c.getFieldInt(tbl, "failover_max_failures")
+	oc.Pipeline = c.getFieldString(tbl, "pipeline")
 	oc.LogLevel = c.getFieldString(tbl, "log_level")
 
 	if c.hasErrs() {
@@ -1790,6 +2057,9 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	if err := models.CheckBufferSettings(oc.BufferStrategy); err != nil {
 		return nil, err
 	}
+	if oc.RetryMultiplier != 0 && oc.RetryMultiplier < 1 {
+		return nil, fmt.Errorf("invalid 'retry_multiplier' setting %v for outputs.%s, must be at least 1", oc.RetryMultiplier, name)
+	}
 	if c.TestMode {
 		oc.BufferStrategy = "discard"
 	} else if oc.BufferStrategy == "disk_write_through" {
@@ -1806,16 +2076,21 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	// General options to ignore
 	case "alias", "always_include_local_tags",
 		"buffer_strategy", "buffer_directory", "buffer_disk_sync",
+		"circuit_breaker_failures", "circuit_breaker_timeout",
 		"collection_jitter", "collection_offset",
-		"data_format", "delay", "drop", "drop_original",
+		"data_format", "dead_letter", "delay", "drop", "drop_original",
+		"failover_group", "failover_max_failures",
 		"fielddrop", "fieldexclude", "fieldinclude", "fieldpass", "flush_interval", "flush_jitter",
-		"grace",
-		"interval",
+		"gather_timeout", "grace",
+		"in_flight_batches", "interval",
 		"log_level", "lvm", // What is this used for?
-		"metric_batch_size", "metric_buffer_limit", "metricpass",
+		"metric_batch_auto_tune", "metric_batch_bytes", "metric_batch_size", "metric_batch_target_latency",
+		"metric_buffer_bytes", "metric_buffer_limit", "metricpass",
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
-		"pass", "period", "precision",
+		"pass", "period", "pipeline", "precision",
+		"retry_initial_interval", "retry_max_interval", "retry_multiplier",
+		"schedule", "schedule_timezone", "strict_series_ordering",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "startup_error_behavior", "labels":
 
 	// secret store options to ignore
@@ -1959,6 +2234,59 @@ func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string) int64 {
 	return 0
 }
 
+func (c *Config) getFieldFloat(tbl *ast.Table, fieldName string) float64 {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Float:
+				f, err := v.Float()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected float type %q, expecting float", v.Value))
+					return 0
+				}
+				return f
+			case *ast.Integer:
+				i, err := v.Int()
+				if err != nil {
+					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting float", v.Value))
+					return 0
+				}
+				return float64(i)
+			}
+			c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting float", fieldName))
+			return 0
+		}
+	}
+
+	return 0
+}
+
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string) int64 {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size Size
+			switch v := kv.Value.(type) {
+			case *ast.Integer:
+				if err := size.UnmarshalText([]byte(v.Value)); err != nil {
+					c.addError(tbl, fmt.Errorf("error parsing size: %w", err))
+					return 0
+				}
+			case *ast.String:
+				if err := size.UnmarshalText([]byte(v.Value)); err != nil {
+					c.addError(tbl, fmt.Errorf("error parsing size: %w", err))
+					return 0
+				}
+			default:
+				c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting size", fieldName))
+				return 0
+			}
+			return int64(size)
+		}
+	}
+
+	return 0
+}
+
 func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string) []string {
 	var target []string
 	if node, ok := tbl.Fields[fieldName]; ok {
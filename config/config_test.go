@@ -90,6 +90,7 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 	inputConfig := &models.InputConfig{
 		Name:     "memcached",
 		Source:   confFile,
+		Line:     16,
 		Filter:   filter,
 		Interval: 10 * time.Second,
 	}
@@ -103,6 +104,45 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct input metadata.")
 }
 
+func TestConfig_LoadFormats(t *testing.T) {
+	t.Setenv("MY_TEST_SERVER", "192.168.1.1")
+
+	var ids []string
+	for _, fn := range []string{"telegraf.toml", "telegraf.yaml", "telegraf.json"} {
+		t.Run(fn, func(t *testing.T) {
+			c := config.NewConfig()
+			require.NoError(t, c.LoadConfig(filepath.Join("testdata", "formats", fn)))
+			require.Empty(t, c.UnusedFields)
+
+			require.Equal(t, map[string]string{"dc": "us-east-1"}, c.Tags)
+			require.Equal(t, config.Duration(10*time.Second), c.Agent.Interval)
+			require.True(t, c.Agent.OmitHostname)
+
+			require.Len(t, c.Inputs, 2)
+			input := c.Inputs[0].Input.(*MockupInputPlugin)
+			require.Equal(t, []string{"192.168.1.1"}, input.Servers)
+			require.Equal(t, 11211, input.Port)
+			require.Equal(t, []string{"metricname1", "metricname2"}, c.Inputs[0].Config.Filter.NamePass)
+			require.Len(t, c.Inputs[0].Config.Filter.TagPassFilters, 1)
+			require.Equal(t, "goodtag", c.Inputs[0].Config.Filter.TagPassFilters[0].Name)
+			require.Equal(t, []string{"mytag"}, c.Inputs[0].Config.Filter.TagPassFilters[0].Values)
+			require.Equal(t, map[string]string{"role": "cache"}, c.Inputs[0].Config.Tags)
+			require.Equal(t, "second", c.Inputs[1].Config.Alias)
+			require.Equal(t, []string{"localhost"}, c.Inputs[1].Input.(*MockupInputPlugin).Servers)
+
+			require.Len(t, c.Outputs, 1)
+			require.Equal(t, []string{"read"}, c.Outputs[0].Output.(*MockupOutputPlugin).Scopes)
+
+			ids = append(ids, c.Inputs[0].Config.ID, c.Inputs[1].Config.ID, c.Outputs[0].Config.ID)
+		})
+	}
+
+	// Equivalent configurations result in the same plugin IDs
+	require.Len(t, ids, 9)
+	require.Equal(t, ids[:3], ids[3:6])
+	require.Equal(t, ids[:3], ids[6:])
+}
+
 func TestConfig_LoadSingleInput(t *testing.T) {
 	c := config.NewConfig()
 	confFile := filepath.Join("testdata", "single_plugin.toml")
@@ -133,6 +173,7 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 	inputConfig := &models.InputConfig{
 		Name:     "memcached",
 		Source:   confFile,
+		Line:     1,
 		Filter:   filter,
 		Interval: 5 * time.Second,
 	}
@@ -169,6 +210,119 @@ func TestConfig_InputCollectionJitterExplicitZeroIsSet(t *testing.T) {
 	require.False(t, c.Inputs[1].Config.CollectionJitterSet)
 }
 
+func TestConfig_InputCardinalityLimit(t *testing.T) {
+	c := config.NewConfig()
+	cfg := []byte(`
+[agent]
+  cardinality_limit = 1000
+  cardinality_window = "10m"
+  cardinality_action = "strip_tag"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+`)
+	require.NoError(t, c.LoadConfigData(cfg, config.EmptySourcePath))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 1000, c.Inputs[0].Config.CardinalityLimit)
+	require.Equal(t, 10*time.Minute, c.Inputs[0].Config.CardinalityWindow)
+	require.Equal(t, "strip_tag", c.Inputs[0].Config.CardinalityAction)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[agent]
+  cardinality_limit = 1000
+  cardinality_action = "truncate"
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), `invalid cardinality action "truncate"`)
+}
+
+func TestConfig_InputSchedule(t *testing.T) {
+	c := config.NewConfig()
+	cfg := []byte(`
+[[inputs.memcached]]
+  schedule = "0 2 * * 1-5"
+  schedule_timezone = "Europe/Amsterdam"
+  servers = ["localhost"]
+`)
+	require.NoError(t, c.LoadConfigData(cfg, config.EmptySourcePath))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, "0 2 * * 1-5", c.Inputs[0].Config.Schedule)
+	require.Equal(t, "Europe/Amsterdam", c.Inputs[0].Config.ScheduleTimezone)
+	require.Empty(t, c.UnusedFields)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[[inputs.memcached]]
+  schedule = "0 25 * * *"
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), `invalid schedule "0 25 * * *"`)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[[inputs.memcached]]
+  schedule = "@daily"
+  schedule_timezone = "Mars/Olympus_Mons"
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), `invalid schedule timezone "Mars/Olympus_Mons"`)
+}
+
+func TestConfig_InputGatherTimeout(t *testing.T) {
+	c := config.NewConfig()
+	cfg := []byte(`
+[agent]
+  gather_timeout = "30s"
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+
+[[inputs.memcached]]
+  gather_timeout = "5s"
+  servers = ["localhost"]
+`)
+	require.NoError(t, c.LoadConfigData(cfg, config.EmptySourcePath))
+	require.Len(t, c.Inputs, 2)
+	require.Equal(t, 30*time.Second, c.Inputs[0].Config.GatherTimeout)
+	require.Equal(t, 5*time.Second, c.Inputs[1].Config.GatherTimeout)
+	require.Empty(t, c.UnusedFields)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[[inputs.memcached]]
+  gather_timeout = "-5s"
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), `negative gather_timeout "-5s" is not allowed`)
+}
+
+func TestConfig_AgentTracing(t *testing.T) {
+	c := config.NewConfig()
+	cfg := []byte(`
+[agent]
+  tracing_endpoint = "http://localhost:4318/v1/traces"
+  tracing_protocol = "http"
+  tracing_sample_rate = 0.5
+`)
+	require.NoError(t, c.LoadConfigData(cfg, config.EmptySourcePath))
+	require.Equal(t, "http://localhost:4318/v1/traces", c.Agent.TracingEndpoint)
+	require.Equal(t, "http", c.Agent.TracingProtocol)
+	require.InDelta(t, 0.5, c.Agent.TracingSampleRate, 0)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[agent]
+  tracing_endpoint = "localhost:4317"
+  tracing_protocol = "udp"
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), `invalid agent tracing_protocol "udp"`)
+
+	c = config.NewConfig()
+	cfg = []byte(`
+[agent]
+  tracing_endpoint = "localhost:4317"
+  tracing_sample_rate = 2.0
+`)
+	require.ErrorContains(t, c.LoadConfigData(cfg, config.EmptySourcePath), "agent tracing_sample_rate must be between 0 and 1")
+}
+
 func TestConfig_LoadSingleInput_WithSeparators(t *testing.T) {
 	c := config.NewConfig()
 	confFile := filepath.Join("testdata", "single_plugin_with_separators.toml")
@@ -201,6 +355,7 @@ func TestConfig_LoadSingleInput_WithSeparators(t *testing.T) {
 	inputConfig := &models.InputConfig{
 		Name:     "memcached",
 		Source:   confFile,
+		Line:     1,
 		Filter:   filter,
 		Interval: 5 * time.Second,
 	}
@@ -261,6 +416,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	expectedConfigs[0] = &models.InputConfig{
 		Name:     "memcached",
 		Source:   confFile,
+		Line:     1,
 		Filter:   filterMockup,
 		Interval: 5 * time.Second,
 	}
@@ -278,6 +434,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	expectedConfigs[1] = &models.InputConfig{
 		Name:              "exec",
 		Source:            filepath.Join("testdata", "subconfig", "exec.conf"), // This is the source of the input
+		Line:              1,
 		MeasurementSuffix: "_myothercollector",
 	}
 	expectedConfigs[1].Tags = make(map[string]string)
@@ -307,6 +464,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	expectedConfigs[2] = &models.InputConfig{
 		Name:     "memcached",
 		Source:   filepath.Join("testdata", "subconfig", "memcached.conf"), // This is the source of the input
+		Line:     1,
 		Filter:   filterMemcached,
 		Interval: 5 * time.Second,
 	}
@@ -317,6 +475,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	expectedConfigs[3] = &models.InputConfig{
 		Name:   "procstat",
 		Source: filepath.Join("testdata", "subconfig", "procstat.conf"), // This is the source of the input
+		Line:   1,
 	}
 	expectedConfigs[3].Tags = make(map[string]string)
 
@@ -559,6 +718,115 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	}
 }
 
+func TestConfig_DeadLetter(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/dead_letter.toml"))
+	require.Len(t, c.Outputs, 2)
+	require.Equal(t, "dlq", c.Outputs[0].Config.DeadLetter)
+	require.Same(t, c.Outputs[1], c.Outputs[0].DeadLetter())
+	require.Nil(t, c.Outputs[1].DeadLetter())
+
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/dead_letter_invalid.toml"), `dead-letter output "unknown" of outputs.http not found`)
+}
+
+func TestConfig_FailoverGroup(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/failover_group.toml"))
+	require.Len(t, c.Outputs, 2)
+	require.Equal(t, "http", c.Outputs[0].Config.FailoverGroup)
+	require.Equal(t, 5, c.Outputs[0].Config.FailoverMaxFailures)
+	require.Equal(t, "http", c.Outputs[1].Config.FailoverGroup)
+	require.Zero(t, c.Outputs[1].Config.FailoverMaxFailures)
+
+	// Members must filter the metrics the same way
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/failover_group_mismatch.toml"),
+		`metric filtering setting of outputs.http must match the first member of failover group "http"`)
+}
+
+func TestConfig_OutputBatchBytes(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/batch_bytes.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	// Plugins of different types are loaded in random order
+	if c.Outputs[0].Config.Name != "serializer_test_new" {
+		c.Outputs[0], c.Outputs[1] = c.Outputs[1], c.Outputs[0]
+	}
+
+	oc := c.Outputs[0].Config
+	require.Equal(t, int64(1024*1024), oc.MetricBatchBytes)
+	require.Equal(t, int64(10000000), oc.MetricBufferBytes)
+	require.True(t, oc.BatchAutoTune)
+	require.Equal(t, 500*time.Millisecond, oc.BatchTargetLatency)
+	require.NotNil(t, oc.SizeSerializer)
+
+	// Outputs without serializer estimate the size using line protocol
+	oc = c.Outputs[1].Config
+	require.Equal(t, int64(65536), oc.MetricBatchBytes)
+	require.False(t, oc.BatchAutoTune)
+	require.Nil(t, oc.SizeSerializer)
+	require.Equal(t, 4, oc.InFlightBatches)
+	require.True(t, oc.StrictSeriesOrdering)
+}
+
+func TestConfig_OutputCircuitBreaker(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/circuit_breaker.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	oc := c.Outputs[0].Config
+	require.Equal(t, time.Second, oc.RetryInitialInterval)
+	require.Equal(t, 30*time.Second, oc.RetryMaxInterval)
+	require.InDelta(t, 1.5, oc.RetryMultiplier, 1e-9)
+	require.Equal(t, 5, oc.CircuitBreakerFailures)
+	require.Equal(t, 2*time.Minute, oc.CircuitBreakerTimeout)
+
+	// Integer multipliers are accepted
+	require.InDelta(t, 3.0, c.Outputs[1].Config.RetryMultiplier, 1e-9)
+
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/circuit_breaker_invalid.toml"), "invalid 'retry_multiplier' setting")
+}
+
+func TestConfig_Pipelines(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/pipelines.toml"))
+	require.Len(t, c.Pipelines, 2)
+	require.Len(t, c.Inputs, 3)
+
+	team, archive := c.Pipeline("team_a"), c.Pipeline("archive")
+	require.NotNil(t, team)
+	require.NotNil(t, archive)
+	require.Equal(t, []string{"archive"}, team.Fanout)
+	require.Nil(t, c.Pipeline("unknown"))
+
+	// Inputs are selected by alias or ID and by label selectors
+	require.True(t, team.SelectsInput(c.Inputs[0]))
+	require.False(t, archive.SelectsInput(c.Inputs[0]))
+	require.False(t, team.SelectsInput(c.Inputs[1]))
+	require.True(t, archive.SelectsInput(c.Inputs[1]))
+	require.False(t, team.SelectsInput(c.Inputs[2]))
+	require.False(t, archive.SelectsInput(c.Inputs[2]))
+
+	require.Equal(t, "team_a", c.Processors[0].Config.Pipeline)
+	require.Equal(t, "team_a", c.Outputs[0].Config.Pipeline)
+	require.Equal(t, "archive", c.Outputs[1].Config.Pipeline)
+	require.Empty(t, c.Outputs[2].Config.Pipeline)
+
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/pipelines_cycle.toml"), "forms a cycle")
+
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/pipelines_unknown.toml"), `pipeline "unknown" of outputs.http not found`)
+
+	// Plugin settings are no pipeline settings
+	c = config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/pipelines_invalid_key.toml"),
+		`pipeline "team_a": line 1: configuration specified the fields ["interval"], but they were not used`)
+}
+
 func TestGetDefaultConfigPathFromEnvURL(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
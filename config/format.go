@@ -0,0 +1,605 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+	"go.yaml.in/yaml/v3"
+)
+
+// Supported formats of configuration files
+const (
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FormatFromPath returns the configuration format of the given file or URL
+// based on its extension. Files without a known extension are TOML.
+func FormatFromPath(path string) string {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" && u.Host != "" {
+		path = u.Path
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	return FormatTOML
+}
+
+// ConvertConfig converts the configuration data from one format to another.
+// Environment variables and secret references are kept as they are, comments
+// are lost when converting from TOML or YAML.
+func ConvertConfig(data []byte, from, to string) ([]byte, error) {
+	data = trimBOM(data)
+
+	var root *yaml.Node
+	var err error
+	switch from {
+	case FormatTOML:
+		root, err = tomlToNode(data)
+	case FormatYAML:
+		root, err = yamlToNode(data)
+	case FormatJSON:
+		root, err = jsonToNode(data)
+	default:
+		return nil, fmt.Errorf("unknown config format %q", from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %w", from, err)
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid %s configuration, expected a mapping at top-level", from)
+	}
+
+	return encodeNode(root, to)
+}
+
+// encodeNode serializes the configuration node in the given format
+func encodeNode(root *yaml.Node, format string) ([]byte, error) {
+	switch format {
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := writeTOMLTable(&buf, nil, root, false); err != nil {
+			return nil, err
+		}
+		return bytes.TrimLeft(buf.Bytes(), "\n"), nil
+	case FormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(root); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, root); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// tomlToNode converts the TOML data to a YAML node tree keeping the order of
+// the tables and options.
+func tomlToNode(data []byte) (*yaml.Node, error) {
+	tbl, err := toml.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return tomlTableToNode(tbl)
+}
+
+func tomlTableToNode(tbl *ast.Table) (*yaml.Node, error) {
+	type field struct {
+		key   string
+		line  int
+		value interface{}
+	}
+	fields := make([]field, 0, len(tbl.Fields))
+	for k, v := range tbl.Fields {
+		f := field{key: k, value: v}
+		switch n := v.(type) {
+		case *ast.KeyValue:
+			f.line = n.Line
+		case *ast.Table:
+			f.line = n.Line
+		case []*ast.Table:
+			if len(n) > 0 {
+				f.line = n[0].Line
+			}
+		}
+		fields = append(fields, f)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].line == fields[j].line {
+			return fields[i].key < fields[j].key
+		}
+		return fields[i].line < fields[j].line
+	})
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, f := range fields {
+		var value *yaml.Node
+		var err error
+		switch n := f.value.(type) {
+		case *ast.KeyValue:
+			value, err = tomlValueToNode(n.Value)
+		case *ast.Table:
+			value, err = tomlTableToNode(n)
+		case []*ast.Table:
+			value = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for _, t := range n {
+				item, err := tomlTableToNode(t)
+				if err != nil {
+					return nil, err
+				}
+				value.Content = append(value.Content, item)
+			}
+		default:
+			err = fmt.Errorf("unknown node type %T", f.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("converting %q failed: %w", f.key, err)
+		}
+		node.Content = append(node.Content, scalarNode("!!str", f.key), value)
+	}
+	return node, nil
+}
+
+func tomlValueToNode(value interface{}) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case *ast.String:
+		return scalarNode("!!str", v.Value), nil
+	case *ast.Integer:
+		i, err := strconv.ParseInt(strings.ReplaceAll(v.Value, "_", ""), 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		return scalarNode("!!int", strconv.FormatInt(i, 10)), nil
+	case *ast.Float:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(v.Value, "_", ""), 64)
+		if err != nil {
+			return nil, err
+		}
+		return scalarNode("!!float", formatFloat(f)), nil
+	case *ast.Boolean:
+		return scalarNode("!!bool", v.Value), nil
+	case *ast.Datetime:
+		return scalarNode("!!timestamp", v.Value), nil
+	case *ast.Array:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, e := range v.Value {
+			item, err := tomlValueToNode(e)
+			if err != nil {
+				return nil, err
+			}
+			if item.Kind != yaml.ScalarNode {
+				node.Style = 0
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+	case *ast.Table:
+		return tomlTableToNode(v)
+	}
+	return nil, fmt.Errorf("unknown value type %T", value)
+}
+
+// yamlToNode parses the YAML data resolving aliases and merge keys. Top-level
+// keys prefixed with "x-" are removed allowing to define anchors there.
+func yamlToNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	root, err := resolveYAMLNode(doc.Content[0])
+	if err != nil || root.Kind != yaml.MappingNode {
+		return root, err
+	}
+
+	content := make([]*yaml.Node, 0, len(root.Content))
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if !strings.HasPrefix(root.Content[i].Value, "x-") {
+			content = append(content, root.Content[i], root.Content[i+1])
+		}
+	}
+	root.Content = content
+	return root, nil
+}
+
+func resolveYAMLNode(n *yaml.Node) (*yaml.Node, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return resolveYAMLNode(n.Alias)
+	case yaml.ScalarNode:
+		return scalarNode(n.ShortTag(), n.Value), nil
+	case yaml.SequenceNode:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: n.Style & yaml.FlowStyle}
+		for _, c := range n.Content {
+			item, err := resolveYAMLNode(c)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+	case yaml.MappingNode:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.ShortTag() == "!!merge" {
+				merged = append(merged, value)
+				continue
+			}
+			v, err := resolveYAMLNode(value)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key.Value, err)
+			}
+			node.Content = append(node.Content, scalarNode("!!str", key.Value), v)
+		}
+
+		// Keys of merged mappings are added unless explicitly specified
+		for _, m := range merged {
+			sources := []*yaml.Node{m}
+			if m.Kind == yaml.SequenceNode {
+				sources = m.Content
+			}
+			for _, src := range sources {
+				src, err := resolveYAMLNode(src)
+				if err != nil {
+					return nil, err
+				}
+				if src.Kind != yaml.MappingNode {
+					return nil, errors.New("merge key requires a mapping")
+				}
+				for i := 0; i+1 < len(src.Content); i += 2 {
+					if mappingValue(node, src.Content[i].Value) == nil {
+						node.Content = append(node.Content, src.Content[i], src.Content[i+1])
+					}
+				}
+			}
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("unsupported YAML node kind %v", n.Kind)
+}
+
+// jsonToNode parses the JSON data keeping the order of the keys.
+func jsonToNode(data []byte) (*yaml.Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := jsonValueToNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return node, nil
+}
+
+func jsonValueToNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := jsonValueToNode(decoder)
+				if err != nil {
+					return nil, fmt.Errorf("key %q: %w", key, err)
+				}
+				node.Content = append(node.Content, scalarNode("!!str", key.(string)), value)
+			}
+			_, err := decoder.Token()
+			return node, err
+		case '[':
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for decoder.More() {
+				item, err := jsonValueToNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, item)
+			}
+			_, err := decoder.Token()
+			return node, err
+		}
+	case string:
+		return scalarNode("!!str", t), nil
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return scalarNode("!!int", t.String()), nil
+		}
+		return scalarNode("!!float", t.String()), nil
+	case bool:
+		return scalarNode("!!bool", strconv.FormatBool(t)), nil
+	case nil:
+		return scalarNode("!!null", "null"), nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", token)
+}
+
+// writeTOMLTable writes the options of the mapping followed by its sub-tables
+// as TOML is not able to add options to a table after a sub-table. Headers of
+// tables only containing sub-tables are omitted and the output is indented
+// like the sample configurations.
+func writeTOMLTable(w *bytes.Buffer, path []string, n *yaml.Node, array bool) error {
+	implicit := len(n.Content) > 0
+	for i := 1; i < len(n.Content); i += 2 {
+		if n.Content[i].Kind != yaml.MappingNode && !isTableArray(n.Content[i]) {
+			implicit = false
+			break
+		}
+	}
+
+	var indent string
+	if len(path) > 0 && (array || !implicit) {
+		header := make([]string, 0, len(path))
+		for _, p := range path {
+			header = append(header, tomlKey(p))
+		}
+		indent = strings.Repeat("  ", max(0, len(path)-2))
+		if len(path) <= 2 {
+			w.WriteByte('\n')
+		}
+		if array {
+			fmt.Fprintf(w, "%s[[%s]]\n", indent, strings.Join(header, "."))
+		} else {
+			fmt.Fprintf(w, "%s[%s]\n", indent, strings.Join(header, "."))
+		}
+		indent += "  "
+	}
+
+	var tables []int
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		if value.Kind == yaml.MappingNode || isTableArray(value) {
+			tables = append(tables, i)
+			continue
+		}
+		v, err := tomlValue(value)
+		if err != nil {
+			return fmt.Errorf("converting %q failed: %w", strings.Join(path, ".")+"."+key, err)
+		}
+		fmt.Fprintf(w, "%s%s = %s\n", indent, tomlKey(key), v)
+	}
+
+	for _, i := range tables {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		subpath := append(append(make([]string, 0, len(path)+1), path...), key)
+		if value.Kind == yaml.MappingNode {
+			if err := writeTOMLTable(w, subpath, value, false); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, item := range value.Content {
+			if err := writeTOMLTable(w, subpath, item, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTableArray returns true for non-empty sequences of mappings
+func isTableArray(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		return false
+	}
+	for _, item := range n.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlValue(n *yaml.Node) (string, error) {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, v)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case yaml.MappingNode:
+		items := make([]string, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := tomlValue(n.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(n.Content[i].Value)+" = "+v)
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	}
+
+	switch n.Tag {
+	case "!!str", "!!timestamp", "!!binary":
+		return tomlString(n.Value), nil
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(i, 10), nil
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return "", err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("unsupported float value %q", n.Value)
+		}
+		return formatFloat(f), nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case "!!null":
+		return "", errors.New("null values are not supported")
+	}
+	return "", fmt.Errorf("unsupported value type %q", n.Tag)
+}
+
+func tomlKey(key string) string {
+	if bareKeyRe.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeJSON writes the node as compact JSON keeping the order of the keys.
+func writeJSON(w *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		w.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			w.Write(key)
+			w.WriteByte(':')
+			if err := writeJSON(w, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		w.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeJSON(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+		return nil
+	}
+
+	switch n.Tag {
+	case "!!int", "!!float", "!!bool":
+		// Reuse the TOML representation which is valid JSON for those types
+		v, err := tomlValue(n)
+		if err != nil {
+			return err
+		}
+		w.WriteString(v)
+	case "!!null":
+		w.WriteString("null")
+	default:
+		buf, err := json.Marshal(n.Value)
+		if err != nil {
+			return err
+		}
+		w.Write(buf)
+	}
+	return nil
+}
+
+func scalarNode(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,150 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	require.Equal(t, config.FormatTOML, config.FormatFromPath("/etc/telegraf/telegraf.conf"))
+	require.Equal(t, config.FormatTOML, config.FormatFromPath(""))
+	require.Equal(t, config.FormatYAML, config.FormatFromPath("telegraf.yaml"))
+	require.Equal(t, config.FormatYAML, config.FormatFromPath("telegraf.YML"))
+	require.Equal(t, config.FormatJSON, config.FormatFromPath("https://example.com/config/telegraf.json?token=x"))
+}
+
+func TestConvertConfigFromTOML(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "formats", "telegraf.toml"))
+	require.NoError(t, err)
+
+	expected := `global_tags:
+  dc: us-east-1
+agent:
+  interval: 10s
+  omit_hostname: true
+inputs:
+  memcached:
+    - servers: ['${MY_TEST_SERVER}']
+      port: 11211
+      namepass: [metricname1, metricname2]
+      tags:
+        role: cache
+      tagpass:
+        goodtag: [mytag]
+    - alias: second
+      servers: [localhost]
+outputs:
+  http:
+    - scopes: [read]
+`
+	actual, err := config.ConvertConfig(data, config.FormatTOML, config.FormatYAML)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(actual))
+
+	// Converting back results in the same configuration
+	for _, format := range []string{config.FormatYAML, config.FormatJSON} {
+		converted, err := config.ConvertConfig(data, config.FormatTOML, format)
+		require.NoError(t, err)
+		roundtrip, err := config.ConvertConfig(converted, format, config.FormatTOML)
+		require.NoError(t, err)
+		require.Equal(t, string(data), string(roundtrip), format)
+	}
+}
+
+func TestConvertConfigToTOML(t *testing.T) {
+	input := `
+x-defaults: &defaults
+  interval: 30s
+  timeout: 5s
+inputs:
+  snmp:
+    - <<: *defaults
+      agents: ["udp://127.0.0.1:161"]
+      version: 2
+      timeout: 10s
+      field:
+        - name: uptime
+          oid: RFC1213-MIB::sysUpTime.0
+          is_tag: false
+      table:
+        - name: interfaces
+          field:
+            - oid: IF-MIB::ifDescr
+              is_tag: true
+  starlark:
+    - source: |
+        def apply(metric):
+            return metric
+      ratio: 0.5
+      "key.with.dots": "yes"
+`
+	expected := `[[inputs.snmp]]
+  agents = ["udp://127.0.0.1:161"]
+  version = 2
+  timeout = "10s"
+  interval = "30s"
+  [[inputs.snmp.field]]
+    name = "uptime"
+    oid = "RFC1213-MIB::sysUpTime.0"
+    is_tag = false
+  [[inputs.snmp.table]]
+    name = "interfaces"
+    [[inputs.snmp.table.field]]
+      oid = "IF-MIB::ifDescr"
+      is_tag = true
+
+[[inputs.starlark]]
+  source = "def apply(metric):\n    return metric\n"
+  ratio = 0.5
+  "key.with.dots" = "yes"
+`
+	actual, err := config.ConvertConfig([]byte(input), config.FormatYAML, config.FormatTOML)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(actual))
+}
+
+func TestConvertConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected string
+	}{
+		{
+			name:     "null value",
+			format:   config.FormatYAML,
+			input:    "agent:\n  interval:\n",
+			expected: `converting "agent.interval" failed: null values are not supported`,
+		},
+		{
+			name:     "no mapping",
+			format:   config.FormatJSON,
+			input:    `["inputs"]`,
+			expected: "expected a mapping at top-level",
+		},
+		{
+			name:     "trailing data",
+			format:   config.FormatJSON,
+			input:    `{"agent": {}} {}`,
+			expected: "unexpected data after top-level value",
+		},
+		{
+			name:     "unknown format",
+			format:   "ini",
+			input:    "[agent]",
+			expected: `unknown config format "ini"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.ConvertConfig([]byte(tt.input), tt.format, config.FormatTOML)
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// mergedLinesPerFile is the line offset of subsequent files in merged
+// configurations
+const mergedLinesPerFile = 1 << 20
+
+// pluginCategories are the top-level tables containing plugins
+var pluginCategories = []string{"inputs", "outputs", "processors", "aggregators", "secretstores", "plugins"}
+
+// tableOrigin is the file a plugin table is defined in and the position of
+// the file in loading order where included files are loaded first.
+type tableOrigin struct {
+	source string
+	file   int
+}
+
+// documentLoader loads configuration documents, i.e. a configuration file
+// merged with the files it includes and the overlays applied.
+type documentLoader struct {
+	// parse converts the configuration data to a TOML table
+	parse func([]byte) (*ast.Table, error)
+
+	// urlRetryAttempts is the number of attempts to fetch remote includes
+	urlRetryAttempts int
+
+	// origins of all plugin tables of the loaded documents
+	origins map[*ast.Table]tableOrigin
+	files   int
+
+	// stack of the files currently loading to detect include cycles
+	stack []string
+}
+
+func newDocumentLoader(parse func([]byte) (*ast.Table, error), urlRetryAttempts int) *documentLoader {
+	return &documentLoader{
+		parse:            parse,
+		urlRetryAttempts: urlRetryAttempts,
+		origins:          make(map[*ast.Table]tableOrigin),
+	}
+}
+
+// load parses the configuration data of the given path, merges the included
+// files in front of the file's content and applies the file's overlays.
+func (l *documentLoader) load(data []byte, path string) (*ast.Table, error) {
+	if slices.Contains(l.stack, path) {
+		return nil, fmt.Errorf("include cycle detected: %s -> %s", strings.Join(l.stack, " -> "), path)
+	}
+	l.stack = append(l.stack, path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	if format := FormatFromPath(path); format != FormatTOML {
+		converted, err := ConvertConfig(data, format, FormatTOML)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing data: %w", err)
+		}
+		data = converted
+	}
+
+	tbl, err := l.parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing data: %w", err)
+	}
+
+	includes, err := stringsField(tbl, "include")
+	if err != nil {
+		return nil, err
+	}
+	delete(tbl.Fields, "include")
+
+	overlays, found := tbl.Fields["overlays"]
+	delete(tbl.Fields, "overlays")
+
+	// Load the included files in front of the content of the file itself
+	doc := tbl
+	if len(includes) > 0 {
+		doc = &ast.Table{Fields: make(map[string]interface{})}
+		for _, include := range includes {
+			paths, err := resolveInclude(path, include)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range paths {
+				buf, _, err := LoadConfigFileWithRetries(p, l.urlRetryAttempts)
+				if err != nil {
+					return nil, fmt.Errorf("loading included file %s failed: %w", p, err)
+				}
+				included, err := l.load(buf, p)
+				if err != nil {
+					return nil, fmt.Errorf("loading included file %s failed: %w", p, err)
+				}
+				if err := mergeDocuments(doc, included); err != nil {
+					return nil, fmt.Errorf("merging included file %s failed: %w", p, err)
+				}
+			}
+		}
+	}
+
+	// Record the origin of the plugins defined in this file
+	origin := tableOrigin{source: path, file: l.files}
+	l.files++
+	for _, tables := range pluginTables(tbl) {
+		for _, t := range tables {
+			l.origins[t] = origin
+		}
+	}
+
+	if doc != tbl {
+		if err := mergeDocuments(doc, tbl); err != nil {
+			return nil, err
+		}
+	}
+
+	if found {
+		if err := applyOverlays(doc, overlays); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// resolveInclude returns the files referenced by the include relative to the
+// including file. Local includes may contain glob patterns.
+func resolveInclude(base, include string) ([]string, error) {
+	if fetchURLRe.MatchString(include) {
+		return []string{include}, nil
+	}
+	if fetchURLRe.MatchString(base) {
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := url.Parse(include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q: %w", include, err)
+		}
+		return []string{u.ResolveReference(ref).String()}, nil
+	}
+
+	if !filepath.IsAbs(include) && base != "" {
+		include = filepath.Join(filepath.Dir(base), include)
+	}
+	if !strings.ContainsAny(include, "*?[") {
+		return []string{include}, nil
+	}
+	matches, err := filepath.Glob(include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %q: %w", include, err)
+	}
+	return matches, nil
+}
+
+// mergeDocuments merges the source document into the destination. Plugins
+// and pipelines are appended while the options of other tables, e.g. the
+// agent settings, are overridden by the source.
+func mergeDocuments(dst, src *ast.Table) error {
+	for key, value := range src.Fields {
+		existing, found := dst.Fields[key]
+		if !found {
+			dst.Fields[key] = value
+			continue
+		}
+
+		switch {
+		case key == "pipelines":
+			a, aok := existing.([]*ast.Table)
+			b, bok := value.([]*ast.Table)
+			if !aok || !bok {
+				return errors.New("invalid configuration, pipelines must be defined as [[pipelines]]")
+			}
+			dst.Fields[key] = append(a, b...)
+		case slices.Contains(pluginCategories, key):
+			a, aok := existing.(*ast.Table)
+			b, bok := value.(*ast.Table)
+			if !aok || !bok {
+				return fmt.Errorf("invalid configuration, error parsing field %q as table", key)
+			}
+			for name, plugins := range b.Fields {
+				merged := append(tableList(a.Fields[name]), tableList(plugins)...)
+				if len(merged) == 0 {
+					return fmt.Errorf("unsupported config format: %s", name)
+				}
+				a.Fields[name] = merged
+			}
+		default:
+			a, aok := existing.(*ast.Table)
+			b, bok := value.(*ast.Table)
+			if !aok || !bok {
+				dst.Fields[key] = value
+				continue
+			}
+			mergeTables(a, b)
+		}
+	}
+	return nil
+}
+
+// mergeTables overrides the options of the destination table with the ones
+// of the source, sub-tables are merged recursively.
+func mergeTables(dst, src *ast.Table) {
+	for key, value := range src.Fields {
+		a, aok := dst.Fields[key].(*ast.Table)
+		b, bok := value.(*ast.Table)
+		if aok && bok {
+			mergeTables(a, b)
+			continue
+		}
+		dst.Fields[key] = value
+	}
+}
+
+// applyOverlays patches the plugins of the document with the overlays given
+// as '[[overlays.<category>.<plugin>]]' tables. Each overlay identifies the
+// plugin by its 'id' or 'alias' setting.
+func applyOverlays(doc *ast.Table, overlays interface{}) error {
+	categories, ok := overlays.(*ast.Table)
+	if !ok {
+		return errors.New("invalid configuration, overlays must be defined as [[overlays.<category>.<plugin>]]")
+	}
+	for category, value := range categories.Fields {
+		if !slices.Contains(pluginCategories, category) {
+			return fmt.Errorf("invalid overlay category %q", category)
+		}
+		plugins, ok := value.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("invalid configuration, error parsing overlays.%s as table", category)
+		}
+		for name, list := range plugins.Fields {
+			overlays := tableList(list)
+			if len(overlays) == 0 {
+				return fmt.Errorf("invalid configuration, error parsing overlays.%s.%s as table", category, name)
+			}
+			for _, overlay := range overlays {
+				if err := applyOverlay(doc, category, name, overlay); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func applyOverlay(doc *ast.Table, category, name string, overlay *ast.Table) error {
+	plugin := category + "." + name
+
+	id, err := stringField(overlay, "id")
+	if err != nil {
+		return fmt.Errorf("overlay for %s in line %d: %w", plugin, overlay.Line, err)
+	}
+	alias, err := stringField(overlay, "alias")
+	if err != nil {
+		return fmt.Errorf("overlay for %s in line %d: %w", plugin, overlay.Line, err)
+	}
+	if (id == "") == (alias == "") {
+		return fmt.Errorf("overlay for %s in line %d: exactly one of 'id' or 'alias' must be set", plugin, overlay.Line)
+	}
+
+	var candidates []*ast.Table
+	if tbl, ok := doc.Fields[category].(*ast.Table); ok {
+		candidates = tableList(tbl.Fields[name])
+	}
+	var targets []*ast.Table
+	for _, candidate := range candidates {
+		matches, err := overlayMatches(plugin, candidate, id, alias)
+		if err != nil {
+			return err
+		}
+		if matches {
+			targets = append(targets, candidate)
+		}
+	}
+
+	selector := fmt.Sprintf("alias %q", alias)
+	if id != "" {
+		selector = fmt.Sprintf("id %q", id)
+	}
+	switch len(targets) {
+	case 0:
+		return fmt.Errorf("overlay for %s in line %d: no plugin with %s found", plugin, overlay.Line, selector)
+	case 1:
+	default:
+		return fmt.Errorf("overlay for %s in line %d: more than one plugin with %s found", plugin, overlay.Line, selector)
+	}
+
+	patch := &ast.Table{Fields: make(map[string]interface{}, len(overlay.Fields))}
+	for key, value := range overlay.Fields {
+		// Secret-stores are identified by their 'id' option instead of the
+		// generated one, so the setting is kept
+		if key == "alias" || key == "id" && category != "secretstores" {
+			continue
+		}
+		patch.Fields[key] = value
+	}
+	mergeTables(targets[0], patch)
+
+	return nil
+}
+
+// overlayMatches returns true if the plugin table is identified by the given
+// id or alias. Secret-stores are identified by their 'id' setting, all other
+// plugins by their generated ID.
+func overlayMatches(plugin string, tbl *ast.Table, id, alias string) (bool, error) {
+	if alias != "" {
+		a, err := stringField(tbl, "alias")
+		return a == alias, err
+	}
+	if strings.HasPrefix(plugin, "secretstores.") {
+		storeID, err := stringField(tbl, "id")
+		return storeID == id, err
+	}
+
+	// Plugins in the legacy 'plugins' table are inputs
+	if name, found := strings.CutPrefix(plugin, "plugins."); found {
+		plugin = "inputs." + name
+	}
+	generated, err := generatePluginID(plugin, tbl)
+	return generated == id, err
+}
+
+// pluginTables returns the tables of all plugins of the document
+func pluginTables(doc *ast.Table) [][]*ast.Table {
+	var tables [][]*ast.Table
+	for _, category := range pluginCategories {
+		tbl, ok := doc.Fields[category].(*ast.Table)
+		if !ok {
+			continue
+		}
+		for _, plugins := range tbl.Fields {
+			tables = append(tables, tableList(plugins))
+		}
+	}
+	return tables
+}
+
+// tableList returns the given table or table-array as list
+func tableList(value interface{}) []*ast.Table {
+	switch v := value.(type) {
+	case *ast.Table:
+		return []*ast.Table{v}
+	case []*ast.Table:
+		return v
+	}
+	return nil
+}
+
+// stringField returns the value of the string option or an empty string if
+// the option does not exist.
+func stringField(tbl *ast.Table, key string) (string, error) {
+	kv, ok := tbl.Fields[key].(*ast.KeyValue)
+	if !ok {
+		return "", nil
+	}
+	s, ok := kv.Value.(*ast.String)
+	if !ok {
+		return "", fmt.Errorf("invalid value for %q, expected a string", key)
+	}
+	return s.Value, nil
+}
+
+// stringsField returns the values of an option given as string or list of
+// strings.
+func stringsField(tbl *ast.Table, key string) ([]string, error) {
+	kv, ok := tbl.Fields[key].(*ast.KeyValue)
+	if !ok {
+		if _, found := tbl.Fields[key]; found {
+			return nil, fmt.Errorf("invalid value for %q, expected a string or a list of strings", key)
+		}
+		return nil, nil
+	}
+	switch v := kv.Value.(type) {
+	case *ast.String:
+		return []string{v.Value}, nil
+	case *ast.Array:
+		values := make([]string, 0, len(v.Value))
+		for _, e := range v.Value {
+			s, ok := e.(*ast.String)
+			if !ok {
+				return nil, fmt.Errorf("invalid value for %q, expected a string or a list of strings", key)
+			}
+			values = append(values, s.Value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("invalid value for %q, expected a string or a list of strings", key)
+}
+
+// MergedConfig returns the configuration of the given files in the given
+// format with all included files merged and the overlays applied. Files are
+// merged in the given order. Environment variables and secret references are
+// kept as they are, comments are lost.
+func MergedConfig(format string, files ...string) ([]byte, error) {
+	parse := func(data []byte) (*ast.Table, error) {
+		data = trimBOM(data)
+		data, err := removeComments(data)
+		if err != nil {
+			return nil, err
+		}
+		tbl, err := toml.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		// Expand environment variables in includes to locate the files
+		if kv, ok := tbl.Fields["include"].(*ast.KeyValue); ok {
+			expandIncludes(kv.Value)
+		}
+		return tbl, nil
+	}
+
+	loader := newDocumentLoader(parse, 0)
+	merged := &ast.Table{Fields: make(map[string]interface{})}
+	for _, fn := range files {
+		data, _, err := LoadConfigFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file %s failed: %w", fn, err)
+		}
+		doc, err := loader.load(data, fn)
+		if err != nil {
+			return nil, fmt.Errorf("loading config file %s failed: %w", fn, err)
+		}
+		if err := mergeDocuments(merged, doc); err != nil {
+			return nil, fmt.Errorf("merging config file %s failed: %w", fn, err)
+		}
+	}
+
+	// Tables are written in the order of their lines, so offset the lines of
+	// each file to keep plugins of included files in front
+	for _, tables := range pluginTables(merged) {
+		for _, t := range tables {
+			t.Line += loader.origins[t].file * mergedLinesPerFile
+		}
+	}
+
+	root, err := tomlTableToNode(merged)
+	if err != nil {
+		return nil, err
+	}
+	return encodeNode(root, format)
+}
+
+func expandIncludes(value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		v.Value = os.ExpandEnv(v.Value)
+	case *ast.Array:
+		for _, e := range v.Value {
+			expandIncludes(e)
+		}
+	}
+}
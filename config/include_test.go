@@ -0,0 +1,181 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+func TestIncludeOverlays(t *testing.T) {
+	base := filepath.Join("testdata", "include", "base.toml")
+	vessel := filepath.Join("testdata", "include", "vessel.toml")
+	exec := filepath.Join("testdata", "include", "extra", "exec.toml")
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll(vessel))
+
+	// Settings of the including file take precedence
+	require.Equal(t, config.Duration(5*time.Second), c.Agent.Interval)
+	require.Equal(t, config.Duration(10*time.Second), c.Agent.FlushInterval)
+	require.Equal(t, "vessel", c.Tags["site"])
+	require.Equal(t, "north", c.Tags["region"])
+
+	// Plugins keep the file they are defined in as source
+	servers := make(map[string][]string, len(c.Inputs))
+	for _, input := range c.Inputs {
+		plugin, ok := input.Input.(*MockupInputPlugin)
+		require.True(t, ok)
+		switch input.Config.Name {
+		case "memcached":
+			require.Equal(t, base, input.Config.Source)
+			servers[input.Config.Alias] = plugin.Servers
+		case "exec":
+			require.Equal(t, exec, input.Config.Source)
+			require.Equal(t, "/usr/bin/vessel-status", plugin.Command)
+		default:
+			require.Failf(t, "unexpected input", "input %q", input.Config.Name)
+		}
+	}
+	require.Len(t, c.Inputs, 3)
+	require.Equal(t, map[string][]string{
+		"cache": {"vessel:11211"},
+		"":      {"localhost:11212"},
+	}, servers)
+
+	// Processors of included files run first
+	require.Len(t, c.Processors, 2)
+	require.Equal(t, base, c.Processors[0].Config.Source)
+	require.Equal(t, vessel, c.Processors[1].Config.Source)
+
+	// Overlays merge sub-tables
+	require.Len(t, c.Outputs, 1)
+	output, ok := c.Outputs[0].Output.(*MockupOutputPlugin)
+	require.True(t, ok)
+	require.Equal(t, "http://central:8080", output.URL)
+	require.Equal(t, map[string]string{"Authorization": "Token base", "X-Site": "vessel"}, output.Headers)
+}
+
+func TestIncludeOverlayByID(t *testing.T) {
+	base, err := filepath.Abs(filepath.Join("testdata", "include", "base.toml"))
+	require.NoError(t, err)
+
+	// Determine the ID of the unnamed plugin
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll(base))
+	var id string
+	for _, input := range c.Inputs {
+		if input.Config.Alias == "" {
+			id = input.ID()
+		}
+	}
+	require.NotEmpty(t, id)
+
+	cfg := fmt.Sprintf(`
+include = %q
+
+[[overlays.inputs.memcached]]
+  id = %q
+  servers = ["vessel:11212"]
+`, base, id)
+	fn := filepath.Join(t.TempDir(), "overlay.toml")
+	require.NoError(t, os.WriteFile(fn, []byte(cfg), 0600))
+
+	c = config.NewConfig()
+	require.NoError(t, c.LoadAll(fn))
+	require.Len(t, c.Inputs, 2)
+	for _, input := range c.Inputs {
+		plugin, ok := input.Input.(*MockupInputPlugin)
+		require.True(t, ok)
+		if input.Config.Alias == "" {
+			require.Equal(t, []string{"vessel:11212"}, plugin.Servers)
+		} else {
+			require.Equal(t, []string{"localhost:11211"}, plugin.Servers)
+		}
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{
+			name:     "cycle",
+			file:     "cycle_a.toml",
+			expected: "include cycle detected",
+		},
+		{
+			name:     "unknown overlay target",
+			file:     "overlay_unknown.toml",
+			expected: `overlay for inputs.memcached in line 3: no plugin with alias "unknown" found`,
+		},
+		{
+			name:     "ambiguous overlay target",
+			file:     "overlay_ambiguous.toml",
+			expected: `overlay for inputs.memcached in line 6: more than one plugin with alias "cache" found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewConfig()
+			err := c.LoadAll(filepath.Join("testdata", "include", tt.file))
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestMergedConfig(t *testing.T) {
+	t.Setenv("VESSEL_TOKEN", "secret")
+
+	cfg := `
+include = "base.toml"
+
+[[overlays.outputs.http]]
+  alias = "central"
+  [overlays.outputs.http.headers]
+    Authorization = "Token ${VESSEL_TOKEN}"
+`
+	dir := t.TempDir()
+	base, err := os.ReadFile(filepath.Join("testdata", "include", "base.toml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.toml"), base, 0600))
+	fn := filepath.Join(dir, "vessel.toml")
+	require.NoError(t, os.WriteFile(fn, []byte(cfg), 0600))
+
+	expected := `[agent]
+  interval = "10s"
+  flush_interval = "10s"
+
+[global_tags]
+  site = "base"
+  region = "north"
+
+[[inputs.memcached]]
+  alias = "cache"
+  servers = ["localhost:11211"]
+
+[[inputs.memcached]]
+  servers = ["localhost:11212"]
+
+[[processors.processor]]
+  option = "base"
+
+[[outputs.http]]
+  alias = "central"
+  url = "http://central:8080"
+  [outputs.http.headers]
+    Authorization = "Token ${VESSEL_TOKEN}"
+    X-Site = "base"
+`
+	actual, err := config.MergedConfig(config.FormatTOML, fn)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(actual))
+}
@@ -0,0 +1,508 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/models"
+)
+
+// renamingPlugins are the plugins able to change the name of the metrics
+// passing them or to create metrics with arbitrary names.
+var renamingPlugins = map[string]bool{
+	"processors.clone":     true,
+	"processors.converter": true,
+	"processors.execd":     true,
+	"processors.override":  true,
+	"processors.parser":    true,
+	"processors.regex":     true,
+	"processors.rename":    true,
+	"processors.split":     true,
+	"processors.starlark":  true,
+	"processors.strings":   true,
+	"aggregators.starlark": true,
+}
+
+// nameOptions are the plugin options commonly used to set the name of the
+// metrics produced by an input.
+var nameOptions = []string{"name", "measurement", "measurement_name", "metric_name"}
+
+// LintIssue is a setting of the assembled configuration which is valid on
+// its own but most likely a mistake, reported by Lint.
+type LintIssue struct {
+	Source  string
+	Line    int
+	Plugin  string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	switch {
+	case i.Source != "" && i.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", i.Source, i.Line, i.Plugin, i.Message)
+	case i.Source != "":
+		return fmt.Sprintf("%s: %s: %s", i.Source, i.Plugin, i.Message)
+	}
+	return i.Plugin + ": " + i.Message
+}
+
+// Lint analyses the pipelines of the loaded configuration and reports
+//   - filters that can never match,
+//   - processors, aggregators and outputs whose 'namepass' or 'namedrop'
+//     setting matches none of the metrics reaching the plugin,
+//   - outputs starved by aggregators with 'drop_original' and
+//   - aliases used by more than one plugin of the same category.
+//
+// The names of the metrics are estimated from the plugin names and the
+// naming settings. Plugins able to produce metrics with arbitrary names are
+// assumed to match everything, so the analysis errs on the side of missing
+// an issue. Lint must be called after loading all configuration files.
+func (c *Config) Lint() []LintIssue {
+	l := &linter{
+		config:   c,
+		leaving:  make(map[string][]string),
+		visiting: make(map[string]bool),
+	}
+
+	plugins := make(map[string][]lintPlugin, 4)
+	for _, input := range c.Inputs {
+		plugins["inputs"] = append(plugins["inputs"], lintPlugin{
+			logName: input.LogName(),
+			alias:   input.Config.Alias,
+			source:  input.Config.Source,
+			line:    input.Config.Line,
+			filter:  &input.Config.Filter,
+		})
+	}
+	for _, processor := range c.Processors {
+		plugins["processors"] = append(plugins["processors"], lintPlugin{
+			logName: processor.LogName(),
+			alias:   processor.Config.Alias,
+			source:  processor.Config.Source,
+			line:    processor.Config.Line,
+			filter:  &processor.Config.Filter,
+		})
+	}
+	for _, aggregator := range c.Aggregators {
+		plugins["aggregators"] = append(plugins["aggregators"], lintPlugin{
+			logName: aggregator.LogName(),
+			alias:   aggregator.Config.Alias,
+			source:  aggregator.Config.Source,
+			line:    aggregator.Config.Line,
+			filter:  &aggregator.Config.Filter,
+		})
+	}
+	for _, output := range c.Outputs {
+		plugins["outputs"] = append(plugins["outputs"], lintPlugin{
+			logName: output.LogName(),
+			alias:   output.Config.Alias,
+			source:  output.Config.Source,
+			line:    output.Config.Line,
+			filter:  &output.Config.Filter,
+		})
+	}
+
+	for _, category := range []string{"inputs", "processors", "aggregators", "outputs"} {
+		l.checkFilters(plugins[category])
+		l.checkAliases(plugins[category])
+	}
+
+	l.checkPipeline("")
+	for _, p := range c.Pipelines {
+		l.checkPipeline(p.Name)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Source != l.issues[j].Source {
+			return l.issues[i].Source < l.issues[j].Source
+		}
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues
+}
+
+type lintPlugin struct {
+	logName string
+	alias   string
+	source  string
+	line    int
+	filter  *models.Filter
+}
+
+type linter struct {
+	config *Config
+	issues []LintIssue
+
+	// leaving are the estimated metric names leaving each pipeline where the
+	// default pipeline has an empty name
+	leaving  map[string][]string
+	visiting map[string]bool
+}
+
+func (l *linter) report(logName, source string, line int, format string, args ...interface{}) {
+	// Line numbers of converted YAML or JSON files do not match the file
+	if FormatFromPath(source) != FormatTOML {
+		line = 0
+	}
+	l.issues = append(l.issues, LintIssue{
+		Source:  source,
+		Line:    line,
+		Plugin:  logName,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) checkFilters(plugins []lintPlugin) {
+	for _, p := range plugins {
+		for _, conflict := range p.filter.Conflicts() {
+			l.report(p.logName, p.source, p.line, "filter can never match: %s", conflict)
+		}
+	}
+}
+
+func (l *linter) checkAliases(plugins []lintPlugin) {
+	// Plugins are not loaded in the order of definition, so report the later
+	// definitions as duplicates
+	plugins = slices.Clone(plugins)
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if plugins[i].source != plugins[j].source {
+			return plugins[i].source < plugins[j].source
+		}
+		return plugins[i].line < plugins[j].line
+	})
+
+	seen := make(map[string]lintPlugin, len(plugins))
+	for _, p := range plugins {
+		if p.alias == "" {
+			continue
+		}
+		first, found := seen[p.alias]
+		if !found {
+			seen[p.alias] = p
+			continue
+		}
+		location := first.source
+		if first.line > 0 && FormatFromPath(first.source) == FormatTOML {
+			location = fmt.Sprintf("%s:%d", first.source, first.line)
+		}
+		l.report(p.logName, p.source, p.line, "alias %q is already used by %s (%s)", p.alias, first.logName, location)
+	}
+}
+
+// checkPipeline checks the processors, aggregators and outputs of the
+// pipeline with the given name against the estimated names of the metrics
+// reaching them and returns the names of the metrics leaving the pipeline.
+func (l *linter) checkPipeline(name string) []string {
+	if names, found := l.leaving[name]; found {
+		return names
+	}
+	if l.visiting[name] {
+		return []string{"*"}
+	}
+	l.visiting[name] = true
+	defer delete(l.visiting, name)
+
+	// Collect the metrics entering the pipeline from the inputs and the
+	// upstream pipelines
+	var names []string
+	for _, input := range l.config.Inputs {
+		if l.feeds(input, name) {
+			names = append(names, inputNames(input)...)
+		}
+	}
+	for _, p := range l.config.Pipelines {
+		if slices.Contains(p.Fanout, name) {
+			names = append(names, l.checkPipeline(p.Name)...)
+		}
+	}
+	names = compactNames(names)
+
+	// Without any metric entering the pipeline there is nothing to analyse
+	if len(names) == 0 {
+		l.leaving[name] = names
+		return names
+	}
+
+	var processors []*models.RunningProcessor
+	for _, processor := range l.config.Processors {
+		if processor.Config.Pipeline == name {
+			processors = append(processors, processor)
+		}
+	}
+	for i, processor := range processors {
+		cfg := processor.Config
+		if !selectsAnyName(&cfg.Filter, names) {
+			msg := fmt.Sprintf("%s matches none of the metrics reaching the processor, expected %s",
+				describeNameFilter(&cfg.Filter), describeNames(names))
+			idx := slices.IndexFunc(processors[i+1:], func(p *models.RunningProcessor) bool {
+				return renamingPlugins["processors."+p.Config.Name]
+			})
+			if idx >= 0 {
+				next := processors[i+1+idx]
+				msg += fmt.Sprintf("; it runs before %s (order %d) which may rename metrics, check the 'order' settings",
+					next.LogName(), next.Config.Order)
+			}
+			l.report(processor.LogName(), cfg.Source, cfg.Line, "%s", msg)
+		}
+		if renamingPlugins["processors."+cfg.Name] {
+			names = append(names, "*")
+		}
+	}
+
+	// All aggregators see the metrics leaving the processors and the metric
+	// is dropped if any aggregator with 'drop_original' selected it.
+	remaining := names
+	var produced []string
+	var dropping []*models.RunningAggregator
+	for _, aggregator := range l.config.Aggregators {
+		cfg := aggregator.Config
+		if cfg.Pipeline != name {
+			continue
+		}
+		if !selectsAnyName(&cfg.Filter, names) {
+			l.report(aggregator.LogName(), cfg.Source, cfg.Line, "%s matches none of the metrics reaching the aggregator, expected %s",
+				describeNameFilter(&cfg.Filter), describeNames(names))
+			continue
+		}
+		for _, n := range names {
+			if !selectsName(&cfg.Filter, n) {
+				continue
+			}
+			if renamingPlugins["aggregators."+cfg.Name] {
+				produced = append(produced, "*")
+				continue
+			}
+			if cfg.NameOverride != "" {
+				n = cfg.NameOverride
+			}
+			produced = append(produced, cfg.MeasurementPrefix+n+cfg.MeasurementSuffix)
+		}
+		if cfg.DropOriginal {
+			remaining = slices.DeleteFunc(slices.Clone(remaining), func(n string) bool {
+				return selectsAllOfName(&cfg.Filter, n)
+			})
+			dropping = append(dropping, aggregator)
+		}
+	}
+	skip := l.config.Agent.SkipProcessorsAfterAggregators != nil && *l.config.Agent.SkipProcessorsAfterAggregators
+	if len(produced) > 0 && !skip {
+		renaming := slices.ContainsFunc(l.config.AggProcessors, func(p *models.RunningProcessor) bool {
+			return p.Config.Pipeline == name && renamingPlugins["processors."+p.Config.Name]
+		})
+		if renaming {
+			produced = append(produced, "*")
+		}
+	}
+	leaving := compactNames(append(slices.Clone(remaining), produced...))
+
+	for _, output := range l.config.Outputs {
+		cfg := output.Config
+		if cfg.Pipeline != name || selectsAnyName(&cfg.Filter, leaving) {
+			continue
+		}
+
+		// Check if the output would receive metrics without the aggregators
+		// dropping the original metrics
+		var starving []string
+		for _, aggregator := range dropping {
+			starves := slices.ContainsFunc(names, func(n string) bool {
+				return selectsName(&cfg.Filter, n) && selectsAllOfName(&aggregator.Config.Filter, n)
+			})
+			if starves {
+				starving = append(starving, aggregator.LogName())
+			}
+		}
+		if len(starving) > 0 {
+			l.report(output.LogName(), cfg.Source, cfg.Line,
+				"all metrics selected by %s are dropped by %s with 'drop_original' before reaching the output",
+				describeNameFilter(&cfg.Filter), strings.Join(starving, ", "))
+			continue
+		}
+		l.report(output.LogName(), cfg.Source, cfg.Line, "%s matches none of the metrics reaching the output, expected %s",
+			describeNameFilter(&cfg.Filter), describeNames(leaving))
+	}
+
+	l.leaving[name] = leaving
+	return leaving
+}
+
+// feeds returns true if the input feeds the pipeline with the given name.
+func (l *linter) feeds(input *models.RunningInput, name string) bool {
+	if name != "" {
+		return l.config.Pipeline(name).SelectsInput(input)
+	}
+	return !slices.ContainsFunc(l.config.Pipelines, func(p *Pipeline) bool {
+		return p.SelectsInput(input)
+	})
+}
+
+// inputNames returns the glob patterns of the estimated metric names
+// produced by the input. Inputs are assumed to produce metrics starting with
+// the first part of the plugin name unless the plugin can name its metrics
+// freely.
+func inputNames(input *models.RunningInput) []string {
+	cfg := input.Config
+
+	var names []string
+	switch {
+	case cfg.NameOverride != "":
+		names = []string{cfg.NameOverride}
+	case len(cfg.Filter.NamePass) > 0 && !slices.ContainsFunc(cfg.Filter.NamePass, isGlob):
+		names = slices.Clone(cfg.Filter.NamePass)
+	case namesMetricsFreely(input.Input):
+		names = []string{"*"}
+	default:
+		stem, _, _ := strings.Cut(cfg.Name, "_")
+		names = []string{stem + "*"}
+	}
+
+	for i, n := range names {
+		names[i] = cfg.MeasurementPrefix + n + cfg.MeasurementSuffix
+	}
+	return names
+}
+
+// namesMetricsFreely returns true if the input can produce metrics with
+// arbitrary names, e.g. when using a parser or when providing a naming option.
+func namesMetricsFreely(input telegraf.Input) bool {
+	switch input.(type) {
+	case telegraf.ServiceInput, telegraf.ParserPlugin, telegraf.ParserFuncPlugin:
+		return true
+	}
+	return hasNameOption(reflect.TypeOf(input), 0)
+}
+
+func hasNameOption(t reflect.Type, depth int) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || depth > 3 {
+		return false
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		if slices.Contains(nameOptions, strings.ToLower(key)) || hasNameOption(field.Type, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsAnyName returns true if the name settings of the filter select at
+// least one of the given name patterns.
+func selectsAnyName(f *models.Filter, names []string) bool {
+	if len(f.NamePass) == 0 && len(f.NameDrop) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(names, func(n string) bool { return selectsName(f, n) })
+}
+
+// selectsName returns true if the name settings of the filter select some of
+// the names matching the given pattern.
+func selectsName(f *models.Filter, name string) bool {
+	if len(f.NamePass) > 0 && !slices.ContainsFunc(f.NamePass, func(p string) bool { return globsIntersect(p, name) }) {
+		return false
+	}
+	return !globsCover(f.NameDrop, name, f.NameDropSeparators)
+}
+
+// selectsAllOfName returns true if the filter selects all metrics with names
+// matching the given pattern.
+func selectsAllOfName(f *models.Filter, name string) bool {
+	if len(f.TagPassFilters) > 0 || len(f.TagDropFilters) > 0 || f.MetricPass != "" {
+		return false
+	}
+	if len(f.NamePass) > 0 && !globsCover(f.NamePass, name, f.NamePassSeparators) {
+		return false
+	}
+	return !slices.ContainsFunc(f.NameDrop, func(p string) bool { return globsIntersect(p, name) })
+}
+
+// globsCover returns true if the given name pattern is matched by the globs.
+// Patterns with wildcards are only covered by an identical glob or the
+// match-all glob.
+func globsCover(globs []string, name, separators string) bool {
+	if len(globs) == 0 {
+		return false
+	}
+	if isGlob(name) {
+		return slices.Contains(globs, name) || slices.Contains(globs, "*")
+	}
+	f, err := filter.Compile(globs, []rune(separators)...)
+	return err == nil && f.Match(name)
+}
+
+// globsIntersect returns true if there is a name matched by both glob
+// patterns. Only the '*' and '?' wildcards are analysed, patterns using other
+// glob syntax are assumed to intersect with everything.
+func globsIntersect(a, b string) bool {
+	if strings.ContainsAny(a, "[]{}\\") || strings.ContainsAny(b, "[]{}\\") {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+
+	memo := make(map[[2]int]bool)
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		key := [2]int{i, j}
+		if result, found := memo[key]; found {
+			return result
+		}
+
+		var result bool
+		switch {
+		case i == len(ra) && j == len(rb):
+			result = true
+		case i < len(ra) && ra[i] == '*':
+			result = match(i+1, j) || (j < len(rb) && match(i, j+1))
+		case j < len(rb) && rb[j] == '*':
+			result = match(i, j+1) || (i < len(ra) && match(i+1, j))
+		case i == len(ra) || j == len(rb):
+			result = false
+		default:
+			result = (ra[i] == rb[j] || ra[i] == '?' || rb[j] == '?') && match(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return match(0, 0)
+}
+
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[]{}\\")
+}
+
+func compactNames(names []string) []string {
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func describeNameFilter(f *models.Filter) string {
+	if len(f.NamePass) > 0 {
+		return fmt.Sprintf("'namepass' %q", f.NamePass)
+	}
+	return fmt.Sprintf("'namedrop' %q", f.NameDrop)
+}
+
+func describeNames(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, n := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", n))
+	}
+	return "metric names like " + strings.Join(quoted, ", ")
+}
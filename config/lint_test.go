@@ -0,0 +1,294 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestLint(t *testing.T) {
+	inputs.Add("cpu", func() telegraf.Input { return &MockupLintInput{} })
+	inputs.Add("mem", func() telegraf.Input { return &MockupLintInput{} })
+	inputs.Add("snmp", func() telegraf.Input { return &MockupLintInputNamed{} })
+	processors.Add("rename", func() telegraf.Processor { return &MockupProcessorPlugin{} })
+	aggregators.Add("stats", func() telegraf.Aggregator { return &MockupLintAggregator{} })
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "no issues",
+			data: `
+[[inputs.cpu]]
+  alias = "cpu"
+
+[[inputs.mem]]
+  alias = "mem"
+  name_prefix = "host_"
+
+[[processors.processor]]
+  namepass = ["host_mem"]
+
+[[aggregators.stats]]
+  namepass = ["cpu"]
+  drop_original = true
+  name_suffix = "_stats"
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["cpu_stats", "host_*"]
+
+[[outputs.http]]
+  url = "http://localhost:8081"
+  namedrop = ["cpu*"]
+`,
+		},
+		{
+			name: "duplicate aliases",
+			data: `
+[[inputs.cpu]]
+  alias = "system"
+
+[[inputs.mem]]
+  alias = "system"
+
+[[outputs.http]]
+  alias = "system"
+  url = "http://localhost:8080"
+`,
+			expected: []string{
+				`5: inputs.mem::system: alias "system" is already used by inputs.cpu::system (<file>:2)`,
+			},
+		},
+		{
+			name: "filter never matches",
+			data: `
+[[inputs.cpu]]
+  namepass = ["cpu"]
+  namedrop = ["c*"]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  fieldinclude = ["usage_idle"]
+  fieldexclude = ["usage_*"]
+`,
+			expected: []string{
+				`2: inputs.cpu: filter can never match: all names in 'namepass' are dropped by 'namedrop'`,
+				`6: outputs.http: filter can never match: all fields in 'fieldinclude' are removed by 'fieldexclude' leaving no fields`,
+			},
+		},
+		{
+			name: "output namepass",
+			data: `
+[[inputs.cpu]]
+
+[[inputs.mem]]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["disk*"]
+
+[[outputs.http]]
+  url = "http://localhost:8081"
+  namepass = ["memory", "cpu_usage"]
+`,
+			expected: []string{
+				`6: outputs.http: 'namepass' ["disk*"] matches none of the metrics reaching the output, ` +
+					`expected metric names like "cpu*", "mem*"`,
+			},
+		},
+		{
+			name: "freely named input",
+			data: `
+[[inputs.snmp]]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["interface"]
+`,
+		},
+		{
+			name: "processor order",
+			data: `
+[[inputs.cpu]]
+  namepass = ["cpu"]
+
+[[processors.processor]]
+  order = 1
+  namepass = ["cpu_total"]
+
+[[processors.rename]]
+  order = 2
+
+[[processors.processor]]
+  order = 3
+  namepass = ["cpu_total"]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["cpu_total"]
+`,
+			expected: []string{
+				`5: processors.processor: 'namepass' ["cpu_total"] matches none of the metrics reaching the processor, ` +
+					`expected metric names like "cpu"; it runs before processors.rename (order 2) which may rename metrics, ` +
+					`check the 'order' settings`,
+			},
+		},
+		{
+			name: "aggregator drop_original",
+			data: `
+[[inputs.cpu]]
+
+[[inputs.mem]]
+
+[[aggregators.stats]]
+  namepass = ["mem*"]
+  drop_original = true
+  name_suffix = "_stats"
+
+[[aggregators.stats]]
+  namepass = ["disk*"]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["mem"]
+
+[[outputs.http]]
+  url = "http://localhost:8081"
+  namepass = ["mem*", "cpu"]
+`,
+			expected: []string{
+				`11: aggregators.stats: 'namepass' ["disk*"] matches none of the metrics reaching the aggregator, ` +
+					`expected metric names like "cpu*", "mem*"`,
+				`14: outputs.http: all metrics selected by 'namepass' ["mem"] are dropped by aggregators.stats ` +
+					`with 'drop_original' before reaching the output`,
+			},
+		},
+		{
+			name: "pipelines",
+			data: `
+[[pipelines]]
+  name = "system"
+  inputs = ["cpu"]
+  fanout = ["archive"]
+
+[[pipelines]]
+  name = "archive"
+
+[[inputs.cpu]]
+  alias = "cpu"
+  name_override = "system_cpu"
+
+[[inputs.mem]]
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+  namepass = ["cpu*"]
+
+[[outputs.http]]
+  url = "http://localhost:8081"
+  pipeline = "archive"
+  namepass = ["mem*", "system_*"]
+
+[[outputs.http]]
+  url = "http://localhost:8082"
+  pipeline = "system"
+  namepass = ["cpu*"]
+`,
+			expected: []string{
+				`16: outputs.http: 'namepass' ["cpu*"] matches none of the metrics reaching the output, ` +
+					`expected metric names like "mem*"`,
+				`25: outputs.http: 'namepass' ["cpu*"] matches none of the metrics reaching the output, ` +
+					`expected metric names like "system_cpu"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "telegraf.conf")
+			require.NoError(t, os.WriteFile(fn, []byte(tt.data), 0600))
+
+			c := config.NewConfig()
+			require.NoError(t, c.LoadAll(fn))
+
+			actual := make([]string, 0, len(tt.expected))
+			for _, issue := range c.Lint() {
+				require.Equal(t, fn, issue.Source)
+				actual = append(actual, fmt.Sprintf("%d: %s: %s", issue.Line, issue.Plugin, issue.Message))
+			}
+			expected := make([]string, 0, len(tt.expected))
+			for _, e := range tt.expected {
+				expected = append(expected, strings.ReplaceAll(e, "<file>", fn))
+			}
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestLintIssueString(t *testing.T) {
+	issue := config.LintIssue{
+		Source:  "telegraf.conf",
+		Line:    12,
+		Plugin:  "outputs.http",
+		Message: "some issue",
+	}
+	require.Equal(t, "telegraf.conf:12: outputs.http: some issue", issue.String())
+
+	issue.Line = 0
+	require.Equal(t, "telegraf.conf: outputs.http: some issue", issue.String())
+}
+
+// Mockup INPUT plugin named after its metrics for testing the linter
+type MockupLintInput struct {
+	Servers []string `toml:"servers"`
+}
+
+func (*MockupLintInput) SampleConfig() string {
+	return "Mockup test input plugin"
+}
+
+func (*MockupLintInput) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+// Mockup INPUT plugin with an option to set the metric name
+type MockupLintInputNamed struct {
+	Fields []struct {
+		Name string `toml:"name"`
+		OID  string `toml:"oid"`
+	} `toml:"field"`
+}
+
+func (*MockupLintInputNamed) SampleConfig() string {
+	return "Mockup test input plugin"
+}
+
+func (*MockupLintInputNamed) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+// Mockup AGGREGATOR plugin for testing the linter
+type MockupLintAggregator struct{}
+
+func (*MockupLintAggregator) SampleConfig() string {
+	return "Mockup test aggregator plugin"
+}
+
+func (*MockupLintAggregator) Add(telegraf.Metric) {}
+
+func (*MockupLintAggregator) Push(telegraf.Accumulator) {}
+
+func (*MockupLintAggregator) Reset() {}
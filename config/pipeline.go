@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/influxdata/toml/ast"
+
+	"github.com/influxdata/telegraf/models"
+)
+
+// Pipeline is a named chain of processors, aggregators and outputs. The
+// pipeline is fed by the selected inputs and by the pipelines fanning out to
+// it. Processors, aggregators and outputs are assigned to the pipeline using
+// their 'pipeline' setting, plugins without the setting belong to the default
+// pipeline receiving the metrics of all inputs not selected by any pipeline.
+type Pipeline struct {
+	Name string `toml:"name"`
+
+	// Inputs are the aliases or IDs of the inputs feeding the pipeline
+	Inputs []string `toml:"inputs"`
+
+	// Selectors select the inputs feeding the pipeline by their labels using
+	// the same syntax as the '--select' command-line flag. Inputs without
+	// labels are never selected.
+	Selectors []string `toml:"selectors"`
+
+	// Fanout are the names of the pipelines receiving a copy of the metrics
+	// leaving this pipeline
+	Fanout []string `toml:"fanout"`
+
+	selector labelSelector
+}
+
+// pipelineKeys are the settings of a pipeline table
+var pipelineKeys = []string{"fanout", "inputs", "name", "selectors"}
+
+// SelectsInput returns true if the given input feeds the pipeline.
+func (p *Pipeline) SelectsInput(input *models.RunningInput) bool {
+	if slices.Contains(p.Inputs, input.ID()) {
+		return true
+	}
+	if input.Config.Alias != "" && slices.Contains(p.Inputs, input.Config.Alias) {
+		return true
+	}
+	return p.selector.selects(input.Config.Labels)
+}
+
+// Pipeline returns the pipeline with the given name or nil if not found.
+func (c *Config) Pipeline(name string) *Pipeline {
+	for _, p := range c.Pipelines {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (c *Config) addPipelines(val interface{}) error {
+	tables, ok := val.([]*ast.Table)
+	if !ok {
+		return errors.New("invalid configuration, pipelines must be defined as [[pipelines]]")
+	}
+
+	for _, tbl := range tables {
+		p := &Pipeline{}
+		if err := c.toml.UnmarshalTable(tbl, p); err != nil {
+			return fmt.Errorf("error parsing pipeline: %w", err)
+		}
+
+		// Check the keys against the pipeline settings as the ignore list
+		// of the plugins would accept plugin settings here
+		var unknown []string
+		for key := range tbl.Fields {
+			if !slices.Contains(pipelineKeys, key) {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			slices.Sort(unknown)
+			return fmt.Errorf(
+				"pipeline %q: line %d: configuration specified the fields %q, but they were not used; "+
+					"this is either a typo or this config option does not exist in this version",
+				p.Name, tbl.Line, unknown)
+		}
+
+		if p.Name == "" {
+			return fmt.Errorf("pipeline in line %d: missing name", tbl.Line)
+		}
+		if c.Pipeline(p.Name) != nil {
+			return fmt.Errorf("pipeline %q defined more than once", p.Name)
+		}
+		if err := p.selector.setSelections(p.Selectors); err != nil {
+			return fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+		c.Pipelines = append(c.Pipelines, p)
+	}
+
+	return nil
+}
+
+// checkPipelines validates the pipeline definitions and the pipeline settings
+// of the plugins. This must be called after all plugins are loaded.
+func (c *Config) checkPipelines() error {
+	check := func(plugin, name string) error {
+		if name != "" && c.Pipeline(name) == nil {
+			return fmt.Errorf("pipeline %q of %s not found", name, plugin)
+		}
+		return nil
+	}
+	for _, processor := range c.Processors {
+		if err := check(processor.LogName(), processor.Config.Pipeline); err != nil {
+			return err
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		if err := check(aggregator.LogName(), aggregator.Config.Pipeline); err != nil {
+			return err
+		}
+	}
+	for _, output := range c.Outputs {
+		if err := check(output.LogName(), output.Config.Pipeline); err != nil {
+			return err
+		}
+	}
+
+	// Outputs can only be linked within the same pipeline and members of a
+	// failover group must shape the metrics the same way
+	groups := make(map[string]*models.OutputConfig)
+	for _, output := range c.Outputs {
+		if dl := output.DeadLetter(); dl != nil && dl.Config.Pipeline != output.Config.Pipeline {
+			return fmt.Errorf("dead-letter output of %s must be in the same pipeline", output.LogName())
+		}
+		name := output.Config.FailoverGroup
+		if name == "" {
+			continue
+		}
+		first, found := groups[name]
+		if !found {
+			groups[name] = output.Config
+			continue
+		}
+		if first.Pipeline != output.Config.Pipeline {
+			return fmt.Errorf("members of failover group %q must be in the same pipeline", name)
+		}
+		if err := models.CheckFailoverMember(first, output.Config); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range c.Pipelines {
+		for _, name := range p.Inputs {
+			found := slices.ContainsFunc(c.Inputs, func(input *models.RunningInput) bool {
+				return input.ID() == name || input.Config.Alias == name
+			})
+			if !found {
+				return fmt.Errorf("input %q of pipeline %q not found", name, p.Name)
+			}
+		}
+		for _, name := range p.Fanout {
+			if name == p.Name {
+				return fmt.Errorf("pipeline %q cannot fan out to itself", p.Name)
+			}
+			if c.Pipeline(name) == nil {
+				return fmt.Errorf("fan-out pipeline %q of pipeline %q not found", name, p.Name)
+			}
+		}
+	}
+
+	// Make sure the fan-out does not form a cycle
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(c.Pipelines))
+	var visit func(p *Pipeline) error
+	visit = func(p *Pipeline) error {
+		switch state[p.Name] {
+		case visiting:
+			return fmt.Errorf("fan-out of pipeline %q forms a cycle", p.Name)
+		case visited:
+			return nil
+		}
+		state[p.Name] = visiting
+		for _, name := range p.Fanout {
+			if err := visit(c.Pipeline(name)); err != nil {
+				return err
+			}
+		}
+		state[p.Name] = visited
+		return nil
+	}
+	for _, p := range c.Pipelines {
+		if err := visit(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -115,7 +115,12 @@ func (l *labelSelector) matches(labels map[string]string) bool {
 	if len(labels) == 0 || len(l.groups) == 0 {
 		return true
 	}
+	return l.selects(labels)
+}
 
+// selects returns true if the labels match any of the selector groups. In
+// contrast to 'matches' no plugin is selected without selectors or labels.
+func (l *labelSelector) selects(labels map[string]string) bool {
 	// Iterate over the filter groups and combine all filters within a group via
 	// logical AND and the different groups via logical OR.
 	return slices.ContainsFunc(l.groups, func(group map[string]filter.Filter) bool {
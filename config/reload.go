@@ -0,0 +1,29 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ReloadCompatible checks if the given configuration can be applied to a
+// running agent using the current configuration by only exchanging plugins.
+// This is the case if the two configurations solely differ in the plugin
+// sections. Any change to the agent settings, the global tags or the secret
+// stores requires a full restart of the agent and is reported as error.
+func (c *Config) ReloadCompatible(other *Config) error {
+	if !reflect.DeepEqual(c.Agent, other.Agent) {
+		return errors.New("agent settings changed")
+	}
+	if !maps.Equal(c.Tags, other.Tags) {
+		return errors.New("global tags changed")
+	}
+	if !maps.Equal(c.secretStoreHashes, other.secretStoreHashes) {
+		return errors.New("secret stores changed")
+	}
+	if !slices.Equal(c.InputFilters, other.InputFilters) || !slices.Equal(c.OutputFilters, other.OutputFilters) {
+		return errors.New("plugin filters changed")
+	}
+	return nil
+}
@@ -0,0 +1,934 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// schemaDialect is the JSON Schema version of the generated schema
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	tomlUnmarshalerType = reflect.TypeOf((*toml.Unmarshaler)(nil)).Elem()
+	tomlRecursiveType   = reflect.TypeOf((*toml.UnmarshalerRec)(nil)).Elem()
+)
+
+// jsonSchema is the subset of JSON Schema used to describe the configuration.
+type jsonSchema struct {
+	Schema                string                 `json:"$schema,omitempty"`
+	Ref                   string                 `json:"$ref,omitempty"`
+	Type                  interface{}            `json:"type,omitempty"`
+	Description           string                 `json:"description,omitempty"`
+	Default               interface{}            `json:"default,omitempty"`
+	Deprecated            bool                   `json:"deprecated,omitempty"`
+	Const                 interface{}            `json:"const,omitempty"`
+	Enum                  []string               `json:"enum,omitempty"`
+	Pattern               string                 `json:"pattern,omitempty"`
+	Properties            map[string]*jsonSchema `json:"properties,omitempty"`
+	Required              []string               `json:"required,omitempty"`
+	AdditionalProperties  *jsonSchema            `json:"additionalProperties,omitempty"`
+	UnevaluatedProperties *bool                  `json:"unevaluatedProperties,omitempty"`
+	Items                 *jsonSchema            `json:"items,omitempty"`
+	AllOf                 []*jsonSchema          `json:"allOf,omitempty"`
+	Not                   *jsonSchema            `json:"not,omitempty"`
+	If                    *jsonSchema            `json:"if,omitempty"`
+	Then                  *jsonSchema            `json:"then,omitempty"`
+	Else                  *jsonSchema            `json:"else,omitempty"`
+	Defs                  map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+// JSONSchema returns a JSON Schema describing the configuration including
+// all registered plugins, parsers and serializers.
+func JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(buildSchema(), "", "  ")
+}
+
+// buildSchema generates the schema of the configuration. Plugins, parsers
+// and serializers are described in the definitions named by category and
+// plugin name, e.g. 'inputs.cpu', and are referenced by the plugin tables.
+func buildSchema() *jsonSchema {
+	b := &schemaBuilder{visiting: make(map[reflect.Type]bool)}
+	root := &jsonSchema{
+		Schema:                schemaDialect,
+		Type:                  "object",
+		Description:           "Telegraf configuration",
+		Properties:            make(map[string]*jsonSchema),
+		UnevaluatedProperties: new(bool),
+		Defs:                  make(map[string]*jsonSchema),
+	}
+
+	// Parsers and serializers are referenced by the plugins depending on the
+	// 'data_format' setting so add them first.
+	for name, creator := range parsers.Parsers {
+		s := b.pluginSchema("parsers", name, creator(""), parsers.Deprecations[name])
+		s.UnevaluatedProperties = nil
+		root.Defs["parsers."+name] = s
+	}
+	root.Defs["parsers"] = dataFormatSchema("parsers", root.Defs)
+	for name, creator := range serializers.Serializers {
+		s := b.pluginSchema("serializers", name, creator(), serializers.Deprecations[name])
+		s.UnevaluatedProperties = nil
+		root.Defs["serializers."+name] = s
+	}
+	root.Defs["serializers"] = dataFormatSchema("serializers", root.Defs)
+
+	for name, creator := range inputs.Inputs {
+		plugin := creator()
+		s := b.pluginSchema("inputs", name, plugin, inputs.Deprecations[name])
+		addCommonOptions(s, inputOptions())
+		switch plugin.(type) {
+		case telegraf.ParserPlugin, telegraf.ParserFuncPlugin:
+			addDataFormat(s, "parsers", setDefaultParser("inputs", name), root.Defs)
+		}
+		root.Defs["inputs."+name] = s
+	}
+	for name, creator := range outputs.Outputs {
+		plugin := creator()
+		s := b.pluginSchema("outputs", name, plugin, outputs.Deprecations[name])
+		addCommonOptions(s, outputOptions())
+		switch plugin.(type) {
+		case telegraf.SerializerPlugin, telegraf.SerializerFuncPlugin:
+			addDataFormat(s, "serializers", "influx", root.Defs)
+		}
+		root.Defs["outputs."+name] = s
+	}
+	for name, creator := range processors.Processors {
+		var plugin interface{} = creator()
+		if p, ok := plugin.(processors.HasUnwrap); ok {
+			plugin = p.Unwrap()
+		}
+		s := b.pluginSchema("processors", name, plugin, processors.Deprecations[name])
+		addCommonOptions(s, processorOptions())
+		switch plugin.(type) {
+		case telegraf.ParserPlugin, telegraf.ParserFuncPlugin:
+			addDataFormat(s, "parsers", setDefaultParser("processors", name), root.Defs)
+		}
+		switch plugin.(type) {
+		case telegraf.SerializerPlugin, telegraf.SerializerFuncPlugin:
+			addDataFormat(s, "serializers", "influx", root.Defs)
+		}
+		root.Defs["processors."+name] = s
+	}
+	for name, creator := range aggregators.Aggregators {
+		s := b.pluginSchema("aggregators", name, creator(), aggregators.Deprecations[name])
+		addCommonOptions(s, aggregatorOptions())
+		root.Defs["aggregators."+name] = s
+	}
+	for name, creator := range secretstores.SecretStores {
+		s := b.pluginSchema("secretstores", name, creator(""), secretstores.Deprecations[name])
+		addCommonOptions(s, secretStoreOptions())
+		s.Required = append(s.Required, "id")
+		root.Defs["secretstores."+name] = s
+	}
+
+	// Describe the top-level tables
+	agent := b.typeSchema(reflect.TypeOf(AgentConfig{}))
+	agent.Description = "Agent settings"
+	root.Properties["agent"] = agent
+	root.Properties["global_tags"] = &jsonSchema{
+		Type:                 "object",
+		Description:          "Tags added to all metrics",
+		AdditionalProperties: &jsonSchema{Type: "string"},
+	}
+	root.Properties["tags"] = &jsonSchema{
+		Type:                 "object",
+		Description:          "Tags added to all metrics, use 'global_tags' instead",
+		Deprecated:           true,
+		AdditionalProperties: &jsonSchema{Type: "string"},
+	}
+	root.Properties["pipelines"] = &jsonSchema{
+		Type:        "array",
+		Description: "Pipelines of processors, aggregators and outputs",
+		Items:       b.typeSchema(reflect.TypeOf(Pipeline{})),
+	}
+	root.Properties["include"] = &jsonSchema{
+		Description: "Files or URLs to load before the content of this file",
+		If:          &jsonSchema{Type: "array"},
+		Then:        &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}},
+		Else:        &jsonSchema{Type: "string"},
+	}
+	// Overlays only contain the options to patch, so they are not checked
+	// against the plugin definitions
+	root.Properties["overlays"] = &jsonSchema{
+		Type:        "object",
+		Description: "Patches of plugins identified by their 'id' or 'alias'",
+		AdditionalProperties: &jsonSchema{
+			Type:                 "object",
+			AdditionalProperties: &jsonSchema{Type: "array", Items: &jsonSchema{Type: "object"}},
+		},
+	}
+	root.Properties["inputs"] = pluginTableSchema("inputs", root.Defs, true)
+	root.Properties["outputs"] = pluginTableSchema("outputs", root.Defs, true)
+	root.Properties["processors"] = pluginTableSchema("processors", root.Defs, false)
+	root.Properties["aggregators"] = pluginTableSchema("aggregators", root.Defs, false)
+	root.Properties["secretstores"] = pluginTableSchema("secretstores", root.Defs, false)
+
+	plugins := pluginTableSchema("inputs", root.Defs, true)
+	plugins.Description = "Input plugins, use 'inputs' instead"
+	plugins.Deprecated = true
+	root.Properties["plugins"] = plugins
+
+	return root
+}
+
+// pluginTableSchema describes the table of all plugins of the given category
+// with each plugin being a list of plugin instances. If single is set a
+// plugin might also be specified as a single table.
+func pluginTableSchema(category string, defs map[string]*jsonSchema, single bool) *jsonSchema {
+	s := &jsonSchema{
+		Type:                  "object",
+		Description:           strings.TrimSuffix(category, "s") + " plugins",
+		Properties:            make(map[string]*jsonSchema),
+		UnevaluatedProperties: new(bool),
+	}
+	if category == "secretstores" {
+		s.Description = "Secret-store plugins"
+	}
+	s.Description = strings.ToUpper(s.Description[:1]) + s.Description[1:]
+
+	prefix := category + "."
+	for key := range defs {
+		name, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+		ref := &jsonSchema{Ref: "#/$defs/" + key}
+		list := &jsonSchema{Type: "array", Items: ref}
+		if single {
+			s.Properties[name] = &jsonSchema{If: &jsonSchema{Type: "array"}, Then: list, Else: ref}
+		} else {
+			s.Properties[name] = list
+		}
+	}
+	return s
+}
+
+// dataFormatSchema selects the parser or serializer definition matching the
+// 'data_format' setting of a plugin.
+func dataFormatSchema(category string, defs map[string]*jsonSchema) *jsonSchema {
+	formats := make([]string, 0)
+	prefix := category + "."
+	for key := range defs {
+		if name, found := strings.CutPrefix(key, prefix); found {
+			formats = append(formats, name)
+		}
+	}
+	sort.Strings(formats)
+
+	s := &jsonSchema{
+		Properties: map[string]*jsonSchema{
+			"data_format": {Type: "string", Enum: formats},
+		},
+	}
+	for _, format := range formats {
+		// The upstream influx parser is selected via 'influx_parser_type'
+		if category == "parsers" && format == "influx_upstream" {
+			continue
+		}
+		s.AllOf = append(s.AllOf, &jsonSchema{
+			If: &jsonSchema{
+				Properties: map[string]*jsonSchema{"data_format": {Const: format}},
+				Required:   []string{"data_format"},
+			},
+			Then: dataFormatRef(category, format, defs),
+		})
+	}
+	return s
+}
+
+// dataFormatRef references the parser or serializer definition for the given
+// data format.
+func dataFormatRef(category, format string, defs map[string]*jsonSchema) *jsonSchema {
+	ref := &jsonSchema{Ref: "#/$defs/" + category + "." + format}
+	if category != "parsers" || format != "influx" {
+		return ref
+	}
+	if _, found := defs["parsers.influx_upstream"]; !found {
+		return ref
+	}
+	return &jsonSchema{
+		If: &jsonSchema{
+			Properties: map[string]*jsonSchema{"influx_parser_type": {Const: "upstream"}},
+			Required:   []string{"influx_parser_type"},
+		},
+		Then: &jsonSchema{Ref: "#/$defs/parsers.influx_upstream"},
+		Else: ref,
+	}
+}
+
+// addDataFormat adds the parser or serializer options to the plugin schema
+// using the given data format as default.
+func addDataFormat(s *jsonSchema, category, format string, defs map[string]*jsonSchema) {
+	options := map[string]*jsonSchema{
+		"data_format": {Type: "string", Description: "Data format of the metrics", Default: format},
+	}
+	if category == "parsers" {
+		options["data_type"] = &jsonSchema{Type: "string"}
+		options["influx_parser_type"] = &jsonSchema{Type: "string", Enum: []string{"internal", "upstream"}}
+	}
+	addCommonOptions(s, options)
+
+	s.AllOf = append(s.AllOf, &jsonSchema{Ref: "#/$defs/" + category})
+	if _, found := defs[category+"."+format]; found {
+		s.AllOf = append(s.AllOf, &jsonSchema{
+			If:   &jsonSchema{Not: &jsonSchema{Required: []string{"data_format"}}},
+			Then: dataFormatRef(category, format, defs),
+		})
+	}
+}
+
+// addCommonOptions adds the options handled by Telegraf instead of the plugin
+// to the plugin schema, keeping the plugin's definition of the option if any.
+func addCommonOptions(s *jsonSchema, options map[string]*jsonSchema) {
+	for key, option := range options {
+		if _, found := s.Properties[key]; !found {
+			s.Properties[key] = option
+		}
+	}
+}
+
+// filterOptions are the metric filtering options of inputs, outputs,
+// processors and aggregators (see buildFilter)
+func filterOptions() map[string]*jsonSchema {
+	stringList := func() *jsonSchema {
+		return &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}}
+	}
+	tagFilter := func() *jsonSchema {
+		return &jsonSchema{Type: "object", AdditionalProperties: stringList()}
+	}
+	deprecated := func(since, removal, replacement string) *jsonSchema {
+		s := stringList()
+		s.Deprecated = true
+		s.Description = deprecationDescription(telegraf.DeprecationInfo{
+			Since:     since,
+			RemovalIn: removal,
+			Notice:    fmt.Sprintf("use %q instead", replacement),
+		})
+		return s
+	}
+
+	return map[string]*jsonSchema{
+		"alias":              {Type: "string", Description: "Name of the plugin instance"},
+		"labels":             {Type: "object", AdditionalProperties: &jsonSchema{Type: "string"}},
+		"log_level":          {Type: "string", Enum: []string{"error", "warn", "info", "debug", "trace"}},
+		"namepass":           stringList(),
+		"namepass_separator": {Type: "string"},
+		"namedrop":           stringList(),
+		"namedrop_separator": {Type: "string"},
+		"fieldinclude":       stringList(),
+		"fieldexclude":       stringList(),
+		"fieldpass":          deprecated("1.29.0", "1.40.0", "fieldinclude"),
+		"fielddrop":          deprecated("1.29.0", "1.40.0", "fieldexclude"),
+		"pass":               deprecated("0.10.4", "1.35.0", "fieldinclude"),
+		"drop":               deprecated("0.10.4", "1.35.0", "fieldexclude"),
+		"tagpass":            tagFilter(),
+		"tagdrop":            tagFilter(),
+		"taginclude":         stringList(),
+		"tagexclude":         stringList(),
+		"metricpass":         {Type: "string"},
+	}
+}
+
+// inputOptions are the common options of input plugins (see buildInput)
+func inputOptions() map[string]*jsonSchema {
+	options := filterOptions()
+	for _, key := range []string{"interval", "precision", "collection_jitter", "collection_offset", "gather_timeout"} {
+		options[key] = &jsonSchema{Type: "string", Description: "Duration, e.g. '10s'"}
+	}
+	for _, key := range []string{"schedule", "schedule_timezone", "name_override", "name_prefix", "name_suffix"} {
+		options[key] = &jsonSchema{Type: "string"}
+	}
+	options["startup_error_behavior"] = &jsonSchema{Type: "string", Enum: []string{"error", "retry", "ignore", "probe"}}
+	options["time_source"] = &jsonSchema{Type: "string", Enum: []string{"metric", "collection_start", "collection_end"}}
+	options["tags"] = &jsonSchema{Type: "object", AdditionalProperties: &jsonSchema{Type: "string"}}
+	return options
+}
+
+// outputOptions are the common options of output plugins (see buildOutput)
+func outputOptions() map[string]*jsonSchema {
+	options := filterOptions()
+	for _, key := range []string{
+		"flush_interval", "flush_jitter", "metric_batch_target_latency",
+		"retry_initial_interval", "retry_max_interval", "circuit_breaker_timeout",
+	} {
+		options[key] = &jsonSchema{Type: "string", Description: "Duration, e.g. '10s'"}
+	}
+	for _, key := range []string{
+		"metric_buffer_limit", "metric_batch_size", "in_flight_batches",
+		"circuit_breaker_failures", "failover_max_failures",
+	} {
+		options[key] = &jsonSchema{Type: "integer"}
+	}
+	for _, key := range []string{"metric_batch_bytes", "metric_buffer_bytes"} {
+		options[key] = &jsonSchema{Type: []string{"integer", "string"}, Description: "Size, e.g. '10MiB'"}
+	}
+	for _, key := range []string{"metric_batch_auto_tune", "strict_series_ordering"} {
+		options[key] = &jsonSchema{Type: "boolean"}
+	}
+	for _, key := range []string{"name_override", "name_prefix", "name_suffix", "dead_letter", "failover_group", "pipeline"} {
+		options[key] = &jsonSchema{Type: "string"}
+	}
+	options["retry_multiplier"] = &jsonSchema{Type: "number"}
+	options["startup_error_behavior"] = &jsonSchema{Type: "string", Enum: []string{"error", "retry", "ignore", "probe"}}
+	return options
+}
+
+// processorOptions are the common options of processor plugins (see
+// buildProcessor)
+func processorOptions() map[string]*jsonSchema {
+	options := filterOptions()
+	options["order"] = &jsonSchema{Type: "integer"}
+	options["pipeline"] = &jsonSchema{Type: "string"}
+	return options
+}
+
+// aggregatorOptions are the common options of aggregator plugins (see
+// buildAggregator)
+func aggregatorOptions() map[string]*jsonSchema {
+	options := filterOptions()
+	for _, key := range []string{"period", "delay", "grace"} {
+		options[key] = &jsonSchema{Type: "string", Description: "Duration, e.g. '10s'"}
+	}
+	for _, key := range []string{"name_override", "name_prefix", "name_suffix", "pipeline"} {
+		options[key] = &jsonSchema{Type: "string"}
+	}
+	options["drop_original"] = &jsonSchema{Type: "boolean"}
+	options["tags"] = &jsonSchema{Type: "object", AdditionalProperties: &jsonSchema{Type: "string"}}
+	return options
+}
+
+// secretStoreOptions are the common options of secret-store plugins (see
+// addSecretStore)
+func secretStoreOptions() map[string]*jsonSchema {
+	return map[string]*jsonSchema{
+		"id":     {Type: "string", Description: "Identifier used to reference secrets", Pattern: secretStorePattern.String()},
+		"labels": {Type: "object", AdditionalProperties: &jsonSchema{Type: "string"}},
+	}
+}
+
+// schemaBuilder generates schemas from the structure of plugins.
+type schemaBuilder struct {
+	visiting map[reflect.Type]bool
+}
+
+// pluginSchema describes the options of the given plugin. The description
+// and defaults are taken from the plugin's sample configuration.
+func (b *schemaBuilder) pluginSchema(category, name string, plugin interface{}, deprecation telegraf.DeprecationInfo) *jsonSchema {
+	s := &jsonSchema{}
+	if t := reflect.TypeOf(plugin); t != nil {
+		s = b.typeSchema(t)
+	}
+	if s.Properties == nil {
+		// Plugins unmarshalling their configuration themselves accept
+		// arbitrary options
+		s = &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema)}
+	}
+
+	if p, ok := plugin.(telegraf.PluginDescriber); ok {
+		var defaults map[string]interface{}
+		s.Description, defaults = sampleConfigInfo(p.SampleConfig())
+		for key, value := range defaults {
+			if option, found := s.Properties[key]; found {
+				option.Default = value
+			}
+		}
+	}
+	if s.Description == "" {
+		s.Description = category + "." + name
+	}
+
+	if deprecation.Since != "" {
+		s.Deprecated = true
+		s.Description = strings.TrimSuffix(s.Description, ".") + ". " + deprecationDescription(deprecation)
+	}
+	return s
+}
+
+// typeSchema describes the given type following the TOML unmarshalling rules.
+func (b *schemaBuilder) typeSchema(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeOf(Duration(0)):
+		return &jsonSchema{Type: []string{"number", "string"}, Description: "Duration, e.g. '10s'"}
+	case reflect.TypeOf(Size(0)):
+		return &jsonSchema{Type: []string{"integer", "string"}, Description: "Size, e.g. '10MiB'"}
+	case reflect.TypeOf(time.Time{}):
+		return &jsonSchema{Type: "string"}
+	}
+
+	pt := reflect.PointerTo(t)
+	if pt.Implements(tomlUnmarshalerType) || pt.Implements(tomlRecursiveType) {
+		return &jsonSchema{}
+	}
+	if pt.Implements(textUnmarshalerType) {
+		return &jsonSchema{Type: []string{"boolean", "number", "string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Array, reflect.Slice:
+		return &jsonSchema{Type: "array", Items: b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		// Stop at recursive types
+		if b.visiting[t] {
+			return &jsonSchema{Type: "object"}
+		}
+		b.visiting[t] = true
+		defer delete(b.visiting, t)
+
+		s := &jsonSchema{
+			Type:                  "object",
+			Properties:            make(map[string]*jsonSchema),
+			UnevaluatedProperties: new(bool),
+		}
+		b.addFields(s, t)
+		return s
+	}
+	return &jsonSchema{}
+}
+
+// addFields adds the fields of the given struct as properties. The key of the
+// field is determined in the same way the TOML decoder does, embedded structs
+// without a key are flattened into the struct.
+func (b *schemaBuilder) addFields(s *jsonSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		key = strings.TrimSpace(key)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && key == "" {
+			b.addFields(s, field.Type)
+			continue
+		}
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+			continue
+		}
+		if key == "" {
+			key = toml.DefaultConfig.FieldToKey(t, field.Name)
+		}
+		if _, found := s.Properties[key]; found {
+			continue
+		}
+
+		option := b.typeSchema(field.Type)
+		if tags := strings.SplitN(field.Tag.Get("deprecated"), ";", 3); tags[0] != "" {
+			info := telegraf.DeprecationInfo{Since: tags[0], Notice: tags[len(tags)-1]}
+			if len(tags) > 2 {
+				info.RemovalIn = tags[1]
+			}
+			option.Deprecated = true
+			option.Description = deprecationDescription(info)
+		}
+		s.Properties[key] = option
+	}
+}
+
+func deprecationDescription(info telegraf.DeprecationInfo) string {
+	msg := "Deprecated since " + info.Since
+	if info.RemovalIn != "" {
+		msg += " and will be removed in " + info.RemovalIn
+	}
+	if info.Notice != "" && info.Notice != info.Since {
+		msg += ": " + info.Notice
+	}
+	return msg
+}
+
+// sampleConfigInfo extracts the plugin description and the option defaults
+// from the sample configuration. The description are the comment lines before
+// the plugin table. Defaults are all options of the plugin table including
+// commented ones, options of sub-tables are ignored.
+func sampleConfigInfo(sample string) (string, map[string]interface{}) {
+	var description []string
+	defaults := make(map[string]interface{})
+
+	var inPlugin bool
+	for _, line := range strings.Split(sample, "\n") {
+		line = strings.TrimSpace(line)
+		if !inPlugin {
+			if strings.HasPrefix(line, "[") {
+				inPlugin = true
+			} else if text, found := strings.CutPrefix(line, "#"); found && !strings.HasPrefix(text, "#") {
+				description = append(description, strings.TrimSpace(text))
+			}
+			continue
+		}
+
+		// Uncomment options but skip documentation
+		if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "##") {
+			line = strings.TrimSpace(line[1:])
+		}
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "#") || !strings.Contains(line, "=") {
+			continue
+		}
+
+		tbl, err := toml.Parse([]byte(line))
+		if err != nil {
+			continue
+		}
+		for key, field := range tbl.Fields {
+			kv, ok := field.(*ast.KeyValue)
+			if !ok {
+				continue
+			}
+			if n, err := tomlValueToNode(kv.Value); err == nil {
+				defaults[key] = nodeValue(n)
+			}
+		}
+	}
+	return strings.Join(description, " "), defaults
+}
+
+// SchemaViolation describes a setting of a configuration not matching the
+// configuration schema.
+type SchemaViolation struct {
+	// Path of the table or option, e.g. 'inputs.cpu[0].percpu'
+	Path string
+	// Message describing the violation
+	Message string
+	// Suggestion is a known option similar to an unknown one
+	Suggestion string
+}
+
+func (v SchemaViolation) String() string {
+	msg := v.Message
+	if v.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", v.Suggestion)
+	}
+	if v.Path == "" {
+		return msg
+	}
+	return v.Path + ": " + msg
+}
+
+// ValidateConfigData validates the configuration data against the schema
+// of all registered plugins and returns the violations found. The format of
+// the data is determined by the extension of the given path. Environment
+// variables are replaced before validation.
+func ValidateConfigData(data []byte, path string) ([]SchemaViolation, error) {
+	if format := FormatFromPath(path); format != FormatTOML {
+		converted, err := ConvertConfig(data, format, FormatTOML)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing data: %w", err)
+		}
+		data = converted
+	}
+	tbl, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing data: %w", err)
+	}
+	n, err := tomlTableToNode(tbl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing data: %w", err)
+	}
+	instance := nodeValue(n)
+
+	root := buildSchema()
+	buf, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("encoding schema failed: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if err := compiler.AddResource("telegraf.schema.json", bytes.NewReader(buf)); err != nil {
+		return nil, fmt.Errorf("adding schema failed: %w", err)
+	}
+	schema, err := compiler.Compile("telegraf.schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema failed: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	var verr *jsonschema.ValidationError
+	if err == nil {
+		return nil, nil
+	} else if !errors.As(err, &verr) {
+		return nil, err
+	}
+
+	seen := make(map[SchemaViolation]bool)
+	violations := make([]SchemaViolation, 0)
+	var collect func(e *jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+		if len(e.Causes) > 0 {
+			return
+		}
+		v, ok := root.violation(instance, e)
+		if ok && !seen[v] {
+			seen[v] = true
+			violations = append(violations, v)
+		}
+	}
+	collect(verr)
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Path == violations[j].Path {
+			return violations[i].Message < violations[j].Message
+		}
+		return violations[i].Path < violations[j].Path
+	})
+	return violations, nil
+}
+
+// violation converts the validation error to a violation suggesting known
+// options for unknown ones. Errors caused by other violations are skipped.
+func (s *jsonSchema) violation(instance interface{}, e *jsonschema.ValidationError) (SchemaViolation, bool) {
+	var segments []string
+	if e.InstanceLocation != "" {
+		for _, segment := range strings.Split(strings.TrimPrefix(e.InstanceLocation, "/"), "/") {
+			segment = strings.ReplaceAll(segment, "~1", "/")
+			segments = append(segments, strings.ReplaceAll(segment, "~0", "~"))
+		}
+	}
+
+	unknown := strings.HasSuffix(e.KeywordLocation, "/unevaluatedProperties") && len(segments) > 0
+	if !unknown {
+		return SchemaViolation{Path: instancePath(instance, segments), Message: e.Message}, true
+	}
+
+	// Find the schema of the table containing the unknown option to suggest
+	// a similar option
+	key := segments[len(segments)-1]
+	segments = segments[:len(segments)-1]
+	schema, value := s, instance
+	for _, segment := range segments {
+		if schema, value = schema.child(s, value, segment); schema == nil {
+			break
+		}
+	}
+
+	v := SchemaViolation{
+		Path:    instancePath(instance, segments),
+		Message: fmt.Sprintf("unknown option %q", key),
+	}
+	switch {
+	case len(segments) == 0:
+		v.Message = fmt.Sprintf("unknown table %q", key)
+	case len(segments) == 1 && segments[0] != "agent":
+		v.Message = fmt.Sprintf("unknown plugin %q", key)
+	}
+	if schema == nil {
+		return v, true
+	}
+
+	best := -1
+	for _, branch := range schema.branches(s, value) {
+		for name := range branch.Properties {
+			// Known options are only reported as unevaluated if their value
+			// is invalid which is reported separately
+			if name == key {
+				return v, false
+			}
+			d := levenshtein(key, name)
+			if d <= max(2, len(key)/4) && (best < 0 || d < best || d == best && name < v.Suggestion) {
+				best = d
+				v.Suggestion = name
+			}
+		}
+	}
+	return v, true
+}
+
+// branches returns the schema and all its sub-schemas applying to the given
+// value following references and conditions.
+func (s *jsonSchema) branches(root *jsonSchema, value interface{}) []*jsonSchema {
+	if s == nil {
+		return nil
+	}
+	result := []*jsonSchema{s}
+	if s.Ref != "" {
+		result = append(result, root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")].branches(root, value)...)
+	}
+	for _, sub := range s.AllOf {
+		result = append(result, sub.branches(root, value)...)
+	}
+	if s.If != nil {
+		if s.If.matches(value) {
+			result = append(result, s.Then.branches(root, value)...)
+		} else {
+			result = append(result, s.Else.branches(root, value)...)
+		}
+	}
+	return result
+}
+
+// child returns the schema and value of the given key or index of the value.
+func (s *jsonSchema) child(root *jsonSchema, value interface{}, key string) (*jsonSchema, interface{}) {
+	for _, branch := range s.branches(root, value) {
+		switch v := value.(type) {
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if branch.Items != nil && err == nil && idx >= 0 && idx < len(v) {
+				return branch.Items, v[idx]
+			}
+		case map[string]interface{}:
+			if option, found := branch.Properties[key]; found {
+				return option, v[key]
+			}
+			if branch.AdditionalProperties != nil {
+				return branch.AdditionalProperties, v[key]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// matches evaluates the subset of keywords used in conditions.
+func (s *jsonSchema) matches(value interface{}) bool {
+	obj, isObject := value.(map[string]interface{})
+	switch s.Type {
+	case "array":
+		if _, ok := value.([]interface{}); !ok {
+			return false
+		}
+	case "object":
+		if !isObject {
+			return false
+		}
+	}
+	for _, key := range s.Required {
+		if _, found := obj[key]; !found {
+			return false
+		}
+	}
+	for key, option := range s.Properties {
+		if v, found := obj[key]; found && option.Const != nil && v != option.Const {
+			return false
+		}
+	}
+	return s.Not == nil || !s.Not.matches(value)
+}
+
+// instancePath formats the JSON pointer segments as TOML path.
+func instancePath(instance interface{}, segments []string) string {
+	var path strings.Builder
+	value := instance
+	for _, segment := range segments {
+		switch v := value.(type) {
+		case []interface{}:
+			path.WriteString("[" + segment + "]")
+			if idx, err := strconv.Atoi(segment); err == nil && idx >= 0 && idx < len(v) {
+				value = v[idx]
+			}
+		case map[string]interface{}:
+			if path.Len() > 0 {
+				path.WriteString(".")
+			}
+			path.WriteString(tomlKey(segment))
+			value = v[segment]
+		}
+	}
+	return path.String()
+}
+
+// nodeValue converts the YAML node tree to generic JSON values.
+func nodeValue(n *yaml.Node) interface{} {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return nodeValue(n.Content[0])
+	case yaml.AliasNode:
+		return nodeValue(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			m[n.Content[i].Value] = nodeValue(n.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			s = append(s, nodeValue(item))
+		}
+		return s
+	}
+
+	switch n.Tag {
+	case "!!int":
+		if v, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+			return v
+		}
+	case "!!float":
+		if v, err := strconv.ParseFloat(n.Value, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return v
+		}
+	case "!!bool":
+		if v, err := strconv.ParseBool(n.Value); err == nil {
+			return v
+		}
+	case "!!null":
+		return nil
+	}
+	return n.Value
+}
+
+// levenshtein computes the edit distance of the two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,292 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func TestJSONSchema(t *testing.T) {
+	inputs.Add("schema_mockup", func() telegraf.Input { return &MockupSchemaPlugin{} })
+
+	buf, err := config.JSONSchema()
+	require.NoError(t, err)
+
+	// The schema must be valid
+	compiler := jsonschema.NewCompiler()
+	require.NoError(t, compiler.AddResource("schema.json", bytes.NewReader(buf)))
+	_, err = compiler.Compile("schema.json")
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]json.RawMessage `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(buf, &schema))
+	require.Contains(t, schema.Properties, "agent")
+	require.Contains(t, schema.Properties, "inputs")
+	require.Contains(t, schema.Defs, "parsers.json")
+	require.Contains(t, schema.Defs, "serializers.influx")
+	require.Contains(t, schema.Defs, "processors.processor")
+
+	expected := `{
+		"type": "object",
+		"description": "Mockup plugin to test the schema",
+		"properties": {
+			"servers": {"type": "array", "items": {"type": "string"}, "default": ["localhost:1234"]},
+			"server": {
+				"type": "string",
+				"description": "Deprecated since 1.10.0 and will be removed in 1.50.0: use 'servers' instead",
+				"deprecated": true
+			},
+			"timeout": {"type": ["number", "string"], "description": "Duration, e.g. '10s'", "default": "5s"},
+			"max_size": {"type": ["integer", "string"], "description": "Size, e.g. '10MiB'"},
+			"password": {"type": ["boolean", "number", "string"]},
+			"ratio": {"type": "number", "default": 0.5},
+			"headers": {"type": "object", "additionalProperties": {"type": "string"}},
+			"device_name": {"type": "boolean"},
+			"tls_ca": {"type": "string"},
+			"endpoint": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {"name": {"type": "string"}, "port": {"type": "integer"}},
+					"unevaluatedProperties": false
+				}
+			}
+		},
+		"unevaluatedProperties": false
+	}`
+	var actual map[string]interface{}
+	require.NoError(t, json.Unmarshal(schema.Defs["inputs.schema_mockup"], &actual))
+
+	// Check the common options separately
+	options := actual["properties"].(map[string]interface{})
+	for _, key := range []string{"interval", "alias", "namepass", "tagpass", "tags", "gather_timeout"} {
+		require.Contains(t, options, key)
+		delete(options, key)
+	}
+	for key := range options {
+		switch key {
+		case "servers", "server", "timeout", "max_size", "password", "ratio", "headers", "device_name", "tls_ca", "endpoint":
+		default:
+			delete(options, key)
+		}
+	}
+	buf, err = json.Marshal(actual)
+	require.NoError(t, err)
+	require.JSONEq(t, expected, string(buf))
+}
+
+func TestValidateConfigDataValid(t *testing.T) {
+	files := []string{
+		"single_plugin.toml",
+		"parsers_new.toml",
+		"processors_with_parsers.toml",
+		"serializers_new.toml",
+		"pipelines.toml",
+		"tagfilter_valid.toml",
+		filepath.Join("include", "vessel.toml"),
+		filepath.Join("include", "cycle_b.toml"),
+		filepath.Join("formats", "telegraf.toml"),
+		filepath.Join("formats", "telegraf.yaml"),
+		filepath.Join("formats", "telegraf.json"),
+	}
+	for _, fn := range files {
+		t.Run(fn, func(t *testing.T) {
+			fn := filepath.Join("testdata", fn)
+			data, err := os.ReadFile(fn)
+			require.NoError(t, err)
+
+			violations, err := config.ValidateConfigData(data, fn)
+			require.NoError(t, err)
+			require.Empty(t, violations)
+		})
+	}
+}
+
+func TestValidateConfigDataInvalidFields(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "invalid_field*.toml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, fn := range files {
+		t.Run(filepath.Base(fn), func(t *testing.T) {
+			data, err := os.ReadFile(fn)
+			require.NoError(t, err)
+
+			violations, err := config.ValidateConfigData(data, fn)
+			require.NoError(t, err)
+			require.Len(t, violations, 1)
+			require.Equal(t, `unknown option "not_a_field"`, violations[0].Message)
+		})
+	}
+}
+
+func TestValidateConfigData(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "misspelled options",
+			path: "telegraf.conf",
+			data: `
+[agent]
+  intervall = "10s"
+
+[[inputs.memcached]]
+  server = ["localhost"]
+  namepas = ["cpu"]
+
+[[outputs.http]]
+  url = "http://localhost"
+  flush_intervall = "10s"
+`,
+			expected: []string{
+				`agent: unknown option "intervall", did you mean "interval"?`,
+				`inputs.memcached[0]: unknown option "namepas", did you mean "namepass"?`,
+				`inputs.memcached[0]: unknown option "server", did you mean "servers"?`,
+				`outputs.http[0]: unknown option "flush_intervall", did you mean "flush_interval"?`,
+			},
+		},
+		{
+			name: "unknown plugin",
+			path: "telegraf.conf",
+			data: `
+[[inputs.memcache]]
+  servers = ["localhost"]
+
+[[output.http]]
+`,
+			expected: []string{
+				`unknown table "output", did you mean "outputs"?`,
+				`inputs: unknown plugin "memcache", did you mean "memcached"?`,
+			},
+		},
+		{
+			name: "wrong types",
+			path: "telegraf.conf",
+			data: `
+[agent]
+  omit_hostname = "yes"
+
+[[inputs.memcached]]
+  servers = "localhost"
+  port = "11211"
+  [inputs.memcached.tags]
+    dc = ["a", "b"]
+`,
+			expected: []string{
+				`agent.omit_hostname: expected boolean, but got string`,
+				`inputs.memcached[0].port: expected integer, but got string`,
+				`inputs.memcached[0].servers: expected array, but got string`,
+				`inputs.memcached[0].tags.dc: expected string, but got array`,
+			},
+		},
+		{
+			name: "parser options",
+			path: "telegraf.conf",
+			data: `
+[[inputs.parser_test_new]]
+  data_format = "csv"
+  csv_header_row_cont = 1
+
+[[inputs.parser_test_new]]
+  json_query = "data"
+
+[[inputs.parser_test_new]]
+  data_format = "unknown"
+`,
+			expected: []string{
+				`inputs.parser_test_new[0]: unknown option "csv_header_row_cont", did you mean "csv_header_row_count"?`,
+				`inputs.parser_test_new[1]: unknown option "json_query"`,
+				`inputs.parser_test_new[2].data_format: value must be one of`,
+			},
+		},
+		{
+			name: "yaml",
+			path: "telegraf.yaml",
+			data: `
+inputs:
+  memcached:
+    - servers: [localhost]
+      intervall: 10s
+`,
+			expected: []string{
+				`inputs.memcached[0]: unknown option "intervall", did you mean "interval"?`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := config.ValidateConfigData([]byte(tt.data), tt.path)
+			require.NoError(t, err)
+
+			actual := make([]string, 0, len(violations))
+			for _, v := range violations {
+				actual = append(actual, v.String())
+			}
+			require.Len(t, actual, len(tt.expected), "%q", actual)
+			for i, expected := range tt.expected {
+				require.Contains(t, actual[i], expected)
+			}
+		})
+	}
+}
+
+// Mockup INPUT plugin for testing the schema generation
+type MockupSchemaPlugin struct {
+	Servers    []string          `toml:"servers"`
+	Server     string            `toml:"server" deprecated:"1.10.0;1.50.0;use 'servers' instead"`
+	Timeout    config.Duration   `toml:"timeout"`
+	MaxSize    config.Size       `toml:"max_size"`
+	Password   config.Secret     `toml:"password"`
+	Ratio      float64           `toml:"ratio"`
+	Headers    map[string]string `toml:"headers"`
+	DeviceName bool
+	Endpoints  []struct {
+		Name string `toml:"name"`
+		Port int    `toml:"port"`
+	} `toml:"endpoint"`
+	Log telegraf.Logger `toml:"-"`
+	MockupSchemaTLS
+}
+
+type MockupSchemaTLS struct {
+	TLSCA string `toml:"tls_ca"`
+}
+
+func (*MockupSchemaPlugin) SampleConfig() string {
+	return `# Mockup plugin to test the schema
+[[inputs.schema_mockup]]
+  ## Servers to connect to
+  servers = ["localhost:1234"]
+
+  ## Timeout for connecting
+  # timeout = "5s"
+
+  ## Ratio of something
+  # ratio = 0.5
+
+  ## Endpoints
+  # [[inputs.schema_mockup.endpoint]]
+  #   name = "foo"
+`
+}
+
+func (*MockupSchemaPlugin) Gather(telegraf.Accumulator) error {
+	return nil
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"log"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"sync/atomic"
 
@@ -45,6 +47,50 @@ func ResetSecrets() {
 	secretCount.Store(0)
 }
 
+// DestroySecrets destroys all secrets of the given plugin and stops tracking
+// them, e.g. for plugins removed from the running agent on a reload. The
+// plugin must not be used anymore afterwards.
+func DestroySecrets(plugin interface{}) {
+	destroySecrets(reflect.ValueOf(plugin), make(map[uintptr]bool))
+}
+
+var secretType = reflect.TypeOf(Secret{})
+
+func destroySecrets(v reflect.Value, visited map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		destroySecrets(v.Elem(), visited)
+	case reflect.Interface:
+		if !v.IsNil() {
+			destroySecrets(v.Elem(), visited)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			destroySecrets(v.Index(i), visited)
+		}
+	case reflect.Struct:
+		if v.Type() == secretType {
+			if v.CanAddr() {
+				s := v.Addr().Interface().(*Secret)
+				unlinkedSecrets = slices.DeleteFunc(unlinkedSecrets, func(u *Secret) bool { return u == s })
+				s.Destroy()
+			}
+			return
+		}
+		// Secrets are part of the plugin settings, so only exported fields
+		// need to be considered
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				destroySecrets(v.Field(i), visited)
+			}
+		}
+	}
+}
+
 func EnableSecretProtection() {
 	selectedImpl = &protectedSecretImpl{}
 }
@@ -313,6 +359,27 @@ func resolve(secret []byte, resolvers map[string]telegraf.ResolveFunc) ([]byte,
 	return newsecret, remaining, replaceErrs
 }
 
+// SplitSecretReference returns the store ID and the key of a secret-store
+// reference such as "@{vault:token}".
+func SplitSecretReference(ref string) (storeID, key string) {
+	return splitLink(ref)
+}
+
+// secretReferencesSince returns the sorted, distinct secret-store references
+// of the secrets unmarshalled after the given number of unlinked secrets.
+func secretReferencesSince(start int) []string {
+	var refs []string
+	for _, s := range unlinkedSecrets[start:] {
+		for _, ref := range s.GetUnlinked() {
+			if !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
+
 func splitLink(s string) (storeID, key string) {
 	// There should _ALWAYS_ be two parts due to the regular expression match
 	parts := strings.SplitN(s[2:len(s)-1], ":", 2)
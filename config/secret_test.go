@@ -351,6 +351,51 @@ func TestSecretEnvironmentVariable(t *testing.T) {
 	require.EqualValues(t, "an env secret", secret.TemporaryString())
 }
 
+func TestLinkSecretsKeepsLinkedSecrets(t *testing.T) {
+	defer func() { unlinkedSecrets = make([]*Secret, 0) }()
+
+	cfg := []byte(`
+[[inputs.mockup]]
+  secret = "@{mock:secret1}"
+`)
+
+	// Link the secret of a running plugin to a dynamic store
+	running := NewConfig()
+	require.NoError(t, running.LoadConfigData(cfg, EmptySourcePath))
+	store := &MockupSecretStore{
+		Secrets: map[string][]byte{"secret1": []byte("old")},
+		Dynamic: true,
+	}
+	require.NoError(t, store.Init())
+	running.SecretStores["mock"] = store
+	require.NoError(t, running.LinkSecrets())
+
+	// Loading and linking a new configuration on reload does not touch the
+	// already linked secret
+	reloaded := NewConfig()
+	require.NoError(t, reloaded.LoadConfigData(cfg, EmptySourcePath))
+	other := &MockupSecretStore{
+		Secrets: map[string][]byte{"secret1": []byte("new")},
+	}
+	require.NoError(t, other.Init())
+	reloaded.SecretStores["mock"] = other
+	require.NoError(t, reloaded.LinkSecrets())
+	require.Empty(t, unlinkedSecrets)
+
+	store.Secrets["secret1"] = []byte("rotated")
+	plugin := running.Inputs[0].Input.(*MockupSecretPlugin)
+	secret, err := plugin.Secret.Get()
+	require.NoError(t, err)
+	defer secret.Destroy()
+	require.EqualValues(t, "rotated", secret.TemporaryString())
+
+	plugin = reloaded.Inputs[0].Input.(*MockupSecretPlugin)
+	secret, err = plugin.Secret.Get()
+	require.NoError(t, err)
+	defer secret.Destroy()
+	require.EqualValues(t, "new", secret.TemporaryString())
+}
+
 func TestSecretCount(t *testing.T) {
 	secretCount.Store(0)
 	cfg := []byte(`
@@ -376,6 +421,55 @@ func TestSecretCount(t *testing.T) {
 	require.Equal(t, int64(0), secretCount.Load())
 }
 
+func TestDestroySecrets(t *testing.T) {
+	defer func() { unlinkedSecrets = make([]*Secret, 0) }()
+	secretCount.Store(0)
+
+	cfg := []byte(`
+[[inputs.mockup]]
+  secret = "@{mock:secret1}"
+
+[[inputs.mockup]]
+  secret = "a secret"
+`)
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(cfg, EmptySourcePath))
+	require.Len(t, c.Inputs, 2)
+	require.Equal(t, int64(2), secretCount.Load())
+	require.Len(t, unlinkedSecrets, 1)
+
+	// Destroying the secrets of a removed plugin stops tracking them
+	DestroySecrets(c.Inputs[0].Input)
+	require.Equal(t, int64(1), secretCount.Load())
+	require.Empty(t, unlinkedSecrets)
+	require.True(t, c.Inputs[0].Input.(*MockupSecretPlugin).Secret.Empty())
+
+	DestroySecrets(c.Inputs[1])
+	require.Zero(t, secretCount.Load())
+}
+
+func TestSecretReferencesOfPlugins(t *testing.T) {
+	defer func() { unlinkedSecrets = make([]*Secret, 0) }()
+
+	cfg := []byte(`
+		[[inputs.mockup]]
+		  secret = "@{mock:user}:@{mock:password}@@{mock:user}"
+
+		[[inputs.mockup]]
+		  secret = "constant"
+	`)
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(cfg, EmptySourcePath))
+	require.Len(t, c.Inputs, 2)
+	require.Equal(t, []string{"@{mock:password}", "@{mock:user}"}, c.Inputs[0].Config.Secrets)
+	require.Empty(t, c.Inputs[1].Config.Secrets)
+
+	storeID, key := SplitSecretReference("@{mock:password}")
+	require.Equal(t, "mock", storeID)
+	require.Equal(t, "password", key)
+}
+
 func TestSecretStoreStatic(t *testing.T) {
 	cfg := []byte(
 		`
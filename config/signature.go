@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	jose "github.com/dvsekhvalnov/jose2go"
+)
+
+// ErrInvalidSignature is returned if the signature of a remote configuration
+// is missing or does not match the configuration.
+var ErrInvalidSignature = errors.New("invalid configuration signature")
+
+// signatureKeys are the public keys to verify the signatures of remote
+// configurations with
+var signatureKeys []crypto.PublicKey
+
+// signatureSuffix is appended to the path of a remote configuration to get
+// the URL of its detached signature
+const signatureSuffix = ".sig"
+
+// jwsAlgorithms are the accepted signing algorithms of JWS signatures
+var jwsAlgorithms = []string{
+	"EdDSA",
+	"ES256", "ES384", "ES512",
+	"PS256", "PS384", "PS512",
+	"RS256", "RS384", "RS512",
+}
+
+// SetSignatureKeys reads the PEM encoded Ed25519, ECDSA or RSA public keys
+// from the given files. If any key is set, remote configurations without a
+// valid detached signature are rejected.
+func SetSignatureKeys(files []string) error {
+	keys := make([]crypto.PublicKey, 0, len(files))
+	for _, fn := range files {
+		buf, err := os.ReadFile(fn)
+		if err != nil {
+			return fmt.Errorf("reading signature key failed: %w", err)
+		}
+		var found bool
+		for {
+			var block *pem.Block
+			block, buf = pem.Decode(buf)
+			if block == nil {
+				break
+			}
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("parsing signature key in %q failed: %w", fn, err)
+			}
+			switch key.(type) {
+			case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+			default:
+				return fmt.Errorf("unsupported signature key type %T in %q", key, fn)
+			}
+			keys = append(keys, key)
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("no public key found in %q", fn)
+		}
+	}
+	signatureKeys = keys
+	return nil
+}
+
+// signatureURL returns the location of the detached signature of the remote
+// configuration.
+func signatureURL(u *url.URL) *url.URL {
+	sig := *u
+	sig.Path += signatureSuffix
+	sig.RawPath = ""
+	return &sig
+}
+
+// verifySignature checks the detached signature of the configuration data
+// against the given keys. The signature is either a raw or base64 encoded
+// Ed25519 signature or a JWS in compact serialization with the configuration
+// as payload. The payload may be detached, i.e. empty.
+func verifySignature(data, signature []byte, keys []crypto.PublicKey) error {
+	sig := signature
+	if len(signature) != ed25519.SignatureSize {
+		signature = bytes.TrimSpace(signature)
+		if len(signature) == 0 {
+			return fmt.Errorf("%w: empty signature", ErrInvalidSignature)
+		}
+		if bytes.Count(signature, []byte(".")) == 2 {
+			return verifyJWS(data, string(signature), keys)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(string(signature))
+		if err != nil {
+			return fmt.Errorf("%w: decoding signature failed: %w", ErrInvalidSignature, err)
+		}
+		sig = decoded
+	}
+	for _, key := range keys {
+		if k, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(k, data, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature does not match any Ed25519 key", ErrInvalidSignature)
+}
+
+func verifyJWS(data []byte, token string, keys []crypto.PublicKey) error {
+	parts := strings.Split(token, ".")
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("%w: decoding JWS header failed: %w", ErrInvalidSignature, err)
+	}
+	var headers struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := json.Unmarshal(header, &headers); err != nil {
+		return fmt.Errorf("%w: parsing JWS header failed: %w", ErrInvalidSignature, err)
+	}
+	if !slices.Contains(jwsAlgorithms, headers.Algorithm) {
+		return fmt.Errorf("%w: unsupported JWS algorithm %q", ErrInvalidSignature, headers.Algorithm)
+	}
+
+	// Attach the configuration for detached payloads, otherwise the payload
+	// must be the configuration
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	if parts[1] == "" {
+		parts[1] = payload
+	} else if parts[1] != payload {
+		return fmt.Errorf("%w: JWS payload does not match the configuration", ErrInvalidSignature)
+	}
+
+	// The EdDSA algorithm is not supported by the JOSE library
+	if headers.Algorithm == "EdDSA" {
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return fmt.Errorf("%w: decoding JWS signature failed: %w", ErrInvalidSignature, err)
+		}
+		for _, key := range keys {
+			if k, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(k, []byte(parts[0]+"."+parts[1]), sig) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: JWS does not match any Ed25519 key", ErrInvalidSignature)
+	}
+
+	token = strings.Join(parts, ".")
+	for _, key := range keys {
+		if _, ok := key.(ed25519.PublicKey); ok {
+			continue
+		}
+		if _, _, err := jose.DecodeBytes(token, key); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: JWS does not match any %s key", ErrInvalidSignature, headers.Algorithm)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jose "github.com/dvsekhvalnov/jose2go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSignatureKeys(t *testing.T) {
+	defer func() { signatureKeys = nil }()
+
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var buf []byte
+	for _, key := range []crypto.PublicKey{edKey, &ecKey.PublicKey} {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	fn := filepath.Join(t.TempDir(), "keys.pem")
+	require.NoError(t, os.WriteFile(fn, buf, 0600))
+
+	require.NoError(t, SetSignatureKeys([]string{fn}))
+	require.Len(t, signatureKeys, 2)
+	require.Equal(t, edKey, signatureKeys[0])
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("no key"), 0600))
+	require.ErrorContains(t, SetSignatureKeys([]string{empty}), "no public key found")
+
+	require.NoError(t, SetSignatureKeys(nil))
+	require.Empty(t, signatureKeys)
+}
+
+func TestVerifySignature(t *testing.T) {
+	data := []byte("[[inputs.cpu]]\n")
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys := []crypto.PublicKey{&rsaKey.PublicKey, edPublic}
+
+	// Build a JWS with detached payload
+	detached := func(token string) string {
+		parts := strings.Split(token, ".")
+		parts[1] = ""
+		return strings.Join(parts, ".")
+	}
+	rsaToken, err := jose.SignBytes(data, jose.RS256, rsaKey)
+	require.NoError(t, err)
+	noneToken, err := jose.SignBytes(data, jose.NONE, nil)
+	require.NoError(t, err)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA"}`))
+	input := header + "." + base64.RawURLEncoding.EncodeToString(data)
+	edToken := input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(edPrivate, []byte(input)))
+
+	tests := []struct {
+		name      string
+		signature []byte
+		expected  string
+	}{
+		{
+			name:      "raw ed25519",
+			signature: ed25519.Sign(edPrivate, data),
+		},
+		{
+			name:      "base64 ed25519",
+			signature: []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edPrivate, data)) + "\n"),
+		},
+		{
+			name:      "jws",
+			signature: []byte(rsaToken),
+		},
+		{
+			name:      "detached jws",
+			signature: []byte(detached(rsaToken)),
+		},
+		{
+			name:      "detached eddsa jws",
+			signature: []byte(detached(edToken)),
+		},
+		{
+			name:      "empty",
+			signature: []byte("\n"),
+			expected:  "empty signature",
+		},
+		{
+			name:      "wrong key",
+			signature: ed25519.Sign(otherPrivate, data),
+			expected:  "signature does not match any Ed25519 key",
+		},
+		{
+			name:      "unsigned jws",
+			signature: []byte(noneToken),
+			expected:  `unsupported JWS algorithm "none"`,
+		},
+		{
+			name:      "jws of other content",
+			signature: []byte(strings.Replace(rsaToken, ".", ".e30", 1)),
+			expected:  "JWS payload does not match the configuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(data, tt.signature, keys)
+			if tt.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidSignature)
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestURLSignature(t *testing.T) {
+	httpLoadConfigRetryInterval = 0 * time.Second
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signatureKeys = []crypto.PublicKey{public}
+	defer func() { signatureKeys = nil }()
+
+	data := []byte("[agent]\n  interval = \"5s\"\n")
+	signature := ed25519.Sign(private, data)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signed/telegraf.conf", "/unsigned/telegraf.conf", "/tampered/telegraf.conf":
+			_, _ = w.Write(data)
+		case "/signed/telegraf.conf.sig":
+			_, _ = w.Write(signature)
+		case "/tampered/telegraf.conf.sig":
+			_, _ = w.Write(ed25519.Sign(private, []byte("[[inputs.exec]]\n")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig(ts.URL+"/signed/telegraf.conf"))
+	require.Equal(t, Duration(5*time.Second), c.Agent.Interval)
+
+	c = NewConfig()
+	err = c.LoadConfig(ts.URL + "/unsigned/telegraf.conf")
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.ErrorContains(t, err, "fetching signature failed")
+
+	c = NewConfig()
+	err = c.LoadConfig(ts.URL + "/tampered/telegraf.conf")
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	require.ErrorContains(t, err, "signature does not match any Ed25519 key")
+}
@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	PluginDescriber
 
@@ -8,6 +10,18 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextInput is an Input supporting the cancellation of a gather. The agent
+// calls GatherContext instead of Gather and cancels the context once the
+// gather exceeds the input's gather_timeout.
+type ContextInput interface {
+	Input
+
+	// GatherContext takes in an accumulator and adds the metrics that the
+	// Input gathers. It should return as soon as possible after the context
+	// is done.
+	GatherContext(context.Context, Accumulator) error
+}
+
 type ServiceInput interface {
 	Input
 
@@ -10,6 +10,7 @@ type config struct {
 	clk   clock.Clock
 	start time.Time
 	align bool
+	next  func(time.Time) time.Time
 
 	notifier chan bool
 }
@@ -34,3 +35,12 @@ func WithStartupNotification(notifier chan bool) Option {
 		c.notifier = notifier
 	}
 }
+
+// WithSchedule triggers the ticks at the times returned by the given function
+// for the previous tick instead of every interval. The ticker stops ticking
+// if the function returns the zero time.
+func WithSchedule(next func(time.Time) time.Time) Option {
+	return func(c *config) {
+		c.next = next
+	}
+}
@@ -17,6 +17,7 @@ type Ticker struct {
 	schedule time.Time
 	interval time.Duration
 	jitter   time.Duration
+	offset   time.Duration
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 
@@ -34,8 +35,11 @@ func NewTicker(interval, jitter, offset time.Duration, opt ...Option) *Ticker {
 
 	schedule := cfg.clk.Now()
 
-	// Align the scheduled trigger time to interval borders
-	if cfg.align {
+	// Align the scheduled trigger time to interval borders or determine the
+	// first trigger time of the schedule
+	if cfg.next != nil {
+		schedule = cfg.next(schedule)
+	} else if cfg.align {
 		// Add minimum interval size to avoid scheduling exceptionally short
 		// intervals. This avoids an issue that can occur where the previous
 		// interval ends slightly early due to very minor clock changes.
@@ -44,7 +48,9 @@ func NewTicker(interval, jitter, offset time.Duration, opt ...Option) *Ticker {
 
 	// Compute the scheduled first tick by adding the offset. By doing so, we
 	// do not need to take the offset into account later.
-	schedule = schedule.Add(offset)
+	if !schedule.IsZero() {
+		schedule = schedule.Add(offset)
+	}
 
 	// Initialize the ticker instance and start it
 	t := &Ticker{
@@ -53,6 +59,7 @@ func NewTicker(interval, jitter, offset time.Duration, opt ...Option) *Ticker {
 		schedule: schedule,
 		interval: interval,
 		jitter:   jitter,
+		offset:   offset,
 		cfg:      cfg,
 	}
 
@@ -74,6 +81,15 @@ func (t *Ticker) Stop() {
 }
 
 func (t *Ticker) run(ctx context.Context) {
+	// A schedule without any trigger time never ticks
+	if t.schedule.IsZero() {
+		if t.cfg.notifier != nil {
+			t.cfg.notifier <- true
+		}
+		<-ctx.Done()
+		return
+	}
+
 	// Start with the first scheduled tick
 	timer := t.clk.Timer(t.clk.Until(t.schedule) + internal.RandomDuration(t.jitter))
 	defer timer.Stop()
@@ -89,8 +105,9 @@ func (t *Ticker) run(ctx context.Context) {
 			// randomizing the timing with the given jitter (if any). Note, we
 			// need to remember the next scheduling without adding the ticker
 			// to avoid drifting of the ticks by jitter/2 on average!
-			t.schedule = t.schedule.Add(t.interval)
-			timer.Reset(t.clk.Until(t.schedule) + internal.RandomDuration(t.jitter))
+			if t.advance() {
+				timer.Reset(t.clk.Until(t.schedule) + internal.RandomDuration(t.jitter))
+			}
 
 			// Fire our event in a non-blocking fashion to avoid blocking the
 			// ticker if the agent code did not read the channel yet
@@ -103,3 +120,18 @@ func (t *Ticker) run(ctx context.Context) {
 		}
 	}
 }
+
+// advance computes the next scheduled tick and returns false if there is none.
+func (t *Ticker) advance() bool {
+	if t.cfg.next == nil {
+		t.schedule = t.schedule.Add(t.interval)
+		return true
+	}
+
+	next := t.cfg.next(t.schedule.Add(-t.offset))
+	if next.IsZero() {
+		return false
+	}
+	t.schedule = next.Add(t.offset)
+	return true
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTicker(t *testing.T) {
+	// Tick at the full minute and 20 seconds past
+	next := func(ts time.Time) time.Time {
+		minute := ts.Truncate(time.Minute)
+		if ts.Before(minute.Add(20 * time.Second)) {
+			return minute.Add(20 * time.Second)
+		}
+		return minute.Add(time.Minute)
+	}
+
+	clk := clock.NewMock()
+	start := clk.Now()
+	end := start.Add(150 * time.Second)
+
+	notify := make(chan bool, 1)
+	ticker := NewTicker(10*time.Second, 0, 5*time.Second, WithClock(clk), WithSchedule(next), WithStartupNotification(notify))
+	defer ticker.Stop()
+	<-notify
+
+	expected := []time.Time{
+		time.Unix(25, 0).UTC(),
+		time.Unix(65, 0).UTC(),
+		time.Unix(85, 0).UTC(),
+		time.Unix(125, 0).UTC(),
+		time.Unix(145, 0).UTC(),
+	}
+
+	actual := make([]time.Time, 0)
+	for !clk.Now().After(end) {
+		select {
+		case ts := <-ticker.C:
+			actual = append(actual, ts.UTC())
+		default:
+			clk.Add(time.Second)
+		}
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestScheduledTickerNeverTicks(t *testing.T) {
+	clk := clock.NewMock()
+	notify := make(chan bool, 1)
+	ticker := NewTicker(10*time.Second, 0, 0, WithClock(clk), WithSchedule(func(time.Time) time.Time {
+		return time.Time{}
+	}), WithStartupNotification(notify))
+	defer ticker.Stop()
+	<-notify
+
+	clk.Add(time.Hour)
+	select {
+	case <-ticker.C:
+		require.Fail(t, "unexpected tick")
+	default:
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// DefaultBatchTargetLatency is the write latency the batch size is tuned for
+// if not configured otherwise.
+const DefaultBatchTargetLatency = time.Second
+
+// batchTuner adapts the batch size of an output to the observed write
+// latency and errors. The size is halved on failed writes and decreased if
+// a write takes longer than the target latency. Full batches written in less
+// than half of the target latency increase the size up to the configured
+// maximum.
+type batchTuner struct {
+	max    int
+	target time.Duration
+	size   atomic.Int64
+}
+
+func newBatchTuner(maxSize int, target time.Duration) *batchTuner {
+	if target <= 0 {
+		target = DefaultBatchTargetLatency
+	}
+	t := &batchTuner{
+		max:    maxSize,
+		target: target,
+	}
+	t.size.Store(int64(maxSize))
+	return t
+}
+
+// current returns the current batch size.
+func (t *batchTuner) current() int {
+	return int(t.size.Load())
+}
+
+// update adapts the batch size after writing a batch with the given number
+// of metrics.
+func (t *batchTuner) update(n int, elapsed time.Duration, err error) {
+	size := t.current()
+
+	var partial *internal.PartialWriteError
+	switch {
+	case err != nil && !errors.As(err, &partial):
+		size /= 2
+	case elapsed > t.target:
+		size -= size / 4
+	case elapsed < t.target/2 && n >= size:
+		size += max(size/10, 1)
+	}
+
+	t.size.Store(int64(min(max(size, 1), t.max)))
+}
@@ -30,6 +30,9 @@ type Transaction struct {
 	// Marks this transaction as valid
 	valid bool
 
+	// Marks the accepted metrics as spooled instead of written
+	spooled bool
+
 	// Internal state that can be used by the buffer implementation
 	state interface{}
 }
@@ -92,6 +95,9 @@ type BufferStats struct {
 	MetricsDropped  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
+
+	// overflow is called for metrics dropped due to a full buffer
+	overflow func(telegraf.Metric)
 }
 
 // NewBuffer returns a new empty Buffer with the given capacity.
@@ -114,6 +120,8 @@ func NewBuffer(name, id, alias string, capacity int, strategy, path string, disk
 		return NewMemoryBuffer(capacity, bs)
 	case "disk_write_through":
 		return NewDiskBuffer(id, path, bs, diskSync)
+	case "hybrid":
+		return NewHybridBuffer(id, path, tags, bs, diskSync)
 	case "discard":
 		return newDiscardBuffer(bs), nil
 	}
@@ -124,7 +132,7 @@ func NewBuffer(name, id, alias string, capacity int, strategy, path string, disk
 // opening or allocating the buffer.
 func CheckBufferSettings(strategy string) error {
 	switch strategy {
-	case "", "memory", "disk_write_through":
+	case "", "memory", "disk_write_through", "hybrid":
 		return nil
 	}
 	return fmt.Errorf("invalid buffer strategy %q", strategy)
@@ -172,6 +180,16 @@ func (b *BufferStats) metricAdded(count int64) {
 	b.MetricsAdded.Incr(count)
 }
 
+// metricAccepted accounts for a metric accepted in the given transaction.
+// Metrics removed from the buffer for spooling are not counted as written.
+func (b *BufferStats) metricAccepted(tx *Transaction, m telegraf.Metric) {
+	if tx.spooled {
+		m.Drop()
+		return
+	}
+	b.metricWritten(m)
+}
+
 func (b *BufferStats) metricWritten(m telegraf.Metric) {
 	AgentMetricsWritten.Incr(1)
 	b.MetricsWritten.Incr(1)
@@ -184,6 +202,18 @@ func (b *BufferStats) metricRejected(m telegraf.Metric) {
 	m.Reject()
 }
 
+// metricOverflowed drops the metric due to a full buffer.
+func (b *BufferStats) metricOverflowed(m telegraf.Metric) {
+	if b.overflow != nil {
+		b.overflow(m)
+	}
+	b.metricDropped(m)
+}
+
+func (b *BufferStats) setOverflowHandler(f func(telegraf.Metric)) {
+	b.overflow = f
+}
+
 func (b *BufferStats) metricDropped(m telegraf.Metric) {
 	AgentMetricsDropped.Incr(1)
 	b.MetricsDropped.Incr(1)
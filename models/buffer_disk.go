@@ -22,8 +22,9 @@ type DiskBuffer struct {
 	file *wal.Log
 	path string
 
-	batchFirst uint64 // Index of the first metric in the batch
-	batchSize  uint64 // Number of metrics currently in the batch
+	// Indices of the metrics in unfinished transactions. Those metrics are
+	// skipped when starting new transactions.
+	inflight map[uint64]bool
 
 	// Ending point of metrics read from disk on telegraf launch.
 	// Used to know whether to discard tracking metrics.
@@ -37,21 +38,16 @@ type DiskBuffer struct {
 
 func NewDiskBuffer(id, path string, stats BufferStats, diskSync bool) (*DiskBuffer, error) {
 	filePath := filepath.Join(path, id)
-	walFile, err := wal.Open(filePath, &wal.Options{
-		AllowEmpty: true,
-		NoSync:     !diskSync,
-	})
+	walFile, err := openBufferFile(filePath, diskSync)
 	if err != nil {
-		if errors.Is(err, wal.ErrCorrupt) {
-			return nil, fmt.Errorf("wal file is corrupt, you have to manually delete the wal at %q and restart Telegraf", filePath)
-		}
-		return nil, fmt.Errorf("failed to open wal file: %w", err)
+		return nil, err
 	}
 
 	buf := &DiskBuffer{
 		BufferStats: stats,
 		file:        walFile,
 		path:        filePath,
+		inflight:    make(map[uint64]bool),
 	}
 	if buf.Len() > 0 {
 		buf.originalEnd = buf.writeIndex()
@@ -59,6 +55,22 @@ func NewDiskBuffer(id, path string, stats BufferStats, diskSync bool) (*DiskBuff
 	return buf, nil
 }
 
+// openBufferFile opens the write-ahead log at the given path for buffering
+// metrics on disk.
+func openBufferFile(path string, diskSync bool) (*wal.Log, error) {
+	walFile, err := wal.Open(path, &wal.Options{
+		AllowEmpty: true,
+		NoSync:     !diskSync,
+	})
+	if err != nil {
+		if errors.Is(err, wal.ErrCorrupt) {
+			return nil, fmt.Errorf("wal file is corrupt, you have to manually delete the wal at %q and restart Telegraf", path)
+		}
+		return nil, fmt.Errorf("failed to open wal file: %w", err)
+	}
+	return walFile, nil
+}
+
 func (b *DiskBuffer) Len() int {
 	b.Lock()
 	defer b.Unlock()
@@ -126,27 +138,31 @@ func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 	b.Lock()
 	defer b.Unlock()
 
-	if b.length() == 0 {
+	if b.length()-len(b.inflight) <= 0 {
 		return &Transaction{}
 	}
-	b.batchFirst = b.readIndex()
-	b.batchSize = 0
 
 	metrics := make([]telegraf.Metric, 0, batchSize)
-	offsets := make([]int, 0, batchSize)
-	readIndex := b.batchFirst
+	indices := make([]uint64, 0, batchSize)
+	readIndex := b.readIndex()
 	endIndex := b.writeIndex()
 	for offset := 0; batchSize > 0 && readIndex < endIndex; offset++ {
-		data, err := b.file.Read(readIndex)
-		if err != nil {
-			panic(err)
-		}
+		index := readIndex
 		readIndex++
 
 		if slices.Contains(b.mask, offset) {
 			// Metric is masked by a previous write and is scheduled for removal
 			continue
 		}
+		if b.inflight[index] {
+			// Metric is part of another unfinished transaction
+			continue
+		}
+
+		data, err := b.file.Read(index)
+		if err != nil {
+			panic(err)
+		}
 
 		// Validate that a tracking metric is from this instance of telegraf and skip ones from older instances.
 		// A tracking metric can be skipped here because metric.Accept() is only called once data is successfully
@@ -177,11 +193,11 @@ func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 		}
 
 		metrics = append(metrics, m)
-		offsets = append(offsets, offset)
-		b.batchSize++
+		indices = append(indices, index)
+		b.inflight[index] = true
 		batchSize--
 	}
-	return &Transaction{Batch: metrics, valid: true, state: offsets}
+	return &Transaction{Batch: metrics, valid: true, state: indices}
 }
 
 func (b *DiskBuffer) EndTransaction(tx *Transaction) {
@@ -195,21 +211,27 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 	}
 	tx.valid = false
 
-	// Get the metric offsets from the transaction
-	offsets := tx.state.([]int)
+	// Get the metric indices from the transaction
+	indices := tx.state.([]uint64)
 
 	b.Lock()
 	defer b.Unlock()
 
-	// Mark metrics which should be removed in the internal mask
+	for _, index := range indices {
+		delete(b.inflight, index)
+	}
+
+	// Mark metrics which should be removed in the internal mask using the
+	// offsets relative to the current front of the WAL file
+	first := b.readIndex()
 	remove := make([]int, 0, len(tx.Accept)+len(tx.Reject))
 	for _, idx := range tx.Accept {
-		b.metricWritten(tx.Batch[idx])
-		remove = append(remove, offsets[idx])
+		b.metricAccepted(tx, tx.Batch[idx])
+		remove = append(remove, int(indices[idx]-first))
 	}
 	for _, idx := range tx.Reject {
 		b.metricRejected(tx.Batch[idx])
-		remove = append(remove, offsets[idx])
+		remove = append(remove, int(indices[idx]-first))
 	}
 	b.mask = append(b.mask, remove...)
 	sort.Ints(b.mask)
@@ -237,8 +259,8 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 	removeIdx := correction + 1
 
 	// Remove the metrics in front from the WAL file
-	if err := b.file.TruncateFront(b.batchFirst + uint64(removeIdx)); err != nil {
-		log.Printf("E! batch length: %d, first: %d, size: %d", len(tx.Batch), b.batchFirst, b.batchSize)
+	if err := b.file.TruncateFront(first + uint64(removeIdx)); err != nil {
+		log.Printf("E! batch length: %d, first: %d, removing: %d", len(tx.Batch), first, removeIdx)
 		panic(err)
 	}
 
@@ -253,7 +275,6 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 		b.originalEnd = 0
 	}
 
-	b.resetBatch()
 	b.BufferSize.Set(int64(b.length()))
 }
 
@@ -267,8 +288,3 @@ func (b *DiskBuffer) Close() error {
 	}
 	return nil
 }
-
-func (b *DiskBuffer) resetBatch() {
-	b.batchFirst = 0
-	b.batchSize = 0
-}
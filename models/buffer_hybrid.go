@@ -0,0 +1,454 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/wal"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// DefaultBufferMemoryLimit is the default estimated size in bytes of the
+// metrics kept in memory by all hybrid buffers together.
+const DefaultBufferMemoryLimit = 64 * 1024 * 1024
+
+// hybridMemory is the memory budget shared by the hybrid buffers of all outputs
+var hybridMemory = &memoryBudget{
+	stat: selfstat.Register("agent", "buffer_memory_bytes", make(map[string]string)),
+}
+
+// memoryBudget limits the estimated size of the metrics kept in memory by
+// multiple buffers together. A limit of zero uses DefaultBufferMemoryLimit.
+type memoryBudget struct {
+	limit atomic.Int64
+	used  atomic.Int64
+	stat  selfstat.Stat
+}
+
+func (b *memoryBudget) setLimit(limit int64) {
+	b.limit.Store(limit)
+}
+
+// reserve takes the given number of bytes from the budget and returns false
+// if the budget is exhausted.
+func (b *memoryBudget) reserve(size int64) bool {
+	limit := b.limit.Load()
+	if limit <= 0 {
+		limit = DefaultBufferMemoryLimit
+	}
+	for {
+		used := b.used.Load()
+		if used+size > limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+size) {
+			b.updateStat(used + size)
+			return true
+		}
+	}
+}
+
+// force takes the given number of bytes from the budget even if this exceeds
+// the limit.
+func (b *memoryBudget) force(size int64) {
+	b.updateStat(b.used.Add(size))
+}
+
+// release returns the given number of bytes to the budget.
+func (b *memoryBudget) release(size int64) {
+	b.updateStat(b.used.Add(-size))
+}
+
+func (b *memoryBudget) updateStat(used int64) {
+	if b.stat != nil {
+		b.stat.Set(used)
+	}
+}
+
+// HybridBuffer keeps metrics in memory and spills them to a write-ahead log
+// on disk once the memory budget shared by all hybrid buffers is exhausted
+// or the output has been failing for longer than the spill delay. The
+// metrics in memory are always older than the ones on disk, so new metrics
+// are written to disk as long as it holds metrics. The metrics are moved
+// back to memory once the output accepts metrics again.
+type HybridBuffer struct {
+	BufferStats
+	sync.Mutex
+
+	// Metrics kept in memory ordered from oldest to newest with their
+	// estimated sizes reserved in the memory budget
+	memory []telegraf.Metric
+	sizes  []int64
+	bytes  int64
+	budget *memoryBudget
+
+	// Number of metrics in unfinished transactions. Those metrics were taken
+	// from memory and keep their reserved size until the transaction ends.
+	inflight int
+
+	file *wal.Log
+	path string
+
+	// Ending point of metrics read from disk on telegraf launch.
+	// Used to know whether to discard tracking metrics.
+	originalEnd uint64
+
+	// Duration of failing writes after which the metrics in memory are
+	// spilled to disk and the start of the current series of failed writes
+	spillAfter   time.Duration
+	failingSince time.Time
+
+	byteStat selfstat.Stat
+	diskStat selfstat.Stat
+}
+
+func NewHybridBuffer(id, path string, tags map[string]string, stats BufferStats, diskSync bool) (*HybridBuffer, error) {
+	filePath := filepath.Join(path, id)
+	walFile, err := openBufferFile(filePath, diskSync)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &HybridBuffer{
+		BufferStats: stats,
+		budget:      hybridMemory,
+		file:        walFile,
+		path:        filePath,
+		byteStat:    selfstat.Register("write", "buffer_bytes", tags),
+		diskStat:    selfstat.Register("write", "buffer_disk_size", tags),
+	}
+	if buf.diskLen() > 0 {
+		buf.originalEnd = buf.writeIndex()
+	}
+	buf.updateStats()
+	return buf, nil
+}
+
+// configure sets the duration of failing writes after which the metrics in
+// memory are spilled to disk, zero disables spilling on failures, and the
+// limit of the memory budget shared by all hybrid buffers.
+func (b *HybridBuffer) configure(spillAfter time.Duration, memoryLimit int64) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.spillAfter = spillAfter
+	b.budget.setLimit(memoryLimit)
+}
+
+func (b *HybridBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *HybridBuffer) length() int {
+	return len(b.memory) + b.inflight + b.diskLen()
+}
+
+// diskLen returns the number of metrics spilled to disk.
+func (b *HybridBuffer) diskLen() int {
+	first := b.readIndex()
+	if first == 0 {
+		return 0
+	}
+	return int(b.writeIndex() - first)
+}
+
+// readIndex is the index of the oldest metric on disk
+func (b *HybridBuffer) readIndex() uint64 {
+	index, err := b.file.FirstIndex()
+	if err != nil {
+		panic(err) // can only occur with a corrupt or closed wal file
+	}
+	return index
+}
+
+// writeIndex is the index to write the next metric on disk to
+func (b *HybridBuffer) writeIndex() uint64 {
+	index, err := b.file.LastIndex()
+	if err != nil {
+		panic(err) // can only occur with a corrupt or closed wal file
+	}
+	return index + 1
+}
+
+func (b *HybridBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	b.metricAdded(int64(len(metrics)))
+	b.spillIfDue()
+
+	// Keep the metrics in memory until the budget is exhausted unless there
+	// are metrics on disk which must be written first
+	var i int
+	if b.diskLen() == 0 {
+		for ; i < len(metrics); i++ {
+			size := int64(lineProtocolSize(metrics[i]))
+			if !b.budget.reserve(size) {
+				break
+			}
+			b.memory = append(b.memory, metrics[i])
+			b.sizes = append(b.sizes, size)
+			b.bytes += size
+		}
+	}
+
+	var dropped int
+	if i < len(metrics) {
+		written, err := b.writeToDisk(metrics[i:])
+		if err != nil {
+			log.Printf("E! Writing metrics to buffer %q failed: %v", b.path, err)
+			for _, m := range metrics[i+written:] {
+				b.metricOverflowed(m)
+				dropped++
+			}
+		}
+	}
+
+	b.updateStats()
+	return dropped
+}
+
+func (b *HybridBuffer) BeginTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	// Fill up the batch with the oldest metrics on disk regardless of the
+	// memory budget to not stall the output
+	if len(b.memory) < batchSize {
+		b.readFromDisk(batchSize-len(b.memory), true)
+	}
+	return b.beginTransaction(batchSize)
+}
+
+// beginMemoryTransaction starts a transaction with the metrics in memory
+// only, leaving the metrics spilled to disk untouched.
+func (b *HybridBuffer) beginMemoryTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.beginTransaction(batchSize)
+}
+
+func (b *HybridBuffer) beginTransaction(batchSize int) *Transaction {
+	n := min(len(b.memory), batchSize)
+	if n == 0 {
+		return &Transaction{}
+	}
+
+	batch := slices.Clone(b.memory[:n])
+	sizes := slices.Clone(b.sizes[:n])
+	clear(b.memory[:n])
+	b.memory = b.memory[n:]
+	b.sizes = b.sizes[n:]
+	b.inflight += n
+
+	b.updateStats()
+	return &Transaction{Batch: batch, valid: true, state: sizes}
+}
+
+func (b *HybridBuffer) EndTransaction(tx *Transaction) {
+	b.endTransaction(tx, true)
+}
+
+// endMemoryTransaction ends a transaction started by beginMemoryTransaction
+// without moving metrics from disk to memory.
+func (b *HybridBuffer) endMemoryTransaction(tx *Transaction) {
+	b.endTransaction(tx, false)
+}
+
+func (b *HybridBuffer) endTransaction(tx *Transaction, drain bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	// Ignore invalid transactions and make sure they can only be finished once
+	if !tx.valid {
+		return
+	}
+	tx.valid = false
+
+	sizes := tx.state.([]int64)
+	for _, idx := range tx.Accept {
+		b.metricAccepted(tx, tx.Batch[idx])
+		b.releaseSize(sizes[idx])
+	}
+	for _, idx := range tx.Reject {
+		b.metricRejected(tx.Batch[idx])
+		b.releaseSize(sizes[idx])
+	}
+
+	// Put the kept metrics back in front of the metrics in memory as they
+	// are older, their size is still reserved in the budget
+	keep := tx.InferKeep()
+	if len(keep) > 0 {
+		metrics := make([]telegraf.Metric, 0, len(keep))
+		metricSizes := make([]int64, 0, len(keep))
+		for _, idx := range keep {
+			metrics = append(metrics, tx.Batch[idx])
+			metricSizes = append(metricSizes, sizes[idx])
+		}
+		b.memory = slices.Insert(b.memory, 0, metrics...)
+		b.sizes = slices.Insert(b.sizes, 0, metricSizes...)
+	}
+	b.inflight = max(b.inflight-len(tx.Batch), 0)
+
+	// The output is considered unavailable as long as writes fail without
+	// accepting or rejecting any metric. Once it recovers, move the metrics
+	// spilled to disk back to memory as far as the budget permits.
+	if len(tx.Accept) == 0 && len(keep) > 0 {
+		if b.failingSince.IsZero() {
+			b.failingSince = time.Now()
+		}
+		b.spillIfDue()
+	} else {
+		b.failingSince = time.Time{}
+		if drain {
+			b.readFromDisk(len(tx.Batch), false)
+		}
+	}
+
+	b.updateStats()
+}
+
+func (b *HybridBuffer) Stats() BufferStats {
+	return b.BufferStats
+}
+
+// Close closes the file of the buffer and releases the memory budget taken
+// by the metrics in memory which are lost.
+func (b *HybridBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.budget.release(b.bytes)
+	b.bytes = 0
+	if err := b.file.Close(); err != nil {
+		return fmt.Errorf("closing buffer failed: %w", err)
+	}
+	return nil
+}
+
+func (b *HybridBuffer) releaseSize(size int64) {
+	b.budget.release(size)
+	b.bytes -= size
+}
+
+// spillIfDue moves the metrics in memory to disk once the output has been
+// failing for longer than the spill delay. This frees the memory budget for
+// other outputs and keeps the metrics across restarts. Metrics in unfinished
+// transactions and metrics in memory while there are metrics on disk, i.e.
+// the ones read back for writing, are not spilled to preserve the order.
+func (b *HybridBuffer) spillIfDue() {
+	if b.spillAfter <= 0 || b.failingSince.IsZero() || time.Since(b.failingSince) < b.spillAfter {
+		return
+	}
+	if len(b.memory) == 0 || b.diskLen() > 0 {
+		return
+	}
+
+	written, err := b.writeToDisk(b.memory)
+	if err != nil {
+		log.Printf("E! Spilling metrics to buffer %q failed: %v", b.path, err)
+	}
+	for _, size := range b.sizes[:written] {
+		b.releaseSize(size)
+	}
+	clear(b.memory[:written])
+	b.memory = b.memory[written:]
+	b.sizes = b.sizes[written:]
+}
+
+// writeToDisk appends the metrics to the file and returns the number of
+// metrics written.
+func (b *HybridBuffer) writeToDisk(metrics []telegraf.Metric) (int, error) {
+	var batch wal.Batch
+	start := b.writeIndex()
+	idx := start
+	for _, m := range metrics {
+		data, err := metric.ToBytes(m)
+		if err != nil {
+			panic(err)
+		}
+		batch.Write(idx, data)
+		idx++
+	}
+
+	if err := b.file.WriteBatch(&batch); err != nil {
+		// This calculation assumes a single writer to the WAL, which is
+		// guaranteed by the mutex and one WAL per buffer instance.
+		return int(b.writeIndex() - start), err
+	}
+	return len(metrics), nil
+}
+
+// readFromDisk moves up to count of the oldest metrics on disk to memory.
+// Unless forced, moving stops once the memory budget is exhausted.
+func (b *HybridBuffer) readFromDisk(count int, force bool) {
+	first := b.readIndex()
+	if first == 0 {
+		return
+	}
+	end := b.writeIndex()
+
+	index := first
+	for ; index < end && count > 0; index++ {
+		data, err := b.file.Read(index)
+		if err != nil {
+			panic(err)
+		}
+
+		// Skip tracking metrics left over from a previous instance, see
+		// DiskBuffer.BeginTransaction for details
+		m, err := metric.FromBytes(data)
+		if err != nil {
+			if !errors.Is(err, metric.ErrSkipTracking) {
+				log.Printf("E! Dropping undecodable metric from buffer %q: %v", b.path, err)
+			}
+			continue
+		}
+		if _, ok := m.(telegraf.TrackingMetric); ok && index < b.originalEnd {
+			continue
+		}
+
+		size := int64(lineProtocolSize(m))
+		if force {
+			b.budget.force(size)
+		} else if !b.budget.reserve(size) {
+			break
+		}
+		b.memory = append(b.memory, m)
+		b.sizes = append(b.sizes, size)
+		b.bytes += size
+		count--
+	}
+
+	if index == first {
+		return
+	}
+	if err := b.file.TruncateFront(index); err != nil {
+		log.Printf("E! first: %d, removing up to: %d", first, index)
+		panic(err)
+	}
+
+	// check if the original end index is still valid, clear if not
+	if b.originalEnd <= index {
+		b.originalEnd = 0
+	}
+}
+
+func (b *HybridBuffer) updateStats() {
+	b.BufferSize.Set(int64(b.length()))
+	b.byteStat.Set(b.bytes)
+	b.diskStat.Set(int64(b.diskLen()))
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestHybridBufferSpillOnBudget(t *testing.T) {
+	metrics := hybridTestMetrics(5)
+	size := int64(lineProtocolSize(metrics[0]))
+	buf := newTestHybridBuffer(t, "spill", t.TempDir(), &memoryBudget{})
+	defer buf.Close()
+	buf.budget.setLimit(3 * size)
+
+	// Metrics exceeding the budget are spilled to disk without dropping
+	require.Zero(t, buf.Add(metrics...))
+	require.Len(t, buf.memory, 3)
+	require.Equal(t, 2, buf.diskLen())
+	require.Equal(t, 5, buf.Len())
+	require.Equal(t, 3*size, buf.budget.used.Load())
+
+	// New metrics go to disk as long as there are metrics on disk
+	tx := buf.BeginTransaction(1)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	extra := hybridTestMetrics(6)[5]
+	buf.Add(extra)
+	require.Equal(t, 2, buf.diskLen())
+
+	// The order of the metrics is preserved across memory and disk
+	tx = buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, append(metrics[1:], extra), tx.Batch)
+	require.Zero(t, buf.diskLen())
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Len())
+	require.Zero(t, buf.budget.used.Load())
+}
+
+func TestHybridBufferSpillOnFailure(t *testing.T) {
+	metrics := hybridTestMetrics(6)
+	buf := newTestHybridBuffer(t, "failure", t.TempDir(), &memoryBudget{})
+	defer buf.Close()
+	buf.configure(time.Minute, 0)
+
+	buf.Add(metrics[:4]...)
+	require.Zero(t, buf.diskLen())
+
+	// Failing writes keep the metrics in memory until the spill delay passed
+	tx := buf.BeginTransaction(2)
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.diskLen())
+	require.False(t, buf.failingSince.IsZero())
+
+	buf.failingSince = time.Now().Add(-time.Hour)
+	tx = buf.BeginTransaction(2)
+	buf.EndTransaction(tx)
+	require.Empty(t, buf.memory)
+	require.Equal(t, 4, buf.diskLen())
+	require.Zero(t, buf.budget.used.Load())
+
+	// Metrics added while the output is unavailable go to disk
+	buf.Add(metrics[4])
+	require.Equal(t, 5, buf.diskLen())
+
+	// The metrics are moved back to memory once the output recovered
+	tx = buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, metrics[:2], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.True(t, buf.failingSince.IsZero())
+	require.Len(t, buf.memory, 2)
+	require.Equal(t, 1, buf.diskLen())
+
+	tx = buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, metrics[2:5], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.diskLen())
+
+	// Without metrics on disk new metrics are kept in memory again
+	buf.Add(metrics[5])
+	require.Len(t, buf.memory, 1)
+	require.Zero(t, buf.diskLen())
+}
+
+func TestHybridBufferSharedBudget(t *testing.T) {
+	metrics := hybridTestMetrics(3)
+	budget := &memoryBudget{}
+	budget.setLimit(2 * int64(lineProtocolSize(metrics[0])))
+
+	first := newTestHybridBuffer(t, "first", t.TempDir(), budget)
+	defer first.Close()
+	second := newTestHybridBuffer(t, "second", t.TempDir(), budget)
+	defer second.Close()
+
+	// The buffers share the memory budget
+	first.Add(metrics[:2]...)
+	second.Add(metrics[2])
+	require.Len(t, first.memory, 2)
+	require.Empty(t, second.memory)
+	require.Equal(t, 1, second.diskLen())
+
+	// Writing the metrics of one buffer frees the budget for the others
+	tx := first.BeginTransaction(2)
+	tx.AcceptAll()
+	first.EndTransaction(tx)
+
+	tx = second.BeginTransaction(2)
+	testutil.RequireMetricsEqual(t, metrics[2:], tx.Batch)
+	tx.AcceptAll()
+	second.EndTransaction(tx)
+	second.Add(metrics[:2]...)
+	require.Len(t, second.memory, 2)
+	require.Zero(t, second.diskLen())
+
+	// Closing a buffer releases its share of the budget
+	require.NoError(t, second.Close())
+	require.Zero(t, budget.used.Load())
+}
+
+func TestHybridBufferRestart(t *testing.T) {
+	path := t.TempDir()
+	metrics := hybridTestMetrics(3)
+	buf := newTestHybridBuffer(t, "restart", path, &memoryBudget{})
+	buf.budget.setLimit(int64(lineProtocolSize(metrics[0])))
+
+	// Metrics spilled to disk are kept across restarts, the ones in memory
+	// are lost
+	buf.Add(metrics...)
+	require.NoError(t, buf.Close())
+
+	buf = newTestHybridBuffer(t, "restart", path, &memoryBudget{})
+	defer buf.Close()
+	require.Equal(t, 2, buf.Len())
+	tx := buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(t, metrics[1:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Len())
+}
+
+func newTestHybridBuffer(t *testing.T, id, path string, budget *memoryBudget) *HybridBuffer {
+	t.Helper()
+	buf, err := NewBuffer("test", id, "", 0, "hybrid", path, true)
+	require.NoError(t, err)
+	h, ok := buf.(*HybridBuffer)
+	require.True(t, ok, "buffer is not a hybrid buffer")
+	h.budget = budget
+	return h
+}
+
+// hybridTestMetrics returns metrics of equal estimated size
+func hybridTestMetrics(n int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := range n {
+		metrics = append(metrics, metric.New("test", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(1700000000+int64(i), 0)))
+	}
+	return metrics
+}
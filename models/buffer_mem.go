@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 // MemoryBuffer stores metrics in a circular buffer.
@@ -17,8 +18,14 @@ type MemoryBuffer struct {
 	size  int // number of metrics currently in the buffer
 	cap   int // the capacity of the buffer
 
-	batchFirst int // index of the first metric in the batch
-	batchSize  int // number of metrics currently in the batch
+	inflight int // number of metrics in unfinished transactions
+
+	// Estimated sizes of the metrics in bytes if a sizer is set
+	sizeOf    func(telegraf.Metric) int
+	sizes     []int
+	bytes     int64 // estimated size of the metrics in the buffer
+	byteLimit int64 // maximum size of the metrics in the buffer
+	byteStat  selfstat.Stat
 }
 
 func NewMemoryBuffer(capacity int, stats BufferStats) (*MemoryBuffer, error) {
@@ -48,9 +55,32 @@ func (b *MemoryBuffer) Add(metrics ...telegraf.Metric) int {
 	}
 
 	b.BufferSize.Set(int64(b.length()))
+	b.updateByteStat()
 	return dropped
 }
 
+// setSizer enables tracking the estimated size of the buffered metrics in
+// bytes. If the limit is positive, the oldest metrics are dropped as soon as
+// the buffered metrics exceed the limit.
+func (b *MemoryBuffer) setSizer(sizeOf func(telegraf.Metric) int, limit int64, stat selfstat.Stat) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.sizeOf = sizeOf
+	b.sizes = make([]int, b.cap)
+	b.byteLimit = limit
+	b.byteStat = stat
+	b.byteStat.Set(0)
+}
+
+// byteLen returns the estimated size of the buffered metrics in bytes.
+func (b *MemoryBuffer) byteLen() int64 {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.bytes
+}
+
 func (b *MemoryBuffer) BeginTransaction(batchSize int) *Transaction {
 	b.Lock()
 	defer b.Unlock()
@@ -60,19 +90,28 @@ func (b *MemoryBuffer) BeginTransaction(batchSize int) *Transaction {
 		return &Transaction{}
 	}
 
-	b.batchFirst = b.first
-	b.batchSize = outLen
-	batchIndex := b.batchFirst
+	b.inflight += outLen
+	batchIndex := b.first
 	batch := make([]telegraf.Metric, outLen)
+	var sizes []int
+	if b.sizeOf != nil {
+		sizes = make([]int, outLen)
+	}
 	for i := range batch {
 		batch[i] = b.buf[batchIndex]
 		b.buf[batchIndex] = nil
+		if sizes != nil {
+			sizes[i] = b.sizes[batchIndex]
+			b.bytes -= int64(sizes[i])
+			b.sizes[batchIndex] = 0
+		}
 		batchIndex = b.next(batchIndex)
 	}
 
-	b.first = b.nextby(b.first, b.batchSize)
+	b.first = b.nextby(b.first, outLen)
 	b.size -= outLen
-	return &Transaction{Batch: batch, valid: true}
+	b.updateByteStat()
+	return &Transaction{Batch: batch, valid: true, state: sizes}
 }
 
 func (b *MemoryBuffer) EndTransaction(tx *Transaction) {
@@ -87,7 +126,7 @@ func (b *MemoryBuffer) EndTransaction(tx *Transaction) {
 
 	// Accept metrics
 	for _, idx := range tx.Accept {
-		b.metricWritten(tx.Batch[idx])
+		b.metricAccepted(tx, tx.Batch[idx])
 	}
 
 	// Reject metrics
@@ -95,7 +134,10 @@ func (b *MemoryBuffer) EndTransaction(tx *Transaction) {
 		b.metricRejected(tx.Batch[idx])
 	}
 
-	// Keep metrics
+	// Keep metrics by putting them back in front of the buffer. Concurrent
+	// transactions ending in a different order than they began thus reorder
+	// the kept metrics, outputs rely on strict series ordering to prevent
+	// reordering the metrics of a series.
 	keep := tx.InferKeep()
 	if len(keep) > 0 {
 		restore := min(len(keep), b.cap-b.size)
@@ -104,19 +146,25 @@ func (b *MemoryBuffer) EndTransaction(tx *Transaction) {
 
 		// Restore the metrics that fit into the buffer
 		current := b.first
+		sizes, _ := tx.state.([]int)
 		for i := 0; i < restore; i++ {
 			b.buf[current] = tx.Batch[keep[i]]
+			if b.sizeOf != nil && sizes != nil {
+				b.sizes[current] = sizes[keep[i]]
+				b.bytes += int64(sizes[keep[i]])
+			}
 			current = b.next(current)
 		}
 
 		// Drop all remaining metrics
 		for i := restore; i < len(keep); i++ {
-			b.metricDropped(tx.Batch[keep[i]])
+			b.metricOverflowed(tx.Batch[keep[i]])
 		}
 	}
 
-	b.resetBatch()
+	b.inflight = max(b.inflight-len(tx.Batch), 0)
 	b.BufferSize.Set(int64(b.length()))
+	b.updateByteStat()
 }
 
 func (*MemoryBuffer) Close() error {
@@ -128,25 +176,31 @@ func (b *MemoryBuffer) Stats() BufferStats {
 }
 
 func (b *MemoryBuffer) length() int {
-	return min(b.size+b.batchSize, b.cap)
+	return min(b.size+b.inflight, b.cap)
 }
 
 func (b *MemoryBuffer) addMetric(m telegraf.Metric) int {
 	dropped := 0
 	// Check if Buffer is full
 	if b.size == b.cap {
-		b.metricDropped(b.buf[b.last])
+		b.metricOverflowed(b.buf[b.last])
 		dropped++
+		if b.sizeOf != nil {
+			b.bytes -= int64(b.sizes[b.last])
+		}
 
-		if b.batchSize > 0 {
-			b.batchSize--
-			b.batchFirst = b.next(b.batchFirst)
+		if b.inflight > 0 {
+			b.inflight--
 		}
 	}
 
 	b.metricAdded(1)
 
 	b.buf[b.last] = m
+	if b.sizeOf != nil {
+		b.sizes[b.last] = b.sizeOf(m)
+		b.bytes += int64(b.sizes[b.last])
+	}
 	b.last = b.next(b.last)
 
 	if b.size == b.cap {
@@ -154,9 +208,27 @@ func (b *MemoryBuffer) addMetric(m telegraf.Metric) int {
 	}
 
 	b.size = min(b.size+1, b.cap)
+
+	// Drop the oldest metrics exceeding the byte limit but keep at least the
+	// newest metric
+	for b.byteLimit > 0 && b.bytes > b.byteLimit && b.size > 1 {
+		b.metricOverflowed(b.buf[b.first])
+		dropped++
+		b.bytes -= int64(b.sizes[b.first])
+		b.buf[b.first] = nil
+		b.sizes[b.first] = 0
+		b.first = b.next(b.first)
+		b.size--
+	}
 	return dropped
 }
 
+func (b *MemoryBuffer) updateByteStat() {
+	if b.sizeOf != nil {
+		b.byteStat.Set(b.bytes)
+	}
+}
+
 // next returns the next index with wrapping.
 func (b *MemoryBuffer) next(index int) int {
 	index++
@@ -183,8 +255,3 @@ func (b *MemoryBuffer) prevby(index, count int) int {
 	index %= b.cap
 	return index
 }
-
-func (b *MemoryBuffer) resetBatch() {
-	b.batchFirst = 0
-	b.batchSize = 0
-}
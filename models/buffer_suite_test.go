@@ -24,7 +24,7 @@ func (s *BufferSuiteTest) SetupTest() {
 	switch s.bufferType {
 	case "", "memory":
 		s.hasMaxCapacity = true
-	case "disk_write_through":
+	case "disk_write_through", "hybrid":
 		path, err := os.MkdirTemp("", "*-buffer-test")
 		s.Require().NoError(err)
 		s.bufferPath = path
@@ -47,6 +47,10 @@ func TestDiskBufferSuite(t *testing.T) {
 	suite.Run(t, &BufferSuiteTest{bufferType: "disk_write_through"})
 }
 
+func TestHybridBufferSuite(t *testing.T) {
+	suite.Run(t, &BufferSuiteTest{bufferType: "hybrid"})
+}
+
 func (s *BufferSuiteTest) newTestBuffer(capacity int) Buffer {
 	s.T().Helper()
 	buf, err := NewBuffer("test", "123", "", capacity, s.bufferType, s.bufferPath, true)
@@ -934,6 +938,36 @@ func (s *BufferSuiteTest) TestPartialWriteBackToFront() {
 	s.Equal(int64(0), buf.Stats().MetricsDropped.Get(), "metrics dropped")
 }
 
+func (s *BufferSuiteTest) TestBufferConcurrentTransactions() {
+	buf := s.newTestBuffer(10)
+	defer buf.Close()
+
+	metrics := make([]telegraf.Metric, 0, 6)
+	for i := range 6 {
+		m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(int64(i), 0))
+		metrics = append(metrics, m)
+		buf.Add(m)
+	}
+
+	// Concurrent transactions must not share metrics
+	tx1 := buf.BeginTransaction(2)
+	tx2 := buf.BeginTransaction(2)
+	testutil.RequireMetricsEqual(s.T(), metrics[0:2], tx1.Batch)
+	testutil.RequireMetricsEqual(s.T(), metrics[2:4], tx2.Batch)
+	s.Equal(6, buf.Len())
+
+	// Finish the transactions in reverse order keeping the first batch
+	tx2.AcceptAll()
+	buf.EndTransaction(tx2)
+	tx1.KeepAll()
+	buf.EndTransaction(tx1)
+	s.Equal(4, buf.Len())
+
+	tx := buf.BeginTransaction(10)
+	testutil.RequireMetricsEqual(s.T(), append(metrics[0:2:2], metrics[4:]...), tx.Batch)
+	s.Equal(int64(2), buf.Stats().MetricsWritten.Get())
+}
+
 type mockMetric struct {
 	telegraf.Metric
 	AcceptF func()
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DefaultCardinalityWindow is the time window distinct series are counted
+	// in if not configured otherwise.
+	DefaultCardinalityWindow = time.Hour
+
+	// DefaultCardinalitySampleRatio is the ratio of new series accepted after
+	// exceeding the series limit with the "sample" action if not configured
+	// otherwise.
+	DefaultCardinalitySampleRatio = 0.1
+)
+
+// CheckCardinalityAction checks if the given action for series exceeding the
+// cardinality limit is valid.
+func CheckCardinalityAction(action string) error {
+	switch action {
+	case "", "drop", "sample", "strip_tag":
+		return nil
+	}
+	return fmt.Errorf("invalid cardinality action %q", action)
+}
+
+// cardinalityGuard limits the number of distinct series of each measurement
+// of an input seen within a sliding time window. Metrics of known series
+// always pass. Once the limit is reached, metrics of new series are dropped,
+// sampled by their series or stripped of the tag with the most distinct
+// values of the measurement.
+type cardinalityGuard struct {
+	limit  int
+	window time.Duration
+	action string
+	ratio  float64
+
+	log  telegraf.Logger
+	tags map[string]string
+
+	sync.Mutex
+	measurements map[string]*seriesSet
+	lastSweep    time.Time
+}
+
+// seriesSet holds the distinct series of a measurement and the distinct
+// values of its tags with the time they were last seen.
+type seriesSet struct {
+	series    map[uint64]time.Time
+	tagValues map[string]map[string]time.Time
+	exceeded  bool
+
+	seriesStat     selfstat.Stat
+	violationsStat selfstat.Stat
+	droppedStat    selfstat.Stat
+	strippedStat   selfstat.Stat
+}
+
+func newCardinalityGuard(config *InputConfig, log telegraf.Logger, tags map[string]string) *cardinalityGuard {
+	g := &cardinalityGuard{
+		limit:        config.CardinalityLimit,
+		window:       config.CardinalityWindow,
+		action:       config.CardinalityAction,
+		ratio:        config.CardinalitySampleRatio,
+		log:          log,
+		tags:         tags,
+		measurements: make(map[string]*seriesSet),
+		lastSweep:    time.Now(),
+	}
+	if g.window <= 0 {
+		g.window = DefaultCardinalityWindow
+	}
+	if g.action == "" {
+		g.action = "drop"
+	}
+	if g.ratio <= 0 || g.ratio > 1 {
+		g.ratio = DefaultCardinalitySampleRatio
+	}
+	return g
+}
+
+// check checks the series of the metric at the given time, potentially
+// removing a tag of the metric, and returns false if the metric must be
+// dropped.
+func (g *cardinalityGuard) check(m telegraf.Metric, now time.Time) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	if now.Sub(g.lastSweep) >= g.window/10 {
+		g.sweep(now)
+	}
+
+	s := g.measurements[m.Name()]
+	if s == nil {
+		s = g.newSeriesSet(m.Name())
+		g.measurements[m.Name()] = s
+	}
+	if g.action == "strip_tag" {
+		s.trackTags(m, now, g.limit)
+	}
+
+	id := m.HashID()
+	if g.admit(s, id, now) {
+		return true
+	}
+
+	s.violationsStat.Incr(1)
+	if !s.exceeded {
+		s.exceeded = true
+		g.log.Warnf("Series limit of %d exceeded for measurement %q within %s; applying action %q to new series",
+			g.limit, m.Name(), g.window, g.action)
+	}
+
+	switch g.action {
+	case "sample":
+		if sampled(id, g.ratio) {
+			s.add(id, now)
+			return true
+		}
+	case "strip_tag":
+		if key := s.offendingTag(m); key != "" {
+			m.RemoveTag(key)
+			if g.admit(s, m.HashID(), now) {
+				s.strippedStat.Incr(1)
+				return true
+			}
+		}
+	}
+
+	s.droppedStat.Incr(1)
+	return false
+}
+
+// admit adds the series if it is known or if the limit is not yet reached.
+func (g *cardinalityGuard) admit(s *seriesSet, id uint64, now time.Time) bool {
+	if _, found := s.series[id]; !found && len(s.series) >= g.limit {
+		return false
+	}
+	s.add(id, now)
+	return true
+}
+
+// sweep forgets the series and tag values not seen within the window.
+func (g *cardinalityGuard) sweep(now time.Time) {
+	g.lastSweep = now
+	expired := now.Add(-g.window)
+	for name, s := range g.measurements {
+		maps.DeleteFunc(s.series, func(_ uint64, seen time.Time) bool { return seen.Before(expired) })
+		for key, values := range s.tagValues {
+			maps.DeleteFunc(values, func(_ string, seen time.Time) bool { return seen.Before(expired) })
+			if len(values) == 0 {
+				delete(s.tagValues, key)
+			}
+		}
+		s.seriesStat.Set(int64(len(s.series)))
+
+		if len(s.series) < g.limit {
+			s.exceeded = false
+		}
+		if len(s.series) == 0 {
+			s.unregister()
+			delete(g.measurements, name)
+		}
+	}
+}
+
+func (g *cardinalityGuard) newSeriesSet(name string) *seriesSet {
+	tags := maps.Clone(g.tags)
+	tags["measurement"] = name
+	return &seriesSet{
+		series:         make(map[uint64]time.Time),
+		tagValues:      make(map[string]map[string]time.Time),
+		seriesStat:     selfstat.Register("cardinality", "series", tags),
+		violationsStat: selfstat.Register("cardinality", "violations", tags),
+		droppedStat:    selfstat.Register("cardinality", "metrics_dropped", tags),
+		strippedStat:   selfstat.Register("cardinality", "metrics_stripped", tags),
+	}
+}
+
+func (s *seriesSet) add(id uint64, now time.Time) {
+	s.series[id] = now
+	s.seriesStat.Set(int64(len(s.series)))
+}
+
+// trackTags records the tag values of the metric. The number of values per
+// tag is capped at the limit as it is only used for finding the tag with the
+// most values.
+func (s *seriesSet) trackTags(m telegraf.Metric, now time.Time, limit int) {
+	for _, tag := range m.TagList() {
+		values := s.tagValues[tag.Key]
+		if values == nil {
+			values = make(map[string]time.Time)
+			s.tagValues[tag.Key] = values
+		}
+		if _, found := values[tag.Value]; found || len(values) < limit {
+			values[tag.Value] = now
+		}
+	}
+}
+
+// offendingTag returns the tag of the metric with the most distinct values
+// within the measurement.
+func (s *seriesSet) offendingTag(m telegraf.Metric) string {
+	var key string
+	var count int
+	for _, tag := range m.TagList() {
+		if n := len(s.tagValues[tag.Key]); n > count {
+			key, count = tag.Key, n
+		}
+	}
+	return key
+}
+
+func (s *seriesSet) unregister() {
+	s.seriesStat.Unregister()
+	s.violationsStat.Unregister()
+	s.droppedStat.Unregister()
+	s.strippedStat.Unregister()
+}
+
+// sampled deterministically selects the given ratio of series so the same
+// series are accepted on each occurrence.
+func sampled(id uint64, ratio float64) bool {
+	// Mix the bits of the hash to get an evenly distributed value
+	id *= 0x9e3779b97f4a7c15
+	return float64(id>>11)/(1<<53) < ratio
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func requestMetric(name string, request int) telegraf.Metric {
+	return metric.New(
+		name,
+		map[string]string{"host": "a", "request_id": fmt.Sprintf("req-%d", request)},
+		map[string]interface{}{"value": 1},
+		time.Unix(0, 0),
+	)
+}
+
+// unregisterCardinalityStats removes the statistics of the guard once the
+// test finished so the counts do not add up over repeated runs.
+func unregisterCardinalityStats(t *testing.T, g *cardinalityGuard) {
+	t.Cleanup(func() {
+		for name := range g.measurements {
+			tags := maps.Clone(g.tags)
+			tags["measurement"] = name
+			for _, field := range []string{"series", "violations", "metrics_dropped", "metrics_stripped"} {
+				selfstat.Unregister("cardinality", field, tags)
+			}
+		}
+	})
+}
+
+func TestCardinalityGuardDrop(t *testing.T) {
+	g := newCardinalityGuard(&InputConfig{CardinalityLimit: 3}, testutil.Logger{}, map[string]string{"_id": "drop"})
+	unregisterCardinalityStats(t, g)
+
+	now := time.Now()
+	for i := range 3 {
+		require.True(t, g.check(requestMetric("http", i), now))
+	}
+
+	// New series exceeding the limit are dropped while known series and
+	// other measurements pass
+	require.False(t, g.check(requestMetric("http", 3), now))
+	require.True(t, g.check(requestMetric("http", 1), now))
+	require.True(t, g.check(requestMetric("cpu", 3), now))
+
+	s := g.measurements["http"]
+	require.Equal(t, int64(3), s.seriesStat.Get())
+	require.Equal(t, int64(1), s.violationsStat.Get())
+	require.Equal(t, int64(1), s.droppedStat.Get())
+	require.Equal(t, int64(1), g.measurements["cpu"].seriesStat.Get())
+}
+
+func TestCardinalityGuardWindow(t *testing.T) {
+	g := newCardinalityGuard(&InputConfig{
+		CardinalityLimit:  2,
+		CardinalityWindow: time.Minute,
+	}, testutil.Logger{}, map[string]string{"_id": "window"})
+	unregisterCardinalityStats(t, g)
+
+	now := time.Now()
+	require.True(t, g.check(requestMetric("http", 0), now))
+	require.True(t, g.check(requestMetric("http", 1), now.Add(30*time.Second)))
+	require.False(t, g.check(requestMetric("http", 2), now.Add(30*time.Second)))
+
+	// Series not seen within the window are forgotten
+	require.True(t, g.check(requestMetric("http", 2), now.Add(70*time.Second)))
+	require.Equal(t, int64(2), g.measurements["http"].seriesStat.Get())
+	require.False(t, g.check(requestMetric("http", 0), now.Add(70*time.Second)))
+}
+
+func TestCardinalityGuardSample(t *testing.T) {
+	g := newCardinalityGuard(&InputConfig{
+		CardinalityLimit:       10,
+		CardinalityAction:      "sample",
+		CardinalitySampleRatio: 0.5,
+	}, testutil.Logger{}, map[string]string{"_id": "sample"})
+	unregisterCardinalityStats(t, g)
+
+	now := time.Now()
+	accepted := make(map[int]bool)
+	for i := range 1000 {
+		accepted[i] = g.check(requestMetric("http", i), now)
+	}
+
+	// About half of the series exceeding the limit are accepted and the
+	// decision is the same on each occurrence of a series
+	s := g.measurements["http"]
+	require.InDelta(t, 505, s.seriesStat.Get(), 50)
+	require.Equal(t, 1000-s.seriesStat.Get(), s.droppedStat.Get())
+	for i := range 1000 {
+		require.Equal(t, accepted[i], g.check(requestMetric("http", i), now))
+	}
+}
+
+func TestCardinalityGuardStripTag(t *testing.T) {
+	g := newCardinalityGuard(&InputConfig{
+		CardinalityLimit:  3,
+		CardinalityAction: "strip_tag",
+	}, testutil.Logger{}, map[string]string{"_id": "strip_tag"})
+	unregisterCardinalityStats(t, g)
+
+	now := time.Now()
+	for i := range 2 {
+		require.True(t, g.check(requestMetric("http", i), now))
+	}
+	m := requestMetric("http", 0)
+	m.RemoveTag("request_id")
+	require.True(t, g.check(m, now))
+
+	// The tag with the most values is removed from metrics of new series
+	m = requestMetric("http", 2)
+	require.True(t, g.check(m, now))
+	require.Equal(t, map[string]string{"host": "a"}, m.Tags())
+
+	// Metrics still exceeding the limit after stripping the tag are dropped
+	m = requestMetric("http", 3)
+	m.AddTag("host", "b")
+	require.False(t, g.check(m, now))
+
+	s := g.measurements["http"]
+	require.Equal(t, int64(3), s.seriesStat.Get())
+	require.Equal(t, int64(1), s.strippedStat.Get())
+	require.Equal(t, int64(1), s.droppedStat.Get())
+}
+
+func TestRunningInputCardinalityLimit(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name:             "cardinality",
+		CardinalityLimit: 1,
+	})
+
+	require.NotNil(t, ri.MakeMetric(requestMetric("http", 0)))
+	require.Nil(t, ri.MakeMetric(requestMetric("http", 1)))
+	require.NotNil(t, ri.MakeMetric(requestMetric("http", 0)))
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DefaultRetryMaxInterval is the maximum backoff between retries of
+	// failed writes if not configured otherwise.
+	DefaultRetryMaxInterval = time.Minute
+
+	// DefaultRetryMultiplier is the factor the backoff is increased by on
+	// each consecutive failed write if not configured otherwise.
+	DefaultRetryMultiplier = 2.0
+
+	// DefaultCircuitBreakerTimeout is the time the circuit breaker stays open
+	// before probing the output if not configured otherwise.
+	DefaultCircuitBreakerTimeout = 30 * time.Second
+)
+
+var (
+	// ErrRetryBackoff is returned for writes skipped during the backoff after
+	// a failed write.
+	ErrRetryBackoff = errors.New("write skipped during retry backoff")
+
+	// ErrCircuitOpen is returned for writes skipped while the circuit breaker
+	// of the output is open.
+	ErrCircuitOpen = errors.New("circuit breaker open")
+)
+
+// States of the circuit breaker as reported by the selfstat metric
+const (
+	circuitClosed   = 0
+	circuitHalfOpen = 1
+	circuitOpen     = 2
+)
+
+var circuitStateNames = map[int]string{
+	circuitClosed:   "closed",
+	circuitHalfOpen: "half-open",
+	circuitOpen:     "open",
+}
+
+// circuitBreaker guards the writes of an output. After a failed write, the
+// next write is delayed by an exponentially increasing backoff with jitter.
+// After the configured number of consecutive failures the breaker opens and
+// skips all writes until the timeout elapsed. Afterwards the breaker is
+// half-open and lets a single probe write through, closing the breaker on
+// success or opening it again on failure.
+type circuitBreaker struct {
+	initial     time.Duration
+	maxInterval time.Duration
+	multiplier  float64
+	maxFailures int
+	timeout     time.Duration
+
+	log telegraf.Logger
+
+	sync.Mutex
+	state    int
+	probing  bool
+	failures int
+	backoff  time.Duration
+	next     time.Time
+
+	stateStat       selfstat.Stat
+	transitionStats map[int]selfstat.Stat
+	failuresStat    selfstat.Stat
+	skippedStat     selfstat.Stat
+}
+
+func newCircuitBreaker(config *OutputConfig, log telegraf.Logger, tags map[string]string) *circuitBreaker {
+	b := &circuitBreaker{
+		initial:     config.RetryInitialInterval,
+		maxInterval: config.RetryMaxInterval,
+		multiplier:  config.RetryMultiplier,
+		maxFailures: config.CircuitBreakerFailures,
+		timeout:     config.CircuitBreakerTimeout,
+		log:         log,
+		stateStat:   selfstat.Register("circuit_breaker", "state", tags),
+		transitionStats: map[int]selfstat.Stat{
+			circuitClosed:   selfstat.Register("circuit_breaker", "closed", tags),
+			circuitHalfOpen: selfstat.Register("circuit_breaker", "half_opened", tags),
+			circuitOpen:     selfstat.Register("circuit_breaker", "opened", tags),
+		},
+		failuresStat: selfstat.Register("circuit_breaker", "consecutive_failures", tags),
+		skippedStat:  selfstat.Register("circuit_breaker", "writes_skipped", tags),
+	}
+	if b.maxInterval <= 0 {
+		b.maxInterval = DefaultRetryMaxInterval
+	}
+	if b.multiplier < 1 {
+		b.multiplier = DefaultRetryMultiplier
+	}
+	if b.timeout <= 0 {
+		b.timeout = DefaultCircuitBreakerTimeout
+	}
+	b.stateStat.Set(circuitClosed)
+	return b
+}
+
+// allow checks if a write may be done at the given time and returns the
+// reason for skipping the write otherwise.
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if now.Before(b.next) {
+			b.skippedStat.Incr(1)
+			return ErrCircuitOpen
+		}
+		b.transition(circuitHalfOpen)
+		b.probing = true
+		return nil
+	case circuitHalfOpen:
+		if b.probing {
+			b.skippedStat.Incr(1)
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+
+	if now.Before(b.next) {
+		b.skippedStat.Incr(1)
+		return ErrRetryBackoff
+	}
+	return nil
+}
+
+// record records the result of a write at the given time. Writes accepting
+// at least some metrics close the breaker and reset the backoff while failed
+// writes schedule the next attempt. Empty writes are not considered a
+// successful probe of the output.
+func (b *circuitBreaker) record(n int, err error, now time.Time) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.probing = false
+	switch {
+	case err != nil && n == 0:
+		b.failure(now)
+	case n > 0 || b.state == circuitClosed:
+		b.failures = 0
+		b.backoff = 0
+		b.next = time.Time{}
+		b.failuresStat.Set(0)
+		if b.state != circuitClosed {
+			b.transition(circuitClosed)
+		}
+	}
+}
+
+func (b *circuitBreaker) failure(now time.Time) {
+	b.failures++
+	b.failuresStat.Set(int64(b.failures))
+
+	if b.state == circuitHalfOpen || (b.maxFailures > 0 && b.failures >= b.maxFailures) {
+		b.transition(circuitOpen)
+		b.next = now.Add(b.timeout)
+		return
+	}
+
+	if b.initial <= 0 {
+		return
+	}
+	if b.backoff == 0 {
+		b.backoff = b.initial
+	} else {
+		b.backoff = min(time.Duration(float64(b.backoff)*b.multiplier), b.maxInterval)
+	}
+
+	// Wait between half and the full backoff to spread the retries of
+	// multiple instances
+	wait := b.backoff/2 + rand.N(b.backoff/2+1)
+	b.next = now.Add(wait)
+	b.log.Debugf("Write failed %d times in a row; retrying in %s", b.failures, wait)
+}
+
+// nextAttempt returns the time of the next write attempt after a failure or
+// the zero time if writes are not delayed.
+func (b *circuitBreaker) nextAttempt() time.Time {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.state == circuitHalfOpen {
+		return time.Time{}
+	}
+	return b.next
+}
+
+func (b *circuitBreaker) transition(state int) {
+	switch state {
+	case circuitOpen:
+		b.log.Warnf("Circuit breaker opened after %d consecutive failed writes; probing again in %s", b.failures, b.timeout)
+	case circuitHalfOpen:
+		b.log.Infof("Circuit breaker half-open; probing output")
+	case circuitClosed:
+		b.log.Infof("Circuit breaker closed after successful write")
+	}
+	b.log.Debugf("Circuit breaker state changed from %s to %s", circuitStateNames[b.state], circuitStateNames[state])
+
+	b.state = state
+	b.stateStat.Set(int64(state))
+	b.transitionStats[state].Incr(1)
+}
+
+// guardWrite calls the given write function if allowed by the circuit breaker
+// and records the result.
+func (r *RunningOutput) guardWrite(write func() (int, error)) func() (int, error) {
+	if r.breaker == nil {
+		return write
+	}
+
+	return func() (int, error) {
+		if err := r.breaker.allow(time.Now()); err != nil {
+			// Make sure we do not block the batch-ready trigger
+			r.writeInFlight.Store(false)
+			return 0, err
+		}
+
+		n, err := write()
+		r.breaker.record(n, err, time.Now())
+		return n, err
+	}
+}
+
+// NextRetry returns the time the output should be retried after failed
+// writes or the zero time if the output is written on the regular flush
+// interval.
+func (r *RunningOutput) NextRetry() time.Time {
+	if r.breaker == nil {
+		return time.Time{}
+	}
+	return r.breaker.nextAttempt()
+}
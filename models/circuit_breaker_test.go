@@ -0,0 +1,146 @@
+package models
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// unregisterCircuitBreakerStats removes the statistics of the breaker with the
+// given tags once the test finished so the counts do not add up over repeated
+// runs.
+func unregisterCircuitBreakerStats(t *testing.T, tags map[string]string) {
+	t.Cleanup(func() {
+		for _, field := range []string{"state", "closed", "half_opened", "opened", "consecutive_failures", "writes_skipped"} {
+			selfstat.Unregister("circuit_breaker", field, tags)
+		}
+	})
+}
+
+func TestCircuitBreakerBackoff(t *testing.T) {
+	b := newCircuitBreaker(&OutputConfig{
+		RetryInitialInterval: time.Second,
+		RetryMaxInterval:     3 * time.Second,
+	}, testutil.Logger{}, map[string]string{"_id": "backoff"})
+	unregisterCircuitBreakerStats(t, map[string]string{"_id": "backoff"})
+
+	now := time.Now()
+	require.NoError(t, b.allow(now))
+	require.True(t, b.nextAttempt().IsZero())
+
+	// The backoff doubles on each failure up to the maximum with the actual
+	// wait being between half and the full backoff
+	errFailed := errors.New("failed")
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		b.record(0, errFailed, now)
+		next := b.nextAttempt()
+		require.GreaterOrEqual(t, next.Sub(now), backoff/2)
+		require.LessOrEqual(t, next.Sub(now), backoff)
+		require.ErrorIs(t, b.allow(next.Add(-time.Millisecond)), ErrRetryBackoff)
+		require.NoError(t, b.allow(next))
+		now = next
+	}
+	require.Equal(t, int64(4), b.failuresStat.Get())
+	require.Equal(t, int64(4), b.skippedStat.Get())
+
+	// Partial writes reset the backoff
+	b.record(1, errFailed, now)
+	require.True(t, b.nextAttempt().IsZero())
+	require.Equal(t, int64(0), b.failuresStat.Get())
+	require.Equal(t, int64(circuitClosed), b.stateStat.Get())
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	b := newCircuitBreaker(&OutputConfig{
+		CircuitBreakerFailures: 2,
+		CircuitBreakerTimeout:  time.Minute,
+	}, testutil.Logger{}, map[string]string{"_id": "states"})
+	unregisterCircuitBreakerStats(t, map[string]string{"_id": "states"})
+
+	now := time.Now()
+	errFailed := errors.New("failed")
+
+	// Without backoff writes are only skipped while the breaker is open
+	b.record(0, errFailed, now)
+	require.NoError(t, b.allow(now))
+	require.Equal(t, int64(circuitClosed), b.stateStat.Get())
+	b.record(0, errFailed, now)
+	require.Equal(t, int64(circuitOpen), b.stateStat.Get())
+	require.Equal(t, int64(1), b.transitionStats[circuitOpen].Get())
+	require.Equal(t, now.Add(time.Minute), b.nextAttempt())
+	require.ErrorIs(t, b.allow(now.Add(30*time.Second)), ErrCircuitOpen)
+
+	// A single probe is let through after the timeout, a failed probe opens
+	// the breaker again
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow(now))
+	require.Equal(t, int64(circuitHalfOpen), b.stateStat.Get())
+	require.ErrorIs(t, b.allow(now), ErrCircuitOpen)
+	b.record(0, errFailed, now)
+	require.Equal(t, int64(circuitOpen), b.stateStat.Get())
+	require.Equal(t, int64(2), b.transitionStats[circuitOpen].Get())
+
+	// Empty writes do not close the breaker but allow another probe
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow(now))
+	b.record(0, nil, now)
+	require.Equal(t, int64(circuitHalfOpen), b.stateStat.Get())
+	require.True(t, b.nextAttempt().IsZero())
+
+	// A successful probe closes the breaker
+	require.NoError(t, b.allow(now))
+	b.record(5, nil, now)
+	require.Equal(t, int64(circuitClosed), b.stateStat.Get())
+	require.Equal(t, int64(2), b.transitionStats[circuitHalfOpen].Get())
+	require.Equal(t, int64(1), b.transitionStats[circuitClosed].Get())
+	require.NoError(t, b.allow(now))
+}
+
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	plugin := &mockOutput{
+		preWriteHook: func([]telegraf.Metric) error {
+			if down.Load() {
+				return errors.New("backend down")
+			}
+			return nil
+		},
+	}
+	model, err := NewRunningOutput(plugin, &OutputConfig{
+		Name:                   "test",
+		ID:                     "circuit_breaker",
+		CircuitBreakerFailures: 1,
+		CircuitBreakerTimeout:  50 * time.Millisecond,
+	}, 10, 100)
+	require.NoError(t, err)
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	for _, m := range first5 {
+		model.AddMetric(m)
+	}
+
+	// The open breaker skips writes without calling the plugin
+	down.Store(true)
+	require.ErrorContains(t, model.Write(), "backend down")
+	require.False(t, model.NextRetry().IsZero())
+	require.ErrorIs(t, model.Write(), ErrCircuitOpen)
+	require.ErrorIs(t, model.WriteBatch(), ErrCircuitOpen)
+	require.Equal(t, uint32(1), plugin.writes.Load())
+
+	// The probe after the timeout closes the breaker and writes the buffer
+	down.Store(false)
+	require.Eventually(t, func() bool {
+		return !time.Now().Before(model.NextRetry())
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, model.Write())
+	require.True(t, model.NextRetry().IsZero())
+	testutil.RequireMetricsEqual(t, first5, plugin.Metrics())
+}
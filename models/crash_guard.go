@@ -0,0 +1,301 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DefaultRestartInitialInterval is the delay before restarting a plugin
+	// after its first panic.
+	DefaultRestartInitialInterval = time.Second
+
+	// DefaultRestartMaxInterval is the maximum delay before restarting a
+	// repeatedly panicking plugin.
+	DefaultRestartMaxInterval = 5 * time.Minute
+)
+
+// ErrPluginCrashed is returned instead of calling a plugin which panicked
+// and is not yet restarted.
+var ErrPluginCrashed = errors.New("plugin crashed and awaits restart")
+
+// ErrPluginBusy is returned instead of calling a plugin exclusively while an
+// abandoned call to the plugin is still running.
+var ErrPluginBusy = errors.New("abandoned call to plugin still running")
+
+// PanicError is returned by a call to a plugin which panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("plugin panicked: %v", e.Value)
+}
+
+// crashGuard isolates a plugin after a panic. Calls to the crashed plugin
+// fail with ErrPluginCrashed until the plugin is restarted by the next call
+// after an exponentially increasing backoff.
+type crashGuard struct {
+	log      telegraf.Logger
+	restart  func() error
+	restarts selfstat.Stat
+
+	// running is held for reading during calls to the plugin and for writing
+	// while restarting the plugin
+	running sync.RWMutex
+
+	// abandoned counts the calls no longer holding running but still running
+	abandoned atomic.Int64
+
+	sync.Mutex
+	crashed bool
+	backoff time.Duration
+	next    time.Time
+}
+
+func newCrashGuard(log telegraf.Logger, restart func() error, restarts selfstat.Stat) *crashGuard {
+	return &crashGuard{
+		log:      log,
+		restart:  restart,
+		restarts: restarts,
+	}
+}
+
+// call calls the plugin function, restarting the plugin first if it crashed
+// before and the backoff elapsed. A panic of the function is recovered and
+// returned as PanicError.
+func (g *crashGuard) call(fn func() error) error {
+	return g.callContext(context.Background(), fn)
+}
+
+// callContext is like call but stops holding the plugin once the context is
+// done, even if the function keeps running, so exclusive calls and restarts
+// of the plugin are not blocked by an abandoned call. Instead, those are
+// skipped until the abandoned call returned.
+func (g *crashGuard) callContext(ctx context.Context, fn func() error) error {
+	// Plugins created without constructor, e.g. in tests, are not guarded
+	if g == nil {
+		return fn()
+	}
+
+	if err := g.ensureRunning(); err != nil {
+		return err
+	}
+
+	// Calls which cannot be abandoned avoid the overhead of watching the
+	// context as they are on the hot path of processors and aggregators
+	if ctx.Done() == nil {
+		g.running.RLock()
+		err := protect(fn)
+		g.running.RUnlock()
+		return g.finish(err)
+	}
+
+	g.running.RLock()
+	var mu sync.Mutex
+	var returned, abandoned bool
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !returned {
+			abandoned = true
+			g.abandoned.Add(1)
+			g.running.RUnlock()
+		}
+	})
+	err := protect(fn)
+	stop()
+	mu.Lock()
+	returned = true
+	if abandoned {
+		g.abandoned.Add(-1)
+	} else {
+		g.running.RUnlock()
+	}
+	mu.Unlock()
+
+	return g.finish(err)
+}
+
+// finish isolates the plugin if the call panicked or resets the backoff
+// otherwise, returning the error of the call.
+func (g *crashGuard) finish(err error) error {
+	if err != nil {
+		var perr *PanicError
+		if errors.As(err, &perr) {
+			g.crash(perr)
+			return err
+		}
+	}
+
+	g.Lock()
+	if !g.crashed {
+		g.backoff = 0
+	}
+	g.Unlock()
+	return err
+}
+
+// exclusive calls the plugin function while no other call to the plugin is
+// running, e.g. to reconnect the plugin. A panic of the function is recovered
+// and returned as PanicError. ErrPluginBusy is returned while an abandoned
+// call is still running.
+func (g *crashGuard) exclusive(fn func() error) error {
+	if g == nil {
+		return protect(fn)
+	}
+
+	g.running.Lock()
+	defer g.running.Unlock()
+	if g.busy() {
+		return ErrPluginBusy
+	}
+	return protect(fn)
+}
+
+// busy returns true while an abandoned call is still running. Calls are only
+// abandoned while holding running for reading, so the result is stable while
+// running is held for writing.
+func (g *crashGuard) busy() bool {
+	return g.abandoned.Load() > 0
+}
+
+// ensureRunning restarts the crashed plugin once the backoff elapsed.
+func (g *crashGuard) ensureRunning() error {
+	g.Lock()
+	defer g.Unlock()
+
+	if !g.crashed {
+		return nil
+	}
+	if time.Now().Before(g.next) {
+		return ErrPluginCrashed
+	}
+
+	// Postpone the restart until an abandoned call returned
+	g.running.Lock()
+	if g.busy() {
+		g.running.Unlock()
+		return ErrPluginCrashed
+	}
+	err := protect(g.restart)
+	g.running.Unlock()
+	g.restarts.Incr(1)
+
+	if err != nil {
+		g.schedule()
+		g.log.Errorf("Restarting plugin failed: %v; retrying in %s", err, g.backoff)
+		return fmt.Errorf("%w: %w", ErrPluginCrashed, err)
+	}
+	g.crashed = false
+	g.log.Info("Restarted plugin after panic")
+	return nil
+}
+
+// crash isolates the plugin after the given panic.
+func (g *crashGuard) crash(perr *PanicError) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.crashed = true
+	g.schedule()
+	g.log.Errorf("Panicked: %v, Stack:\n%s", perr.Value, perr.Stack)
+	g.log.Errorf("Restarting plugin in %s; PLEASE REPORT THIS PANIC ON GITHUB with "+
+		"stack trace, configuration, and OS information: "+
+		"https://github.com/influxdata/telegraf/issues/new/choose", g.backoff)
+}
+
+// schedule schedules the next restart doubling the backoff.
+func (g *crashGuard) schedule() {
+	if g.backoff == 0 {
+		g.backoff = DefaultRestartInitialInterval
+	} else {
+		g.backoff = min(2*g.backoff, DefaultRestartMaxInterval)
+	}
+	g.next = time.Now().Add(g.backoff)
+}
+
+// protect calls the function and converts a panic to a PanicError.
+func protect(fn func() error) (err error) {
+	defer func() {
+		//nolint:revive // recover is called inside a deferred function
+		if r := recover(); r != nil {
+			stack := make([]byte, 4096)
+			stack = stack[:runtime.Stack(stack, false)]
+			err = &PanicError{Value: r, Stack: stack}
+		}
+	}()
+	return fn()
+}
+
+// pause calls the function while no other call to the plugin is running and
+// returns true if called. The function is not called while an abandoned call
+// is still running. Panics of the function are not recovered.
+func (g *crashGuard) pause(fn func()) bool {
+	if g == nil {
+		fn()
+		return true
+	}
+
+	g.running.Lock()
+	defer g.running.Unlock()
+	if g.busy() {
+		return false
+	}
+	fn()
+	return true
+}
+
+// guardedState serializes accessing the state of a plugin with the calls to
+// the plugin to allow checkpointing the state of a running plugin. While an
+// abandoned call is running, the state taken last is returned instead.
+type guardedState struct {
+	plugin telegraf.StatefulPlugin
+	guard  *crashGuard
+
+	sync.Mutex
+	last interface{}
+}
+
+// newGuardedState returns the state of the plugin guarded by the crash guard
+// if the plugin is stateful.
+func newGuardedState(plugin any, guard *crashGuard) (telegraf.StatefulPlugin, bool) {
+	// Unwrap processors upgraded to streaming processors
+	if p, ok := plugin.(interface{ Unwrap() telegraf.Processor }); ok {
+		plugin = p.Unwrap()
+	}
+	stateful, ok := plugin.(telegraf.StatefulPlugin)
+	if !ok {
+		return nil, false
+	}
+	return &guardedState{plugin: stateful, guard: guard}, true
+}
+
+func (s *guardedState) GetState() interface{} {
+	s.Lock()
+	defer s.Unlock()
+
+	var state interface{}
+	if !s.guard.pause(func() { state = s.plugin.GetState() }) {
+		return s.last
+	}
+	s.last = state
+	return state
+}
+
+func (s *guardedState) SetState(state interface{}) error {
+	var err error
+	if !s.guard.pause(func() { err = s.plugin.SetState(state) }) {
+		return ErrPluginBusy
+	}
+	return err
+}
@@ -0,0 +1,435 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestCrashGuardBackoff(t *testing.T) {
+	var restarts int
+	g := newCrashGuard(testutil.Logger{}, func() error {
+		restarts++
+		return nil
+	}, selfstat.Register("crash_guard_test", "restarts", map[string]string{"test": "backoff"}))
+	start := g.restarts.Get()
+
+	require.NoError(t, g.call(func() error { return nil }))
+
+	// A panic is returned as error and isolates the plugin
+	err := g.call(func() error { panic("boom") })
+	var perr *PanicError
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, "boom", perr.Value)
+	require.NotEmpty(t, perr.Stack)
+	require.Equal(t, DefaultRestartInitialInterval, g.backoff)
+
+	called := false
+	require.ErrorIs(t, g.call(func() error { called = true; return nil }), ErrPluginCrashed)
+	require.False(t, called)
+	require.Zero(t, restarts)
+
+	// The plugin is restarted once the backoff elapsed and the backoff
+	// doubles for each subsequent panic
+	g.next = time.Now()
+	require.ErrorAs(t, g.call(func() error { panic("boom") }), &perr)
+	require.Equal(t, 1, restarts)
+	require.Equal(t, 2*DefaultRestartInitialInterval, g.backoff)
+
+	// A successful call resets the backoff
+	g.next = time.Now()
+	require.NoError(t, g.call(func() error { return nil }))
+	require.Equal(t, 2, restarts)
+	require.Zero(t, g.backoff)
+	require.Equal(t, int64(2), g.restarts.Get()-start)
+}
+
+func TestCrashGuardRestartFailed(t *testing.T) {
+	g := newCrashGuard(testutil.Logger{}, func() error {
+		return errors.New("connection refused")
+	}, selfstat.Register("crash_guard_test", "restarts", map[string]string{"test": "restart_failed"}))
+
+	require.Error(t, g.call(func() error { panic("boom") }))
+
+	g.next = time.Now()
+	err := g.call(func() error { return nil })
+	require.ErrorIs(t, err, ErrPluginCrashed)
+	require.ErrorContains(t, err, "connection refused")
+	require.Equal(t, 2*DefaultRestartInitialInterval, g.backoff)
+
+	// A panicking restart fails the same way
+	g.restart = func() error { panic("boom again") }
+	g.next = time.Now()
+	require.ErrorIs(t, g.call(func() error { return nil }), ErrPluginCrashed)
+	require.Equal(t, 4*DefaultRestartInitialInterval, g.backoff)
+}
+
+func TestCrashGuardAbandonedCall(t *testing.T) {
+	var restarts int
+	g := newCrashGuard(testutil.Logger{}, func() error {
+		restarts++
+		return nil
+	}, selfstat.Register("crash_guard_test", "restarts", map[string]string{"test": "abandoned"}))
+	start := g.restarts.Get()
+	state := &guardedState{plugin: &statefulPlugin{state: 1}, guard: g}
+	require.Equal(t, 1, state.GetState())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- g.callContext(ctx, func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// Exclusive access is blocked by the running call
+	require.False(t, g.running.TryLock())
+
+	// Once abandoned, the call does not block exclusive access anymore but
+	// exclusive calls, checkpoints and restarts are skipped until it returned
+	cancel()
+	called := false
+	require.ErrorIs(t, g.exclusive(func() error { called = true; return nil }), ErrPluginBusy)
+	require.False(t, called)
+	state.plugin.(*statefulPlugin).state = 2
+	require.Equal(t, 1, state.GetState())
+	require.ErrorIs(t, state.SetState(3), ErrPluginBusy)
+	g.crashed = true
+	g.next = time.Now()
+	require.ErrorIs(t, g.call(func() error { return nil }), ErrPluginCrashed)
+	require.Zero(t, restarts)
+	g.crashed = false
+	require.NoError(t, g.call(func() error { return nil }))
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, g.exclusive(func() error { called = true; return nil }))
+	require.True(t, called)
+	require.Equal(t, 2, state.GetState())
+
+	// The restart of a crashed plugin happens once the abandoned call returned
+	require.Equal(t, start, g.restarts.Get())
+	g.crashed = true
+	require.NoError(t, g.call(func() error { return nil }))
+	require.Equal(t, 1, restarts)
+	require.Equal(t, int64(1), g.restarts.Get()-start)
+}
+
+func TestCrashGuardCallAllocations(t *testing.T) {
+	g := newCrashGuard(testutil.Logger{}, func() error { return nil },
+		selfstat.Register("crash_guard_test", "restarts", map[string]string{"test": "allocations"}))
+	fn := func() error { return nil }
+
+	// Calls which cannot be abandoned are on the hot path of processors and
+	// aggregators and must not allocate
+	require.Zero(t, testing.AllocsPerRun(100, func() { _ = g.call(fn) }))
+}
+
+func TestRunningProcessorPanic(t *testing.T) {
+	p := &panickingProcessor{panics: 1}
+	rp := NewRunningProcessor(p, &ProcessorConfig{Name: "panicking", ID: "panicking-processor"})
+	require.NoError(t, rp.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, rp.Start(acc))
+	passed := rp.MetricsPassed.Get()
+	restarts := rp.crash.restarts.Get()
+
+	var perr *PanicError
+	require.ErrorAs(t, rp.Add(testutil.TestMetric(1), acc), &perr)
+
+	// Metrics are passed on unprocessed until the processor is restarted
+	require.NoError(t, rp.Add(testutil.TestMetric(2), acc))
+	require.Equal(t, int64(1), rp.MetricsPassed.Get()-passed)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{testutil.TestMetric(2)}, acc.GetTelegrafMetrics())
+	require.Zero(t, p.processed)
+
+	rp.crash.next = time.Now()
+	require.NoError(t, rp.Add(testutil.TestMetric(3), acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Equal(t, 1, p.processed)
+	require.Equal(t, 2, p.inits)
+	require.Equal(t, 2, p.starts)
+	require.Equal(t, 1, p.stops)
+	require.Equal(t, int64(1), rp.crash.restarts.Get()-restarts)
+}
+
+func TestRunningAggregatorPanic(t *testing.T) {
+	a := &panickingAggregator{panics: 1}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:   "panicking",
+		ID:     "panicking-aggregator",
+		Period: time.Minute,
+	})
+	require.NoError(t, ra.Init())
+	dropped := ra.MetricsDropped.Get()
+	restarts := ra.crash.restarts.Get()
+
+	now := time.Now()
+	ra.UpdateWindow(now.Add(-time.Minute), now.Add(time.Minute))
+	m := func(v int64) telegraf.Metric {
+		return metric.New("test", nil, map[string]interface{}{"value": v}, now)
+	}
+
+	// The metric causing the panic and metrics added until the restart are
+	// dropped
+	require.False(t, ra.Add(m(1)))
+	require.False(t, ra.Add(m(2)))
+	require.Equal(t, int64(2), ra.MetricsDropped.Get()-dropped)
+
+	ra.crash.next = time.Now()
+	require.False(t, ra.Add(m(3)))
+	require.Equal(t, int64(3), a.sum)
+	require.Equal(t, 2, a.inits)
+	require.Equal(t, int64(1), ra.crash.restarts.Get()-restarts)
+}
+
+func TestRunningOutputPanic(t *testing.T) {
+	o := &panickingOutput{panics: 1}
+	ro, err := NewRunningOutput(o, &OutputConfig{Name: "panicking", ID: "panicking-output"}, 10, 10)
+	require.NoError(t, err)
+	require.NoError(t, ro.Init())
+	require.NoError(t, ro.Connect())
+	writeErrors := ro.WriteErrors.Get()
+	restarts := ro.crash.restarts.Get()
+
+	// Metrics are kept in the buffer until the output is restarted
+	ro.AddMetric(testutil.TestMetric(1))
+	var perr *PanicError
+	require.ErrorAs(t, ro.Write(), &perr)
+	require.ErrorIs(t, ro.Write(), ErrPluginCrashed)
+	require.Equal(t, 1, ro.BufferLength())
+	require.Equal(t, int64(2), ro.WriteErrors.Get()-writeErrors)
+
+	ro.crash.next = time.Now()
+	require.NoError(t, ro.Write())
+	require.Len(t, o.metrics, 1)
+	require.Equal(t, 1, o.closes)
+	require.Equal(t, 2, o.connects)
+	require.Equal(t, int64(1), ro.crash.restarts.Get()-restarts)
+}
+
+func TestRunningInputPanic(t *testing.T) {
+	i := &panickingInput{panics: 1}
+	ri := NewRunningInput(i, &InputConfig{Name: "panicking", ID: "panicking-input"})
+	require.NoError(t, ri.Init())
+	defer GlobalGatherErrors.Set(0)
+	gatherErrors := ri.GatherErrors.Get()
+	restarts := ri.crash.restarts.Get()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, ri.Start(acc))
+
+	var perr *PanicError
+	require.ErrorAs(t, ri.Gather(acc), &perr)
+	require.ErrorIs(t, ri.Gather(acc), ErrPluginCrashed)
+	require.Equal(t, int64(2), ri.GatherErrors.Get()-gatherErrors)
+
+	// Service inputs are stopped and started again on restart
+	ri.crash.next = time.Now()
+	require.NoError(t, ri.Gather(acc))
+	require.Equal(t, 2, i.inits)
+	require.Equal(t, 2, i.starts)
+	require.Equal(t, 1, i.stops)
+	require.Equal(t, int64(1), ri.crash.restarts.Get()-restarts)
+}
+
+func TestStatefulPluginSerialized(t *testing.T) {
+	p := &statefulProcessor{entered: make(chan bool), release: make(chan bool)}
+	rp := NewRunningProcessor(p, &ProcessorConfig{Name: "stateful", ID: "stateful-processor"})
+	state, ok := rp.StatefulPlugin()
+	require.True(t, ok)
+
+	// Accessing the state waits for running calls to the plugin
+	acc := &testutil.Accumulator{}
+	go func() {
+		_ = rp.Add(testutil.TestMetric(1), acc)
+	}()
+	<-p.entered
+
+	done := make(chan interface{})
+	go func() {
+		done <- state.GetState()
+	}()
+	select {
+	case <-done:
+		require.Fail(t, "state accessed during a call to the plugin")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(p.release)
+	require.Equal(t, 1, <-done)
+
+	// Plugins without state are not reported as stateful
+	_, ok = NewRunningProcessor(&panickingProcessor{}, &ProcessorConfig{Name: "stateless"}).StatefulPlugin()
+	require.False(t, ok)
+}
+
+type statefulProcessor struct {
+	entered chan bool
+	release chan bool
+	count   int
+}
+
+func (*statefulProcessor) SampleConfig() string             { return "" }
+func (*statefulProcessor) Start(telegraf.Accumulator) error { return nil }
+func (*statefulProcessor) Stop()                            {}
+
+func (p *statefulProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	p.entered <- true
+	<-p.release
+	p.count++
+	acc.AddMetric(m)
+	return nil
+}
+
+func (p *statefulProcessor) GetState() interface{} {
+	return p.count
+}
+
+func (p *statefulProcessor) SetState(state interface{}) error {
+	p.count = state.(int)
+	return nil
+}
+
+type panickingProcessor struct {
+	panics int
+	acc    telegraf.Accumulator
+
+	inits, starts, stops, processed int
+}
+
+func (*panickingProcessor) SampleConfig() string {
+	return ""
+}
+
+func (p *panickingProcessor) Init() error {
+	p.inits++
+	return nil
+}
+
+func (p *panickingProcessor) Start(acc telegraf.Accumulator) error {
+	p.starts++
+	p.acc = acc
+	return nil
+}
+
+func (p *panickingProcessor) Add(m telegraf.Metric, _ telegraf.Accumulator) error {
+	if p.panics > 0 {
+		p.panics--
+		panic("processor panicked")
+	}
+	p.processed++
+	p.acc.AddMetric(m)
+	return nil
+}
+
+func (p *panickingProcessor) Stop() {
+	p.stops++
+}
+
+type panickingAggregator struct {
+	mockAggregator
+	panics int
+	inits  int
+}
+
+func (a *panickingAggregator) Init() error {
+	a.inits++
+	return nil
+}
+
+func (a *panickingAggregator) Add(in telegraf.Metric) {
+	if a.panics > 0 {
+		a.panics--
+		panic("aggregator panicked")
+	}
+	a.mockAggregator.Add(in)
+}
+
+type panickingOutput struct {
+	panics  int
+	metrics []telegraf.Metric
+
+	connects, closes int
+}
+
+func (*panickingOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *panickingOutput) Connect() error {
+	o.connects++
+	return nil
+}
+
+func (o *panickingOutput) Close() error {
+	o.closes++
+	return nil
+}
+
+func (o *panickingOutput) Write(metrics []telegraf.Metric) error {
+	if o.panics > 0 {
+		o.panics--
+		panic("output panicked")
+	}
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+type panickingInput struct {
+	panics int
+
+	inits, starts, stops int
+}
+
+func (*panickingInput) SampleConfig() string {
+	return ""
+}
+
+func (i *panickingInput) Init() error {
+	i.inits++
+	return nil
+}
+
+func (i *panickingInput) Start(telegraf.Accumulator) error {
+	i.starts++
+	return nil
+}
+
+func (i *panickingInput) Stop() {
+	i.stops++
+}
+
+func (i *panickingInput) Gather(telegraf.Accumulator) error {
+	if i.panics > 0 {
+		i.panics--
+		panic("input panicked")
+	}
+	return nil
+}
+
+// statefulPlugin is a plugin with an integer state.
+type statefulPlugin struct {
+	state int
+}
+
+func (p *statefulPlugin) GetState() interface{} {
+	return p.state
+}
+
+func (p *statefulPlugin) SetState(state interface{}) error {
+	p.state = state.(int)
+	return nil
+}
@@ -0,0 +1,234 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// DefaultFailoverMaxFailures is the number of consecutive failed writes of
+// the active member after which a failover group switches to the next member.
+const DefaultFailoverMaxFailures = 3
+
+// failoverGroup is an ordered group of outputs where only one member, the
+// active one, receives the metrics. All members write from the buffer of the
+// first member so each metric is delivered to exactly one member. Members
+// before the active one keep trying to write on each flush and the group
+// switches back as soon as such a member succeeds.
+type failoverGroup struct {
+	name    string
+	members []*RunningOutput
+	stats   []failoverStats
+
+	active   atomic.Int32
+	failures int
+}
+
+type failoverStats struct {
+	active      selfstat.Stat
+	activations selfstat.Stat
+	failures    selfstat.Stat
+	written     selfstat.Stat
+}
+
+func newFailoverGroup(name string, members []*RunningOutput) *failoverGroup {
+	g := &failoverGroup{
+		name:    name,
+		members: members,
+		stats:   make([]failoverStats, 0, len(members)),
+	}
+	for i, member := range members {
+		tags := map[string]string{
+			"failover_group": name,
+			"output":         member.Config.Name,
+			"_id":            member.Config.ID,
+		}
+		if member.Config.Alias != "" {
+			tags["alias"] = member.Config.Alias
+		}
+		stats := failoverStats{
+			active:      selfstat.Register("failover", "active", tags),
+			activations: selfstat.Register("failover", "activations", tags),
+			failures:    selfstat.Register("failover", "write_failures", tags),
+			written:     selfstat.Register("failover", "metrics_written", tags),
+		}
+		if i == 0 {
+			stats.active.Set(1)
+		} else {
+			stats.active.Set(0)
+		}
+		g.stats = append(g.stats, stats)
+	}
+	return g
+}
+
+// activeMember returns the member currently receiving the metrics.
+func (g *failoverGroup) activeMember() *RunningOutput {
+	return g.members[g.active.Load()]
+}
+
+// write calls the given write function of the member if the member is the
+// active one or one of the preferred members before it. The group switches
+// to the next member once the active member failed the configured number of
+// times in a row and switches back to a preferred member as soon as it wrote
+// metrics successfully.
+func (g *failoverGroup) write(member *RunningOutput, write func() (int, error)) error {
+	// Serialize the writes of the members as all of them use the same buffer.
+	// The lock belongs to the first member to also serialize with writes of
+	// groups replaced during a reload.
+	g.members[0].failoverLock.Lock()
+	defer g.members[0].failoverLock.Unlock()
+
+	idx := slices.Index(g.members, member)
+	active := int(g.active.Load())
+	if idx < 0 || idx > active {
+		// Standby members do not write, make sure we do not block the
+		// batch-ready trigger of the member
+		member.writeInFlight.Store(false)
+		return nil
+	}
+
+	n, err := write()
+	g.stats[idx].written.Incr(int64(n))
+	if err != nil {
+		g.stats[idx].failures.Incr(1)
+		if idx != active {
+			return err
+		}
+		g.failures++
+
+		maxFailures := member.Config.FailoverMaxFailures
+		if maxFailures <= 0 {
+			maxFailures = DefaultFailoverMaxFailures
+		}
+		if g.failures >= maxFailures && idx < len(g.members)-1 {
+			next := g.members[idx+1]
+			member.log.Warnf("Failed to write %d times in a row; switching failover group %q to %s",
+				g.failures, g.name, next.LogName())
+			g.activate(idx + 1)
+		}
+		return err
+	}
+
+	// Only switch back if the member actually wrote metrics as an empty write
+	// does not prove the member is healthy
+	if idx < active && n > 0 {
+		member.log.Infof("Recovered; switching failover group %q back from %s", g.name, g.members[active].LogName())
+		g.activate(idx)
+	}
+	if idx == int(g.active.Load()) {
+		g.failures = 0
+	}
+	return nil
+}
+
+func (g *failoverGroup) activate(idx int) {
+	g.stats[g.active.Load()].active.Set(0)
+	g.stats[idx].active.Set(1)
+	g.stats[idx].activations.Incr(1)
+	g.active.Store(int32(idx))
+	g.failures = 0
+}
+
+// LinkFailoverGroups groups the given outputs by their failover group setting
+// keeping the order of the outputs. Outputs without a failover group are
+// removed from any previous group.
+func LinkFailoverGroups(outputs []*RunningOutput) {
+	var names []string
+	members := make(map[string][]*RunningOutput)
+	for _, output := range outputs {
+		name := output.Config.FailoverGroup
+		if name == "" {
+			output.failover.Store(nil)
+			continue
+		}
+		if _, found := members[name]; !found {
+			names = append(names, name)
+		}
+		members[name] = append(members[name], output)
+	}
+
+	for _, name := range names {
+		g := newFailoverGroup(name, members[name])
+		for _, member := range g.members {
+			member.failover.Store(g)
+		}
+	}
+}
+
+// LeaveFailoverGroup removes the output from its failover group, e.g. when
+// the output is stopped. The remaining members are not affected.
+func (r *RunningOutput) LeaveFailoverGroup() {
+	r.failover.Store(nil)
+}
+
+// FailoverGroup returns the name of the failover group the output is linked
+// to or an empty string if the output is not part of a group.
+func (r *RunningOutput) FailoverGroup() string {
+	if g := r.failover.Load(); g != nil {
+		return g.name
+	}
+	return ""
+}
+
+// FailoverActive returns true if the output is the active member of its
+// failover group or not part of a group.
+func (r *RunningOutput) FailoverActive() bool {
+	g := r.failover.Load()
+	return g == nil || g.activeMember() == r
+}
+
+// CheckFailoverMember returns an error if the output selects or modifies the
+// metrics differently than the first member of its failover group. The
+// metrics are filtered and modified once when added to the buffer of the
+// first member, so the settings of the other members would be ignored.
+func CheckFailoverMember(first, member *OutputConfig) error {
+	var setting string
+	switch {
+	case !sameFilterSettings(&first.Filter, &member.Filter):
+		setting = "metric filtering"
+	case first.NameOverride != member.NameOverride:
+		setting = "name_override"
+	case first.NamePrefix != member.NamePrefix:
+		setting = "name_prefix"
+	case first.NameSuffix != member.NameSuffix:
+		setting = "name_suffix"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s setting of outputs.%s must match the first member of failover group %q",
+		setting, member.Name, member.FailoverGroup)
+}
+
+// sameFilterSettings returns true if both filters are configured the same.
+func sameFilterSettings(a, b *Filter) bool {
+	sameTagFilters := func(a, b []TagFilter) bool {
+		return slices.EqualFunc(a, b, func(x, y TagFilter) bool {
+			return x.Name == y.Name && slices.Equal(x.Values, y.Values)
+		})
+	}
+	return slices.Equal(a.NameDrop, b.NameDrop) && a.NameDropSeparators == b.NameDropSeparators &&
+		slices.Equal(a.NamePass, b.NamePass) && a.NamePassSeparators == b.NamePassSeparators &&
+		slices.Equal(a.FieldExclude, b.FieldExclude) && slices.Equal(a.FieldInclude, b.FieldInclude) &&
+		sameTagFilters(a.TagDropFilters, b.TagDropFilters) && sameTagFilters(a.TagPassFilters, b.TagPassFilters) &&
+		slices.Equal(a.TagExclude, b.TagExclude) && slices.Equal(a.TagInclude, b.TagInclude) &&
+		a.MetricPass == b.MetricPass
+}
+
+// isStandby returns true if the output is not the first member of a failover
+// group and thus does not accept metrics itself.
+func (r *RunningOutput) isStandby() bool {
+	g := r.failover.Load()
+	return g != nil && g.members[0] != r
+}
+
+// source returns the buffer the output writes from, which is the buffer of
+// the first member for outputs in a failover group.
+func (r *RunningOutput) source() Buffer {
+	if g := r.failover.Load(); g != nil {
+		return g.members[0].buffer
+	}
+	return r.buffer
+}
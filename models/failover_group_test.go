@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestFailoverGroupSwitchAndSwitchBack(t *testing.T) {
+	var down atomic.Bool
+	primaryPlugin := &mockOutput{
+		preWriteHook: func([]telegraf.Metric) error {
+			if down.Load() {
+				return errors.New("primary down")
+			}
+			return nil
+		},
+	}
+	primary, err := NewRunningOutput(primaryPlugin, &OutputConfig{
+		Name:                "test",
+		ID:                  "primary",
+		FailoverGroup:       "influx",
+		FailoverMaxFailures: 2,
+	}, 10, 100)
+	require.NoError(t, err)
+	require.NoError(t, primary.Connect())
+	defer primary.Close()
+
+	standbyPlugin := &mockOutput{}
+	standby, err := NewRunningOutput(standbyPlugin, &OutputConfig{Name: "test", ID: "standby", FailoverGroup: "influx"}, 10, 100)
+	require.NoError(t, err)
+	require.NoError(t, standby.Connect())
+	defer standby.Close()
+
+	LinkFailoverGroups([]*RunningOutput{primary, standby})
+	require.Equal(t, "influx", standby.FailoverGroup())
+	g := primary.failover.Load()
+	activations := []int64{g.stats[0].activations.Get(), g.stats[1].activations.Get()}
+	failures := g.stats[0].failures.Get()
+	written := []int64{g.stats[0].written.Get(), g.stats[1].written.Get()}
+	require.True(t, primary.FailoverActive())
+	require.False(t, standby.FailoverActive())
+
+	// Add the metrics the way the agent does for every output
+	add := func(metrics []telegraf.Metric) {
+		for _, m := range metrics {
+			primary.AddMetric(m)
+			standby.AddMetric(m)
+		}
+	}
+
+	// The healthy primary receives the metrics, the standby does not write
+	add(first5)
+	require.NoError(t, standby.Write())
+	require.Empty(t, standbyPlugin.Metrics())
+	require.NoError(t, primary.Write())
+	testutil.RequireMetricsEqual(t, first5, primaryPlugin.Metrics())
+
+	// Switch to the standby after the configured number of failures
+	down.Store(true)
+	add(next5)
+	require.Error(t, primary.Write())
+	require.True(t, primary.FailoverActive())
+	require.Error(t, primary.Write())
+	require.False(t, primary.FailoverActive())
+	require.True(t, standby.FailoverActive())
+	require.NoError(t, standby.Write())
+	testutil.RequireMetricsEqual(t, next5, standbyPlugin.Metrics())
+
+	// The primary keeps trying and takes over again once recovered
+	add(first5)
+	require.Error(t, primary.Write())
+	require.True(t, standby.FailoverActive())
+	down.Store(false)
+	require.NoError(t, primary.Write())
+	require.True(t, primary.FailoverActive())
+	require.NoError(t, standby.Write())
+
+	// Each metric must be delivered to exactly one member
+	testutil.RequireMetricsEqual(t, append(first5, first5...), primaryPlugin.Metrics())
+	testutil.RequireMetricsEqual(t, next5, standbyPlugin.Metrics())
+	require.Zero(t, primary.BufferLength())
+	require.Zero(t, standby.BufferLength())
+
+	require.Equal(t, int64(1), g.stats[0].active.Get())
+	require.Equal(t, int64(0), g.stats[1].active.Get())
+	require.Equal(t, int64(1), g.stats[0].activations.Get()-activations[0])
+	require.Equal(t, int64(1), g.stats[1].activations.Get()-activations[1])
+	require.Equal(t, int64(3), g.stats[0].failures.Get()-failures)
+	require.Equal(t, int64(10), g.stats[0].written.Get()-written[0])
+	require.Equal(t, int64(5), g.stats[1].written.Get()-written[1])
+}
+
+func TestFailoverGroupConnectFailure(t *testing.T) {
+	primary, err := NewRunningOutput(&mockOutput{
+		startupErrorCount: -1,
+		startupError:      errors.New("connection refused"),
+	}, &OutputConfig{Name: "test", ID: "primary", FailoverGroup: "connect", FailoverMaxFailures: 1}, 10, 100)
+	require.NoError(t, err)
+	defer primary.Close()
+
+	standbyPlugin := &mockOutput{}
+	standby, err := NewRunningOutput(standbyPlugin, &OutputConfig{Name: "test", ID: "standby", FailoverGroup: "connect"}, 10, 100)
+	require.NoError(t, err)
+	require.NoError(t, standby.Connect())
+	defer standby.Close()
+
+	other, err := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "test", ID: "other"}, 10, 100)
+	require.NoError(t, err)
+	defer other.Close()
+
+	LinkFailoverGroups([]*RunningOutput{primary, other, standby})
+	require.Empty(t, other.FailoverGroup())
+
+	for _, m := range first5 {
+		primary.AddMetric(m)
+		standby.AddMetricNoCopy(m.Copy())
+	}
+
+	// A primary that cannot connect counts as failed
+	require.Error(t, primary.Write())
+	require.True(t, standby.FailoverActive())
+	require.NoError(t, standby.WriteBatch())
+	testutil.RequireMetricsEqual(t, first5, standbyPlugin.Metrics())
+}
+
+func TestCheckFailoverMember(t *testing.T) {
+	first := &OutputConfig{
+		Name:          "first",
+		FailoverGroup: "group",
+		NamePrefix:    "pre_",
+		Filter: Filter{
+			NamePass:       []string{"cpu"},
+			TagPassFilters: []TagFilter{{Name: "host", Values: []string{"a"}}},
+		},
+	}
+
+	member := *first
+	member.Name = "member"
+	require.NoError(t, CheckFailoverMember(first, &member))
+
+	member.Filter.TagPassFilters = []TagFilter{{Name: "host", Values: []string{"b"}}}
+	require.ErrorContains(t, CheckFailoverMember(first, &member), "metric filtering setting of outputs.member")
+
+	member.Filter = first.Filter
+	member.NamePrefix = ""
+	require.ErrorContains(t, CheckFailoverMember(first, &member), `name_prefix setting of outputs.member must match the first member of failover group "group"`)
+}
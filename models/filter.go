@@ -3,6 +3,8 @@ package models
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/cel-go/cel"
@@ -176,6 +178,75 @@ func (f *Filter) IsActive() bool {
 	return f.isActive
 }
 
+// Conflicts returns the reasons why the filter can never let a metric pass,
+// e.g. all names in 'namepass' being dropped by 'namedrop'. The result is
+// empty if no such conflict is found. Only literal entries and the match-all
+// pattern are analysed, so overlapping globs are not reported. The filter
+// does not need to be compiled.
+func (f *Filter) Conflicts() []string {
+	var conflicts []string
+
+	if len(f.NamePass) > 0 && coversAll(f.NameDrop, f.NamePass, []rune(f.NameDropSeparators)...) {
+		conflicts = append(conflicts, "all names in 'namepass' are dropped by 'namedrop'")
+	}
+
+	// Tag filters with the same tag name are combined, a metric passes if any
+	// of the 'tagpass' filters matches.
+	if len(f.TagPassFilters) > 0 {
+		drop := make(map[string][]string, len(f.TagDropFilters))
+		for _, tf := range f.TagDropFilters {
+			drop[tf.Name] = append(drop[tf.Name], tf.Values...)
+		}
+		var passing, empty int
+		for _, tf := range f.TagPassFilters {
+			if len(tf.Values) == 0 {
+				empty++
+			} else if !coversAll(drop[tf.Name], tf.Values) {
+				passing++
+			}
+		}
+		if passing == 0 {
+			if empty == len(f.TagPassFilters) {
+				conflicts = append(conflicts, "'tagpass' has no values to match")
+			} else {
+				conflicts = append(conflicts, "all values in 'tagpass' are dropped by 'tagdrop'")
+			}
+		}
+	}
+
+	// Metrics without fields are dropped
+	if len(f.FieldInclude) > 0 && coversAll(f.FieldExclude, f.FieldInclude) {
+		conflicts = append(conflicts, "all fields in 'fieldinclude' are removed by 'fieldexclude' leaving no fields")
+	}
+
+	return conflicts
+}
+
+// coversAll returns true if every entry of the given list is matched by the
+// patterns. Entries containing glob syntax are only covered by an identical
+// pattern or the match-all pattern.
+func coversAll(patterns, entries []string, separators ...rune) bool {
+	if len(patterns) == 0 || len(entries) == 0 {
+		return false
+	}
+	f, err := filter.Compile(patterns, separators...)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if strings.ContainsAny(entry, "*?[]{}\\") {
+			if !slices.Contains(patterns, entry) && !slices.Contains(patterns, "*") {
+				return false
+			}
+			continue
+		}
+		if !f.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
 // shouldNamePass returns true if the metric should pass, false if it should drop
 // based on the drop/pass filter parameters
 func (f *Filter) shouldNamePass(key string) bool {
@@ -543,6 +543,100 @@ func TestFilterTagsPassAndDrop(t *testing.T) {
 	}
 }
 
+func TestFilterConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{
+			name: "no conflict",
+			filter: Filter{
+				NamePass:       []string{"cpu", "mem"},
+				NameDrop:       []string{"cpu"},
+				TagPassFilters: []TagFilter{{Name: "cpu", Values: []string{"cpu0", "cpu1"}}},
+				TagDropFilters: []TagFilter{{Name: "cpu", Values: []string{"cpu0"}}},
+				FieldInclude:   []string{"usage_*"},
+				FieldExclude:   []string{"usage_idle"},
+			},
+		},
+		{
+			name: "overlapping globs",
+			filter: Filter{
+				NamePass: []string{"cpu*"},
+				NameDrop: []string{"c*"},
+			},
+		},
+		{
+			name: "namepass dropped",
+			filter: Filter{
+				NamePass: []string{"cpu", "mem*"},
+				NameDrop: []string{"cpu", "mem*"},
+			},
+			expected: []string{"all names in 'namepass' are dropped by 'namedrop'"},
+		},
+		{
+			name: "namepass dropped by match-all",
+			filter: Filter{
+				NamePass: []string{"cpu", "mem*"},
+				NameDrop: []string{"*"},
+			},
+			expected: []string{"all names in 'namepass' are dropped by 'namedrop'"},
+		},
+		{
+			name: "namepass dropped with separators",
+			filter: Filter{
+				NamePass:           []string{"cpu.usage"},
+				NameDrop:           []string{"cpu.*"},
+				NameDropSeparators: ".",
+			},
+			expected: []string{"all names in 'namepass' are dropped by 'namedrop'"},
+		},
+		{
+			name: "tagpass dropped",
+			filter: Filter{
+				TagPassFilters: []TagFilter{
+					{Name: "cpu", Values: []string{"cpu0"}},
+					{Name: "host", Values: []string{"a", "b"}},
+				},
+				TagDropFilters: []TagFilter{
+					{Name: "cpu", Values: []string{"cpu*"}},
+					{Name: "host", Values: []string{"a", "b"}},
+				},
+			},
+			expected: []string{"all values in 'tagpass' are dropped by 'tagdrop'"},
+		},
+		{
+			name: "tagpass dropped for other tag",
+			filter: Filter{
+				TagPassFilters: []TagFilter{{Name: "cpu", Values: []string{"cpu0"}}},
+				TagDropFilters: []TagFilter{{Name: "host", Values: []string{"cpu0"}}},
+			},
+		},
+		{
+			name: "tagpass without values",
+			filter: Filter{
+				TagPassFilters: []TagFilter{{Name: "cpu"}},
+			},
+			expected: []string{"'tagpass' has no values to match"},
+		},
+		{
+			name: "fields removed",
+			filter: Filter{
+				FieldInclude: []string{"usage_idle", "usage_user"},
+				FieldExclude: []string{"usage_*"},
+			},
+			expected: []string{"all fields in 'fieldinclude' are removed by 'fieldexclude' leaving no fields"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.filter.Conflicts())
+		})
+	}
+}
+
 func TestFilterMetricPass(t *testing.T) {
 	m := metric.New("cpu",
 		map[string]string{
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -22,6 +25,8 @@ type RunningAggregator struct {
 	MetricsFiltered selfstat.Stat
 	MetricsDropped  selfstat.Stat
 	PushTime        selfstat.Stat
+
+	crash *crashGuard
 }
 
 func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConfig) *RunningAggregator {
@@ -44,7 +49,7 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 	SetLoggerOnPlugin(aggregator, logger)
 	SetStatisticsOnPlugin(aggregator, logger, tags)
 
-	return &RunningAggregator{
+	r := &RunningAggregator{
 		Aggregator: aggregator,
 		Config:     config,
 		MetricsPushed: selfstat.Register(
@@ -69,19 +74,32 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 		),
 		log: logger,
 	}
+	r.crash = newCrashGuard(logger, r.restart, selfstat.Register("aggregate", "restarts", tags))
+	return r
+}
+
+// AggregatorState is the persisted state of a stateful aggregator including
+// the current aggregation window.
+type AggregatorState struct {
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	State       json.RawMessage `json:"state"`
 }
 
 // AggregatorConfig is the common config for all aggregators.
 type AggregatorConfig struct {
 	Name         string
 	Source       string
+	Line         int
 	Alias        string
 	ID           string
+	Labels       map[string]string
 	DropOriginal bool
 	Period       time.Duration
 	Delay        time.Duration
 	Grace        time.Duration
 	LogLevel     string
+	Pipeline     string
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -125,6 +143,66 @@ func (r *RunningAggregator) UpdateWindow(start, until time.Time) {
 	r.log.Debugf("Updated aggregation range [%s, %s]", start, until)
 }
 
+// Stateful returns true if the aggregator plugin supports persisting its
+// state.
+func (r *RunningAggregator) Stateful() bool {
+	_, ok := r.Aggregator.(telegraf.StatefulPlugin)
+	return ok
+}
+
+// StatefulPlugin returns the running aggregator as state accessor if the
+// aggregator plugin is stateful to also persist the aggregation window.
+func (r *RunningAggregator) StatefulPlugin() (telegraf.StatefulPlugin, bool) {
+	return r, r.Stateful()
+}
+
+// GetState returns the state of the aggregator plugin together with the
+// current aggregation window.
+func (r *RunningAggregator) GetState() interface{} {
+	r.Lock()
+	defer r.Unlock()
+
+	state := AggregatorState{
+		PeriodStart: r.periodStart,
+		PeriodEnd:   r.periodEnd,
+	}
+	if plugin, ok := r.Aggregator.(telegraf.StatefulPlugin); ok {
+		serialized, err := json.Marshal(plugin.GetState())
+		if err != nil {
+			r.log.Errorf("marshalling state failed: %v", err)
+		}
+		state.State = serialized
+	}
+	return state
+}
+
+// SetState restores the state of the aggregator plugin and continues the
+// aggregation window of the previous run.
+func (r *RunningAggregator) SetState(state interface{}) error {
+	s, ok := state.(AggregatorState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if plugin, ok := r.Aggregator.(telegraf.StatefulPlugin); ok && len(s.State) > 0 {
+		// Use the initial state of the plugin as blueprint for unmarshalling
+		nstate := reflect.New(reflect.TypeOf(plugin.GetState())).Interface()
+		if err := json.Unmarshal(s.State, &nstate); err != nil {
+			return fmt.Errorf("unmarshalling state failed: %w", err)
+		}
+		if err := plugin.SetState(reflect.ValueOf(nstate).Elem().Interface()); err != nil {
+			return err
+		}
+	}
+	r.periodStart = s.PeriodStart
+	r.periodEnd = s.PeriodEnd
+
+	return nil
+}
+
 func (r *RunningAggregator) MakeMetric(telegrafMetric telegraf.Metric) telegraf.Metric {
 	m := makeMetric(
 		telegrafMetric,
@@ -171,7 +249,9 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 		return r.Config.DropOriginal
 	}
 
-	r.Aggregator.Add(m)
+	if err := r.crash.call(func() error { r.Aggregator.Add(m); return nil }); err != nil {
+		r.MetricsDropped.Incr(1)
+	}
 	return r.Config.DropOriginal
 }
 
@@ -194,11 +274,29 @@ func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 
 	r.UpdateWindow(since, until)
 
+	span := startSpan("push", "aggregators", r.Config.Name, r.ID(), r.Config.Alias)
+	pushed := r.MetricsPushed.Get()
+
 	start := time.Now()
-	r.Aggregator.Push(acc)
+	err := r.crash.call(func() error {
+		r.Aggregator.Push(acc)
+		return nil
+	})
 	elapsed := time.Since(start)
 	r.PushTime.Incr(elapsed.Nanoseconds())
-	r.Aggregator.Reset()
+	endSpan(span, r.MetricsPushed.Get()-pushed, err)
+	if err == nil {
+		r.Aggregator.Reset()
+	}
+}
+
+// restart resets and initializes the aggregator again after a panic. The
+// caller must hold the lock.
+func (r *RunningAggregator) restart() error {
+	if err := protect(func() error { r.Aggregator.Reset(); return nil }); err != nil {
+		r.log.Debugf("Resetting plugin failed: %v", err)
+	}
+	return r.Init()
 }
 
 func (r *RunningAggregator) Log() telegraf.Logger {
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -8,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/persister"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -240,6 +243,54 @@ func TestRunningAggregatorAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func TestRunningAggregatorRestoreState(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	now := time.Now()
+
+	a := &statefulMockAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		ID:     "test",
+		Period: time.Minute,
+	})
+	require.True(t, ra.Stateful())
+	ra.UpdateWindow(now, now.Add(ra.Config.Period))
+
+	m := metric.New("RITest",
+		map[string]string{},
+		map[string]interface{}{
+			"value": int64(101),
+		},
+		now.Add(time.Second),
+		telegraf.Untyped)
+	require.False(t, ra.Add(m))
+
+	p := &persister.Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register(ra.ID(), ra))
+	require.NoError(t, p.Store())
+
+	// Restore the state into a new aggregator
+	restored := NewRunningAggregator(&statefulMockAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		ID:     "test",
+		Period: time.Minute,
+	})
+	p = &persister.Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register(restored.ID(), restored))
+	require.NoError(t, p.Load())
+	require.True(t, restored.EndPeriod().Equal(now.Add(time.Minute)))
+
+	// Metrics within the restored window are added to the restored state
+	require.False(t, restored.Add(m))
+	acc := testutil.Accumulator{}
+	restored.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, int64(202), acc.Metrics[0].Fields["sum"])
+}
+
 type mockAggregator struct {
 	sum int64
 }
@@ -266,3 +317,20 @@ func (t *mockAggregator) Add(in telegraf.Metric) {
 		}
 	}
 }
+
+type statefulMockAggregator struct {
+	mockAggregator
+}
+
+func (t *statefulMockAggregator) GetState() interface{} {
+	return t.sum
+}
+
+func (t *statefulMockAggregator) SetState(state interface{}) error {
+	sum, ok := state.(int64)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	t.sum = sum
+	return nil
+}
@@ -1,10 +1,15 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/robfig/cron/v3"
+
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	logging "github.com/influxdata/telegraf/logger"
@@ -27,6 +32,17 @@ type RunningInput struct {
 	startAcc    telegraf.Accumulator
 	started     bool
 	retries     uint64
+	paused      atomic.Bool
+	cardinality *cardinalityGuard
+	crash       *crashGuard
+	schedule    cron.Schedule
+
+	// gathering is set while a gather runs, including an abandoned one
+	gathering atomic.Bool
+
+	// gatherTimes protects the collection times as an abandoned gather might
+	// still run while the next one starts
+	gatherTimes sync.Mutex
 	gatherStart time.Time
 	gatherEnd   time.Time
 
@@ -57,7 +73,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 	SetLoggerOnPlugin(input, logger)
 	SetStatisticsOnPlugin(input, logger, tags)
 
-	return &RunningInput{
+	ri := &RunningInput{
 		Input:  input,
 		Config: config,
 		MetricsGathered: selfstat.Register(
@@ -87,14 +103,21 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 		),
 		log: logger,
 	}
+	ri.crash = newCrashGuard(logger, ri.restart, selfstat.Register("gather", "restarts", tags))
+	if config.CardinalityLimit > 0 {
+		ri.cardinality = newCardinalityGuard(config, logger, tags)
+	}
+	return ri
 }
 
 // InputConfig is the common config for all inputs.
 type InputConfig struct {
 	Name                 string
 	Source               string
+	Line                 int
 	Alias                string
 	ID                   string
+	Labels               map[string]string
 	Interval             time.Duration
 	CollectionJitter     time.Duration
 	CollectionJitterSet  bool
@@ -111,6 +134,30 @@ type InputConfig struct {
 	Filter                  Filter
 	AlwaysIncludeLocalTags  bool
 	AlwaysIncludeGlobalTags bool
+
+	// GatherTimeout is the maximum duration of a gather before it is
+	// cancelled or abandoned by the agent. A timeout of zero disables the
+	// limit.
+	GatherTimeout time.Duration
+
+	// Schedule is a cron expression of the wall-clock times to gather the
+	// input at instead of every interval, evaluated in ScheduleTimezone or
+	// the local timezone if empty.
+	Schedule         string
+	ScheduleTimezone string
+
+	// CardinalityLimit is the maximum number of distinct series per
+	// measurement within CardinalityWindow. CardinalityAction determines the
+	// handling of new series exceeding the limit with CardinalitySampleRatio
+	// being the ratio of series accepted by the "sample" action.
+	CardinalityLimit       int
+	CardinalityWindow      time.Duration
+	CardinalityAction      string
+	CardinalitySampleRatio float64
+
+	// Secrets are the secret-store references, e.g. "@{vault:token}", used
+	// in the settings of the input
+	Secrets []string
 }
 
 func (*RunningInput) metricFiltered(metric telegraf.Metric) {
@@ -136,12 +183,32 @@ func (r *RunningInput) Init() error {
 		return fmt.Errorf("invalid 'time_source' setting %q", r.Config.TimeSource)
 	}
 
+	if r.Config.Schedule != "" {
+		schedule, err := ParseSchedule(r.Config.Schedule, r.Config.ScheduleTimezone)
+		if err != nil {
+			return err
+		}
+		r.schedule = schedule
+	}
+
 	if p, ok := r.Input.(telegraf.Initializer); ok {
 		return p.Init()
 	}
 	return nil
 }
 
+// Scheduled returns true if the input is gathered at the times of a schedule
+// instead of every interval.
+func (r *RunningInput) Scheduled() bool {
+	return r.schedule != nil
+}
+
+// NextGather returns the first scheduled gather time after the given time or
+// the zero time if there is none.
+func (r *RunningInput) NextGather(after time.Time) time.Time {
+	return r.schedule.Next(after)
+}
+
 func (r *RunningInput) Start(acc telegraf.Accumulator) error {
 	plugin, ok := r.Input.(telegraf.ServiceInput)
 	if !ok {
@@ -195,6 +262,28 @@ func (r *RunningInput) Stop() {
 	}
 }
 
+// Pause suspends the periodic gathering of the input.
+func (r *RunningInput) Pause() {
+	r.paused.Store(true)
+}
+
+// Resume continues the periodic gathering of a paused input.
+func (r *RunningInput) Resume() {
+	r.paused.Store(false)
+}
+
+// Paused returns true if the periodic gathering of the input is suspended.
+func (r *RunningInput) Paused() bool {
+	return r.paused.Load()
+}
+
+// StatefulPlugin returns the state of a stateful input plugin. A checkpoint
+// of the state waits for a running gather to finish while a gather abandoned
+// after its timeout keeps the state taken last.
+func (r *RunningInput) StatefulPlugin() (telegraf.StatefulPlugin, bool) {
+	return newGuardedState(r.Input, r.crash)
+}
+
 func (r *RunningInput) ID() string {
 	if p, ok := r.Input.(telegraf.PluginWithID); ok {
 		return p.ID()
@@ -238,18 +327,58 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 
 	switch r.Config.TimeSource {
 	case "collection_start":
+		r.gatherTimes.Lock()
 		metric.SetTime(r.gatherStart)
+		r.gatherTimes.Unlock()
 	case "collection_end":
+		r.gatherTimes.Lock()
 		metric.SetTime(r.gatherEnd)
+		r.gatherTimes.Unlock()
 	default:
 	}
 
+	if r.cardinality != nil && !r.cardinality.check(metric, time.Now()) {
+		metric.Drop()
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return metric
 }
 
+// Gather gathers the metrics of the input. A panic of the plugin is returned
+// as error and the plugin is restarted with backoff.
 func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+	return r.GatherContext(context.Background(), acc)
+}
+
+// GatherContext gathers the metrics of the input passing the context to
+// inputs implementing telegraf.ContextInput. Errors of a gather returning
+// after the context is done are not counted, the caller is expected to
+// account for the cancelled gather.
+func (r *RunningInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	r.gathering.Store(true)
+	defer r.gathering.Store(false)
+
+	err := r.crash.callContext(ctx, func() error { return r.gather(ctx, acc) })
+
+	var perr *PanicError
+	if errors.As(err, &perr) || errors.Is(err, ErrPluginCrashed) {
+		r.GatherErrors.Incr(1)
+		GlobalGatherErrors.Incr(1)
+	}
+	return err
+}
+
+// Gathering returns true while a gather of the input runs. As gathers are
+// not started concurrently, this denotes an abandoned gather still running
+// when checked before starting a new gather.
+func (r *RunningInput) Gathering() bool {
+	return r.gathering.Load()
+}
+
+func (r *RunningInput) gather(ctx context.Context, acc telegraf.Accumulator) error {
 	// Try to connect if we are not yet started up
 	if plugin, ok := r.Input.(telegraf.ServiceInput); ok && !r.started {
 		r.retries++
@@ -266,20 +395,78 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
-	r.gatherStart = time.Now()
-	err := r.Input.Gather(acc)
-	r.gatherEnd = time.Now()
+	span := startSpan("gather", "inputs", r.Config.Name, r.ID(), r.Config.Alias)
+	gathered := r.MetricsGathered.Get()
+
+	start := time.Now()
+	r.gatherTimes.Lock()
+	r.gatherStart = start
+	r.gatherTimes.Unlock()
+
+	var err error
+	if plugin, ok := r.Input.(telegraf.ContextInput); ok {
+		err = plugin.GatherContext(ctx, acc)
+	} else {
+		err = r.Input.Gather(acc)
+	}
+
+	end := time.Now()
+	r.gatherTimes.Lock()
+	r.gatherEnd = end
+	r.gatherTimes.Unlock()
 
-	r.GatherTime.Incr(r.gatherEnd.Sub(r.gatherStart).Nanoseconds())
+	r.GatherTime.Incr(end.Sub(start).Nanoseconds())
+	endSpan(span, r.MetricsGathered.Get()-gathered, err)
 
 	if err != nil {
-		r.GatherErrors.Incr(1)
-		GlobalGatherErrors.Incr(1)
+		if ctx.Err() == nil {
+			r.GatherErrors.Incr(1)
+			GlobalGatherErrors.Incr(1)
+		}
 		return err
 	}
 	return nil
 }
 
+// restart stops a started service input and initializes the plugin again
+// after a panic. Service inputs are started again on the next gather.
+func (r *RunningInput) restart() error {
+	if plugin, ok := r.Input.(telegraf.ServiceInput); ok && r.started {
+		if err := protect(func() error { plugin.Stop(); return nil }); err != nil {
+			r.log.Debugf("Stopping plugin failed: %v", err)
+		}
+		r.started = false
+	}
+	return r.Init()
+}
+
+// RefreshSecrets lets the input pick up changed secrets. Inputs implementing
+// telegraf.SecretRefresher are told to refresh while started service inputs
+// are restarted. Other inputs resolve their secrets on every gather.
+func (r *RunningInput) RefreshSecrets() error {
+	if plugin, ok := r.Input.(telegraf.SecretRefresher); ok {
+		return r.crash.exclusive(plugin.RefreshSecrets)
+	}
+
+	plugin, ok := r.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
+	}
+
+	// Inputs failing to start again are retried on the next gather
+	return r.crash.exclusive(func() error {
+		if !r.started {
+			return nil
+		}
+		plugin.Stop()
+		if err := plugin.Start(r.startAcc); err != nil {
+			r.started = false
+			return err
+		}
+		return nil
+	})
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
@@ -292,3 +479,8 @@ func (r *RunningInput) IncrGatherTimeouts() {
 	GlobalGatherTimeouts.Incr(1)
 	r.GatherTimeouts.Incr(1)
 }
+
+func (r *RunningInput) IncrGatherErrors() {
+	GlobalGatherErrors.Incr(1)
+	r.GatherErrors.Incr(1)
+}
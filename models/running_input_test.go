@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -612,6 +613,83 @@ func TestRunningInputStatisticsGatherErrorsCount(t *testing.T) {
 	require.Equal(t, int64(1), GlobalGatherErrors.Get())
 }
 
+func TestRunningInputGatherContext(t *testing.T) {
+	plugin := &contextInput{}
+	model := NewRunningInput(plugin, &InputConfig{
+		Name: "context",
+		ID:   "context-gather",
+	})
+	require.NoError(t, model.Init())
+	GlobalGatherErrors.Set(0)
+	defer GlobalGatherErrors.Set(0)
+
+	// The context is passed to inputs accepting one
+	var acc testutil.Accumulator
+	require.NoError(t, model.Gather(&acc))
+	require.Equal(t, 1, plugin.calls)
+
+	// Errors of a cancelled gather are left to the caller to account for
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, model.GatherContext(ctx, &acc), context.Canceled)
+	require.Equal(t, 2, plugin.calls)
+	require.Zero(t, model.GatherErrors.Get())
+	require.Zero(t, GlobalGatherErrors.Get())
+}
+
+func TestRunningInputRefreshSecrets(t *testing.T) {
+	plugin := &panickingInput{}
+	model := NewRunningInput(plugin, &InputConfig{Name: "panicking", ID: "refresh-service"})
+	require.NoError(t, model.Init())
+
+	// Service inputs not started yet pick up the secrets on start
+	require.NoError(t, model.RefreshSecrets())
+	require.Zero(t, plugin.starts)
+
+	// Started service inputs are restarted
+	require.NoError(t, model.Start(&testutil.Accumulator{}))
+	require.NoError(t, model.RefreshSecrets())
+	require.Equal(t, 2, plugin.starts)
+	require.Equal(t, 1, plugin.stops)
+
+	// Inputs implementing the refresher are not restarted
+	refresher := &refreshingInput{}
+	model = NewRunningInput(refresher, &InputConfig{Name: "refreshing", ID: "refresh-refresher"})
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Start(&testutil.Accumulator{}))
+	require.NoError(t, model.RefreshSecrets())
+	require.Equal(t, 1, refresher.refreshes)
+	require.Equal(t, 1, refresher.starts)
+	require.Zero(t, refresher.stops)
+}
+
+type refreshingInput struct {
+	panickingInput
+	refreshes int
+}
+
+func (i *refreshingInput) RefreshSecrets() error {
+	i.refreshes++
+	return nil
+}
+
+type contextInput struct {
+	calls int
+}
+
+func (*contextInput) SampleConfig() string {
+	return ""
+}
+
+func (*contextInput) Gather(telegraf.Accumulator) error {
+	return errors.New("gather called instead of gather context")
+}
+
+func (i *contextInput) GatherContext(ctx context.Context, _ telegraf.Accumulator) error {
+	i.calls++
+	return ctx.Err()
+}
+
 type mockInput struct {
 	probeReturn  error
 	gatherReturn error
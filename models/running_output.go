@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -10,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	logging "github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -23,12 +25,21 @@ const (
 	DefaultMetricBufferLimit = 10000
 )
 
+// Reasons for sending metrics to the dead-letter output
+const (
+	DeadLetterRejected           = "rejected"
+	DeadLetterOverflow           = "overflow"
+	DeadLetterSerializationError = "serialization_error"
+)
+
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name                 string
 	Source               string
+	Line                 int
 	Alias                string
 	ID                   string
+	Labels               map[string]string
 	StartupErrorBehavior string
 	Filter               Filter
 
@@ -45,6 +56,62 @@ type OutputConfig struct {
 	BufferDirectory string
 	BufferDiskSync  bool
 
+	// BufferMemoryLimit is the estimated size in bytes of the metrics kept
+	// in memory by the hybrid buffers of all outputs together and
+	// BufferSpillAfter the duration of failing writes after which a hybrid
+	// buffer moves its metrics to disk
+	BufferMemoryLimit int64
+	BufferSpillAfter  time.Duration
+
+	// DeadLetter is the alias or ID of the output receiving the metrics
+	// rejected by or dropped from this output
+	DeadLetter string
+
+	// FailoverGroup is the name of the failover group the output belongs to
+	// and FailoverMaxFailures the number of consecutive failed writes after
+	// which the next member of the group takes over
+	FailoverGroup       string
+	FailoverMaxFailures int
+
+	// Pipeline is the name of the pipeline the output belongs to
+	Pipeline string
+
+	// MetricBatchBytes and MetricBufferBytes limit the estimated serialized
+	// size of a batch and of the buffer in bytes in addition to the number
+	// of metrics. SizeSerializer is used to estimate the serialized size.
+	MetricBatchBytes  int64
+	MetricBufferBytes int64
+	SizeSerializer    telegraf.Serializer
+
+	// BatchAutoTune enables adapting the batch size to the write latency and
+	// errors with BatchTargetLatency being the desired latency of a write
+	BatchAutoTune      bool
+	BatchTargetLatency time.Duration
+
+	// InFlightBatches is the maximum number of batches written concurrently
+	// on flush, only supported by outputs implementing
+	// telegraf.ConcurrentOutput. With StrictSeriesOrdering, metrics of a series are only
+	// written once no other batch with metrics of the same series is in
+	// flight.
+	InFlightBatches      int
+	StrictSeriesOrdering bool
+
+	// RetryInitialInterval enables delaying the next write after a failed
+	// write by an exponential backoff with jitter, starting at the interval
+	// and increasing by RetryMultiplier per failure up to RetryMaxInterval.
+	// CircuitBreakerFailures is the number of consecutive failed writes
+	// opening the circuit breaker which probes the output again after
+	// CircuitBreakerTimeout.
+	RetryInitialInterval   time.Duration
+	RetryMaxInterval       time.Duration
+	RetryMultiplier        float64
+	CircuitBreakerFailures int
+	CircuitBreakerTimeout  time.Duration
+
+	// Secrets are the secret-store references, e.g. "@{vault:token}", used
+	// in the settings of the output
+	Secrets []string
+
 	LogLevel string
 }
 
@@ -54,6 +121,9 @@ type RunningOutput struct {
 	droppedMetrics  atomic.Int64
 	writeInFlight   atomic.Bool
 	lastWriteFailed atomic.Bool
+	lastError       atomic.Int64
+	deadLetter      atomic.Pointer[RunningOutput]
+	failover        atomic.Pointer[failoverGroup]
 
 	Output            telegraf.Output
 	Config            *OutputConfig
@@ -67,13 +137,37 @@ type RunningOutput struct {
 
 	BatchReady chan time.Time
 
+	// inflight tracks the transactions of the running writes with their
+	// buffer so they can be handed back when abandoning the writes
+	inflightLock sync.Mutex
+	inflight     map[*Transaction]Buffer
+	abandoned    bool
+	activeWrites atomic.Int64
+
 	buffer Buffer
 	log    telegraf.Logger
 
+	// Estimation of the metric sizes and tuning of the batch size, if enabled
+	sizer     *sizeEstimator
+	tuner     *batchTuner
+	batchStat selfstat.Stat
+	bytesStat selfstat.Stat
+
+	// Retry backoff and circuit breaker for failed writes, if enabled
+	breaker *circuitBreaker
+
+	// Isolation and restart of the plugin after a panic
+	crash *crashGuard
+
 	started bool
 	retries uint64
 
-	aggMutex sync.Mutex
+	aggMutex     sync.Mutex
+	failoverLock sync.Mutex
+
+	// Number of metrics per series in batches currently in flight
+	inflightSeries map[uint64]int
+	seriesLock     sync.Mutex
 }
 
 func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, bufferLimit int) (*RunningOutput, error) {
@@ -143,10 +237,49 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 		),
 		log: logger,
 	}
+	ro.crash = newCrashGuard(logger, ro.restart, selfstat.Register("write", "restarts", tags))
+	if h, ok := b.(interface{ setOverflowHandler(func(telegraf.Metric)) }); ok {
+		h.setOverflowHandler(func(m telegraf.Metric) {
+			ro.sendToDeadLetter(m, DeadLetterOverflow)
+		})
+	}
+
+	if h, ok := b.(*HybridBuffer); ok {
+		h.configure(config.BufferSpillAfter, config.BufferMemoryLimit)
+	}
+
+	if config.MetricBatchBytes > 0 || config.MetricBufferBytes > 0 || config.BatchAutoTune {
+		ro.batchStat = selfstat.Register("write", "batch_size", tags)
+		ro.batchStat.Set(int64(batchSize))
+		ro.bytesStat = selfstat.Register("write", "batch_bytes", tags)
+	}
+	if config.RetryInitialInterval > 0 || config.CircuitBreakerFailures > 0 {
+		ro.breaker = newCircuitBreaker(config, logger, tags)
+	}
+	if config.StrictSeriesOrdering {
+		ro.inflightSeries = make(map[uint64]int)
+	}
+	if config.BatchAutoTune {
+		ro.tuner = newBatchTuner(batchSize, config.BatchTargetLatency)
+	}
+	if config.MetricBatchBytes > 0 || config.MetricBufferBytes > 0 {
+		ro.sizer = newSizeEstimator(config.SizeSerializer)
+		if s, ok := b.(sizedBuffer); ok {
+			s.setSizer(ro.sizer.size, config.MetricBufferBytes, selfstat.Register("write", "buffer_bytes", tags))
+		} else if config.MetricBufferBytes > 0 {
+			logger.Warnf("Buffer strategy %q does not support limiting the buffer in bytes", config.BufferStrategy)
+		}
+	}
 
 	return ro, nil
 }
 
+// sizedBuffer is a buffer tracking the estimated size of its metrics in bytes.
+type sizedBuffer interface {
+	setSizer(sizeOf func(telegraf.Metric) int, limit int64, stat selfstat.Stat)
+	byteLen() int64
+}
+
 func (r *RunningOutput) LogName() string {
 	return logName("outputs", r.Config.Name, r.Config.Alias)
 }
@@ -156,6 +289,12 @@ func (r *RunningOutput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// StatefulPlugin returns the state of a stateful output plugin. A checkpoint
+// of the state waits for running writes and reconnects to finish.
+func (r *RunningOutput) StatefulPlugin() (telegraf.StatefulPlugin, bool) {
+	return newGuardedState(r.Output, r.crash)
+}
+
 func (r *RunningOutput) ID() string {
 	if p, ok := r.Output.(telegraf.PluginWithID); ok {
 		return p.ID()
@@ -170,6 +309,13 @@ func (r *RunningOutput) Init() error {
 		return fmt.Errorf("invalid 'startup_error_behavior' setting %q", r.Config.StartupErrorBehavior)
 	}
 
+	// Writing batches concurrently requires the output to support it
+	if r.Config.InFlightBatches > 1 {
+		if o, ok := r.Output.(telegraf.ConcurrentOutput); !ok || !o.SupportsConcurrentWrites() {
+			return errors.New("'in_flight_batches' requires an output supporting concurrent writes")
+		}
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -209,8 +355,40 @@ func (r *RunningOutput) Connect() error {
 	return err
 }
 
-// Close closes the output
+// restart closes, initializes and connects the output again after a panic.
+func (r *RunningOutput) restart() error {
+	if err := protect(r.Output.Close); err != nil {
+		r.log.Debugf("Closing plugin failed: %v", err)
+	}
+	if err := r.Init(); err != nil {
+		return err
+	}
+	return r.Output.Connect()
+}
+
+// RefreshSecrets lets the output pick up changed secrets. Outputs
+// implementing telegraf.SecretRefresher are told to refresh, all others are
+// reconnected. Writes wait for the refresh to finish.
+func (r *RunningOutput) RefreshSecrets() error {
+	if plugin, ok := r.Output.(telegraf.SecretRefresher); ok {
+		return r.crash.exclusive(plugin.RefreshSecrets)
+	}
+	return r.crash.exclusive(func() error {
+		if err := r.Output.Close(); err != nil {
+			r.log.Debugf("Closing plugin failed: %v", err)
+		}
+		return r.Output.Connect()
+	})
+}
+
+// Close closes the output. Outputs with an abandoned write still running are
+// left open as neither the output nor its buffer may be closed while writing.
 func (r *RunningOutput) Close() {
+	if r.Writing() {
+		r.log.Warn("Not closing the output as an abandoned write is still running")
+		return
+	}
+
 	if err := r.Output.Close(); err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
@@ -223,6 +401,10 @@ func (r *RunningOutput) Close() {
 // AddMetric adds a metric to the output.
 // The given metric will be copied if the output selects the metric.
 func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
+	if r.isStandby() {
+		return
+	}
+
 	ok, err := r.Config.Filter.Select(metric)
 	if err != nil {
 		r.log.Errorf("filtering failed: %v", err)
@@ -237,6 +419,11 @@ func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
 // AddMetricNoCopy adds a metric to the output.
 // Takes ownership of metric regardless of whether the output selects it for outputting.
 func (r *RunningOutput) AddMetricNoCopy(metric telegraf.Metric) {
+	if r.isStandby() {
+		metric.Drop()
+		return
+	}
+
 	ok, err := r.Config.Filter.Select(metric)
 	if err != nil {
 		r.log.Errorf("filtering failed: %v", err)
@@ -276,7 +463,11 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 
 	r.droppedMetrics.Add(int64(r.buffer.Add(metric)))
 
-	r.triggerBatchCheck()
+	if g := r.failover.Load(); g != nil {
+		g.activeMember().triggerBatchCheck()
+	} else {
+		r.triggerBatchCheck()
+	}
 }
 
 func (r *RunningOutput) triggerBatchCheck() {
@@ -284,7 +475,7 @@ func (r *RunningOutput) triggerBatchCheck() {
 	// metrics than the batch-size in the buffer. We guard this trigger to not
 	// be issued if a write is already ongoing to avoid event storms when adding
 	// new metrics during write.
-	if r.buffer.Len() >= r.MetricBatchSize && !r.lastWriteFailed.Load() {
+	if r.batchReady() && !r.lastWriteFailed.Load() {
 		// Please note: We cannot merge this if into the one above because then
 		// the compare-and-swap condition would always be evaluated and the
 		// swap happens unconditionally from the buffer fullness.
@@ -297,9 +488,66 @@ func (r *RunningOutput) triggerBatchCheck() {
 	}
 }
 
+// batchReady returns true if the source buffer holds at least a full batch in
+// terms of the number of metrics or the batch size in bytes.
+func (r *RunningOutput) batchReady() bool {
+	buffer := r.source()
+	if buffer.Len() >= r.batchSize() {
+		return true
+	}
+	if r.Config.MetricBatchBytes > 0 {
+		if s, ok := buffer.(sizedBuffer); ok {
+			return s.byteLen() >= r.Config.MetricBatchBytes
+		}
+	}
+	return false
+}
+
+// batchSize returns the maximum number of metrics of a batch which is tuned
+// if enabled.
+func (r *RunningOutput) batchSize() int {
+	if r.tuner != nil {
+		return r.tuner.current()
+	}
+	return r.MetricBatchSize
+}
+
 // Write writes all metrics to the output, stopping when all have been sent on
-// or error.
+// or error. Writes are skipped during the retry backoff or while the circuit
+// breaker is open, if enabled.
 func (r *RunningOutput) Write() error {
+	return r.WriteContext(context.Background())
+}
+
+// WriteContext writes the metrics of the output like Write but does not start
+// new batches once the context is done. Batches already in flight are not
+// interrupted.
+func (r *RunningOutput) WriteContext(ctx context.Context) error {
+	write := func() (int, error) {
+		return r.write(ctx)
+	}
+	if g := r.failover.Load(); g != nil {
+		return g.write(r, r.guardWrite(write))
+	}
+	_, err := r.guardWrite(write)()
+	return err
+}
+
+// WriteBatch writes a single batch of metrics to the output.
+func (r *RunningOutput) WriteBatch() error {
+	if g := r.failover.Load(); g != nil {
+		return g.write(r, r.guardWrite(r.writeBatch))
+	}
+	_, err := r.guardWrite(r.writeBatch)()
+	return err
+}
+
+// write writes all metrics of the source buffer until the context is done and
+// returns the number of metrics accepted by the output.
+func (r *RunningOutput) write(ctx context.Context) (int, error) {
+	r.activeWrites.Add(1)
+	defer r.activeWrites.Add(-1)
+
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
@@ -307,7 +555,7 @@ func (r *RunningOutput) Write() error {
 			var serr *internal.StartupError
 			if !errors.As(err, &serr) || !serr.Retry || !serr.Partial {
 				r.StartupErrors.Incr(1)
-				return internal.ErrNotConnected
+				return 0, internal.ErrNotConnected
 			}
 			r.log.Debugf("Partially connected after %d attempts", r.retries)
 		} else {
@@ -324,35 +572,68 @@ func (r *RunningOutput) Write() error {
 		r.triggerBatchCheck()
 	}()
 
+	buffer := r.source()
 	if output, ok := r.Output.(telegraf.AggregatingOutput); ok {
 		r.aggMutex.Lock()
 		metrics := output.Push()
-		r.buffer.Add(metrics...)
+		buffer.Add(metrics...)
 		output.Reset()
 		r.aggMutex.Unlock()
 	}
 
 	// Only process the metrics in the buffer now. Metrics added while we are
-	// writing will be sent on the next call. We can safely add one more write
-	// because 'doTransaction' will abort early for empty batches.
-	nBuffer := r.buffer.Len()
-	nBatches := nBuffer/r.MetricBatchSize + 1
-	for i := 0; i < nBatches; i++ {
-		if err := r.doTransaction(); err != nil {
-			return err
+	// writing will be sent on the next call. Multiple batches might be in
+	// flight at the same time and the write stops on the first error.
+	var remaining, written atomic.Int64
+	remaining.Store(int64(buffer.Len()))
+
+	var failed atomic.Bool
+	var errOnce sync.Once
+	var writeErr error
+	loop := func() {
+		for remaining.Load() > 0 && !failed.Load() && ctx.Err() == nil {
+			n, batched, err := r.doTransaction(buffer)
+			written.Add(int64(n))
+			if err != nil {
+				failed.Store(true)
+				errOnce.Do(func() { writeErr = err })
+				return
+			}
+			if batched == 0 {
+				return
+			}
+			remaining.Add(-int64(batched))
 		}
 	}
-	return nil
+
+	if r.Config.InFlightBatches <= 1 {
+		loop()
+	} else {
+		var wg sync.WaitGroup
+		for range r.Config.InFlightBatches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				loop()
+			}()
+		}
+		wg.Wait()
+	}
+	return int(written.Load()), writeErr
 }
 
-// WriteBatch writes a single batch of metrics to the output.
-func (r *RunningOutput) WriteBatch() error {
+// writeBatch writes a single batch of metrics of the source buffer and
+// returns the number of metrics accepted by the output.
+func (r *RunningOutput) writeBatch() (int, error) {
+	r.activeWrites.Add(1)
+	defer r.activeWrites.Add(-1)
+
 	// Try to connect if we are not yet started up
 	if !r.started {
 		r.retries++
 		if err := r.Output.Connect(); err != nil {
 			r.StartupErrors.Incr(1)
-			return internal.ErrNotConnected
+			return 0, internal.ErrNotConnected
 		}
 		r.started = true
 		r.log.Debugf("Successfully connected after %d attempts", r.retries)
@@ -366,42 +647,209 @@ func (r *RunningOutput) WriteBatch() error {
 		r.triggerBatchCheck()
 	}()
 
-	return r.doTransaction()
+	n, _, err := r.doTransaction(r.source())
+	return n, err
 }
 
-func (r *RunningOutput) doTransaction() error {
-	tx := r.buffer.BeginTransaction(r.MetricBatchSize)
+// doTransaction writes a batch of metrics from the buffer and returns the
+// number of metrics accepted by the output and the number of metrics written.
+func (r *RunningOutput) doTransaction(buffer Buffer) (int, int, error) {
+	tx := buffer.BeginTransaction(r.batchSize())
 	if len(tx.Batch) == 0 {
-		return nil
+		return 0, 0, nil
+	}
+	if !r.trackTransaction(tx, buffer) {
+		tx.KeepAll()
+		buffer.EndTransaction(tx)
+		return 0, 0, nil
+	}
+
+	// Only write the selected metrics, the remaining metrics are kept in the
+	// buffer for the next transaction
+	selected := r.selectMetrics(tx.Batch)
+	defer r.releaseSeries(tx.Batch, selected)
+	if len(selected) == 0 {
+		if r.untrackTransaction(tx) {
+			tx.KeepAll()
+			buffer.EndTransaction(tx)
+		}
+		return 0, 0, nil
+	}
+
+	batch := make([]telegraf.Metric, 0, len(selected))
+	for _, idx := range selected {
+		batch = append(batch, tx.Batch[idx])
+	}
+	sub := &Transaction{Batch: batch}
+
+	elapsed, err := r.writeMetrics(sub.Batch)
+	if !r.untrackTransaction(tx) {
+		// The metrics were handed back to the buffer while writing
+		return 0, 0, nil
+	}
+	r.updateTransaction(sub, err)
+	if r.tuner != nil {
+		r.tuner.update(len(sub.Batch), elapsed, err)
+	}
+	if r.batchStat != nil {
+		r.batchStat.Set(int64(r.batchSize()))
+	}
+
+	// Map the indices of the written metrics back to the transaction
+	for _, idx := range sub.Accept {
+		tx.Accept = append(tx.Accept, selected[idx])
+	}
+	for _, idx := range sub.Reject {
+		tx.Reject = append(tx.Reject, selected[idx])
 	}
-	err := r.writeMetrics(tx.Batch)
-	r.updateTransaction(tx, err)
-	r.buffer.EndTransaction(tx)
+
+	// Send the rejected metrics to the dead-letter output before the buffer
+	// releases them
+	if len(tx.Reject) > 0 {
+		reason := DeadLetterRejected
+		if errors.Is(err, internal.ErrSerialization) {
+			reason = DeadLetterSerializationError
+		}
+		for _, idx := range tx.Reject {
+			r.sendToDeadLetter(tx.Batch[idx], reason)
+		}
+	}
+	accepted, batched := len(tx.Accept), len(sub.Batch)
+	buffer.EndTransaction(tx)
 
 	if err != nil {
 		r.WriteErrors.Incr(1)
 		GlobalWriteErrors.Incr(1)
-		return err
+		r.lastError.Store(time.Now().UnixNano())
+		return accepted, batched, err
 	}
 
-	return nil
+	return accepted, batched, nil
+}
+
+// trackTransaction registers the transaction of a running write. It returns
+// false if the writes of the output were abandoned.
+func (r *RunningOutput) trackTransaction(tx *Transaction, buffer Buffer) bool {
+	r.inflightLock.Lock()
+	defer r.inflightLock.Unlock()
+
+	if r.abandoned {
+		return false
+	}
+	if r.inflight == nil {
+		r.inflight = make(map[*Transaction]Buffer)
+	}
+	r.inflight[tx] = buffer
+	return true
+}
+
+// untrackTransaction unregisters the transaction of a finished write. It
+// returns false if the transaction was handed back to the buffer already.
+func (r *RunningOutput) untrackTransaction(tx *Transaction) bool {
+	r.inflightLock.Lock()
+	defer r.inflightLock.Unlock()
+
+	_, found := r.inflight[tx]
+	delete(r.inflight, tx)
+	return found
+}
+
+// AbandonWrites stops the output from starting new batches and hands the
+// batches of the writes still running back to the buffer, e.g. to spool them
+// on shutdown. Metrics of a running write are thus kept even if the write
+// succeeds later. The number of metrics handed back is returned.
+func (r *RunningOutput) AbandonWrites() int {
+	r.inflightLock.Lock()
+	defer r.inflightLock.Unlock()
+
+	r.abandoned = true
+	var n int
+	for tx, buffer := range r.inflight {
+		tx.KeepAll()
+		buffer.EndTransaction(tx)
+		n += len(tx.Batch)
+	}
+	clear(r.inflight)
+	return n
+}
+
+// Writing returns true while a write of the output is running.
+func (r *RunningOutput) Writing() bool {
+	return r.activeWrites.Load() > 0
+}
+
+// selectMetrics returns the indices of the batch metrics to write. With
+// strict series ordering, metrics of series in other batches in flight are
+// skipped and the series of the selected metrics are marked as in flight.
+// The selected metrics are limited to the batch size in bytes, if any.
+func (r *RunningOutput) selectMetrics(batch []telegraf.Metric) []int {
+	if r.inflightSeries != nil {
+		r.seriesLock.Lock()
+		defer r.seriesLock.Unlock()
+	}
+
+	selected := make([]int, 0, len(batch))
+	metrics := make([]telegraf.Metric, 0, len(batch))
+	for i, m := range batch {
+		if r.inflightSeries != nil && r.inflightSeries[m.HashID()] > 0 {
+			continue
+		}
+		selected = append(selected, i)
+		metrics = append(metrics, m)
+	}
+
+	if r.sizer != nil && len(metrics) > 0 {
+		n, size := r.sizer.fit(metrics, r.Config.MetricBatchBytes)
+		selected = selected[:n]
+		r.bytesStat.Set(size)
+	}
+
+	if r.inflightSeries != nil {
+		for _, idx := range selected {
+			r.inflightSeries[batch[idx].HashID()]++
+		}
+	}
+	return selected
+}
+
+// releaseSeries unmarks the series of the selected metrics as in flight. This
+// must be called after the transaction ended to keep the metrics of a series
+// in order.
+func (r *RunningOutput) releaseSeries(batch []telegraf.Metric, selected []int) {
+	if r.inflightSeries == nil {
+		return
+	}
+
+	r.seriesLock.Lock()
+	defer r.seriesLock.Unlock()
+	for _, idx := range selected {
+		id := batch[idx].HashID()
+		if r.inflightSeries[id] <= 1 {
+			delete(r.inflightSeries, id)
+		} else {
+			r.inflightSeries[id]--
+		}
+	}
 }
 
-func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
+func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) (time.Duration, error) {
 	if dropped := r.droppedMetrics.Load(); dropped > 0 {
 		r.log.Warnf("Metric buffer overflow; %d metrics have been dropped", dropped)
 		r.droppedMetrics.Add(-dropped)
 	}
 
+	span := startSpan("write", "outputs", r.Config.Name, r.ID(), r.Config.Alias)
+
 	start := time.Now()
-	err := r.Output.Write(metrics)
+	err := r.crash.call(func() error { return r.Output.Write(metrics) })
 	elapsed := time.Since(start)
 	r.WriteTime.Incr(elapsed.Nanoseconds())
+	endSpan(span, int64(len(metrics)), err)
 
 	if err == nil {
 		r.log.Debugf("Wrote batch of %d metrics in %s", len(metrics), elapsed)
 	}
-	return err
+	return elapsed, err
 }
 
 func (r *RunningOutput) updateTransaction(tx *Transaction, err error) {
@@ -429,9 +877,86 @@ func (r *RunningOutput) updateTransaction(tx *Transaction, err error) {
 	tx.Reject = writeErr.MetricsReject
 }
 
+// SetDeadLetter sets the output receiving the metrics rejected by or dropped
+// from this output. Passing nil disables the dead-letter routing.
+func (r *RunningOutput) SetDeadLetter(output *RunningOutput) {
+	r.deadLetter.Store(output)
+}
+
+// DeadLetter returns the output receiving the metrics rejected by or dropped
+// from this output or nil if not set.
+func (r *RunningOutput) DeadLetter() *RunningOutput {
+	return r.deadLetter.Load()
+}
+
+// LinkDeadLetters resolves the dead-letter outputs configured for the given
+// outputs by alias or ID and sets them on the outputs. An output must not
+// use itself as dead-letter output and the dead-letter outputs must not form
+// a cycle. Outputs with an invalid dead-letter setting are not linked and
+// the errors are returned.
+func LinkDeadLetters(outputs []*RunningOutput) error {
+	var errs []error
+	targets := make(map[*RunningOutput]*RunningOutput, len(outputs))
+	for _, output := range outputs {
+		name := output.Config.DeadLetter
+		if name == "" {
+			continue
+		}
+
+		var matches []*RunningOutput
+		for _, candidate := range outputs {
+			if candidate.Config.Alias == name || candidate.ID() == name {
+				matches = append(matches, candidate)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			errs = append(errs, fmt.Errorf("dead-letter output %q of %s not found", name, output.LogName()))
+		case len(matches) > 1:
+			errs = append(errs, fmt.Errorf("dead-letter output %q of %s is ambiguous", name, output.LogName()))
+		case matches[0] == output:
+			errs = append(errs, fmt.Errorf("%s cannot be its own dead-letter output", output.LogName()))
+		default:
+			targets[output] = matches[0]
+		}
+	}
+
+	for _, output := range outputs {
+		visited := map[*RunningOutput]bool{output: true}
+		for next := targets[output]; next != nil; next = targets[next] {
+			if visited[next] {
+				errs = append(errs, fmt.Errorf("dead-letter outputs of %s form a cycle", output.LogName()))
+				delete(targets, output)
+				break
+			}
+			visited[next] = true
+		}
+	}
+
+	for _, output := range outputs {
+		output.SetDeadLetter(targets[output])
+	}
+	return errors.Join(errs...)
+}
+
+// sendToDeadLetter adds a copy of the metric to the dead-letter output tagged
+// with the reason and the ID of this output. Tracking information is not
+// transferred to the copy.
+func (r *RunningOutput) sendToDeadLetter(m telegraf.Metric, reason string) {
+	output := r.deadLetter.Load()
+	if output == nil {
+		return
+	}
+
+	dm := metric.FromMetric(m)
+	dm.AddTag("dead_letter_reason", reason)
+	dm.AddTag("dead_letter_output", r.ID())
+	output.AddMetricNoCopy(dm)
+}
+
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
-	if r.Config.BufferStrategy == "disk_write_through" {
+	if r.unboundedBuffer() {
 		r.log.Debugf("Buffer fullness: %d metrics", nBuffer)
 	} else {
 		r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -445,3 +970,39 @@ func (r *RunningOutput) Log() telegraf.Logger {
 func (r *RunningOutput) BufferLength() int {
 	return r.buffer.Len()
 }
+
+// BufferFull returns true if the buffer the output writes from cannot take
+// the given number of metrics without overwriting the oldest ones. An empty
+// buffer is never full and disk and hybrid buffers never overwrite metrics.
+func (r *RunningOutput) BufferFull(headroom int) bool {
+	if r.unboundedBuffer() {
+		return false
+	}
+	n := r.source().Len()
+	return n > 0 && n+max(headroom, 1) > r.MetricBufferLimit
+}
+
+// unboundedBuffer returns true if the buffer strategy of the output moves
+// metrics to disk instead of being limited by the metric_buffer_limit.
+func (r *RunningOutput) unboundedBuffer() bool {
+	switch r.Config.BufferStrategy {
+	case "disk_write_through", "hybrid":
+		return true
+	}
+	return false
+}
+
+// BufferStats returns the statistics of the output's metric buffer.
+func (r *RunningOutput) BufferStats() BufferStats {
+	return r.buffer.Stats()
+}
+
+// LastErrorTime returns the time of the last failed write or the zero time
+// if no write failed yet.
+func (r *RunningOutput) LastErrorTime() time.Time {
+	ts := r.lastError.Load()
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ts)
+}
@@ -805,6 +805,7 @@ func TestRunningOutputInternalMetrics(t *testing.T) {
 				"metrics_dropped":  0,
 				"metrics_filtered": 0,
 				"metrics_written":  0,
+				"restarts":         0,
 				"write_errors":     0,
 				"write_time_ns":    0,
 				"startup_errors":   0,
@@ -838,6 +839,16 @@ func TestRunningOutputStartupBehaviorInvalid(t *testing.T) {
 	require.ErrorContains(t, ro.Init(), "invalid 'startup_error_behavior'")
 }
 
+func TestRunningOutputInFlightBatchesUnsupported(t *testing.T) {
+	ro, err := NewRunningOutput(&perfOutput{}, &OutputConfig{Name: "test", InFlightBatches: 2}, 5, 10)
+	require.NoError(t, err)
+	require.ErrorContains(t, ro.Init(), "'in_flight_batches' requires an output supporting concurrent writes")
+
+	ro, err = NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "test", InFlightBatches: 2}, 5, 10)
+	require.NoError(t, err)
+	require.NoError(t, ro.Init())
+}
+
 func TestRunningOutputRetryableStartupBehaviorDefault(t *testing.T) {
 	serr := &internal.StartupError{
 		Err:   errors.New("retryable err"),
@@ -1338,6 +1349,348 @@ func TestRunningOutputStatisticsWriteErrorsCount(t *testing.T) {
 }
 
 // Benchmark adding metrics.
+func TestRunningOutputDeadLetterRejected(t *testing.T) {
+	lost := 0
+	plugin := &mockOutput{
+		batchAcceptSize:  4,
+		metricFatalIndex: &lost,
+	}
+	model, err := NewRunningOutput(plugin, &OutputConfig{ID: "source"}, 5, 10)
+	require.NoError(t, err)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	dlqPlugin := &mockOutput{}
+	dlq, err := NewRunningOutput(dlqPlugin, &OutputConfig{}, 5, 10)
+	require.NoError(t, err)
+	defer dlq.Close()
+	model.SetDeadLetter(dlq)
+
+	for _, mt := range first5 {
+		model.AddMetric(mt)
+	}
+	for _, mt := range next5 {
+		model.AddMetric(mt)
+	}
+	require.ErrorIs(t, model.Write(), internal.ErrSizeLimitReached)
+	require.ErrorIs(t, model.Write(), internal.ErrSizeLimitReached)
+	require.NoError(t, model.Write())
+
+	expected := make([]telegraf.Metric, 0, 2)
+	for _, m := range []telegraf.Metric{first5[0], first5[4]} {
+		m = m.Copy()
+		m.AddTag("dead_letter_reason", "rejected")
+		m.AddTag("dead_letter_output", "source")
+		expected = append(expected, m)
+	}
+	require.NoError(t, dlq.Write())
+	testutil.RequireMetricsEqual(t, expected, dlqPlugin.Metrics())
+}
+
+func TestRunningOutputDeadLetterSerializationError(t *testing.T) {
+	plugin := &mockOutput{
+		preWriteHook: func(metrics []telegraf.Metric) error {
+			werr := &internal.PartialWriteError{Err: internal.ErrSerialization}
+			for i := range metrics {
+				if i == 1 {
+					werr.MetricsReject = append(werr.MetricsReject, i)
+				} else {
+					werr.MetricsAccept = append(werr.MetricsAccept, i)
+				}
+			}
+			return werr
+		},
+	}
+	model, err := NewRunningOutput(plugin, &OutputConfig{ID: "source"}, 5, 10)
+	require.NoError(t, err)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	dlqPlugin := &mockOutput{}
+	dlq, err := NewRunningOutput(dlqPlugin, &OutputConfig{}, 5, 10)
+	require.NoError(t, err)
+	defer dlq.Close()
+	model.SetDeadLetter(dlq)
+
+	for _, mt := range first5 {
+		model.AddMetric(mt)
+	}
+	require.ErrorIs(t, model.Write(), internal.ErrSerialization)
+	require.Zero(t, model.BufferLength())
+
+	expected := first5[1].Copy()
+	expected.AddTag("dead_letter_reason", "serialization_error")
+	expected.AddTag("dead_letter_output", "source")
+	require.NoError(t, dlq.Write())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, dlqPlugin.Metrics())
+}
+
+func TestRunningOutputDeadLetterOverflow(t *testing.T) {
+	model, err := NewRunningOutput(&mockOutput{}, &OutputConfig{ID: "source"}, 5, 5)
+	require.NoError(t, err)
+	require.NoError(t, model.Init())
+	defer model.Close()
+
+	dlqPlugin := &mockOutput{}
+	dlq, err := NewRunningOutput(dlqPlugin, &OutputConfig{}, 5, 10)
+	require.NoError(t, err)
+	defer dlq.Close()
+	model.SetDeadLetter(dlq)
+
+	// Overflow the buffer and expect the oldest metrics in the dead-letter
+	// output
+	for _, mt := range first5 {
+		model.AddMetric(mt)
+	}
+	for _, mt := range next5 {
+		model.AddMetric(mt)
+	}
+
+	expected := make([]telegraf.Metric, 0, len(first5))
+	for _, m := range first5 {
+		m = m.Copy()
+		m.AddTag("dead_letter_reason", "overflow")
+		m.AddTag("dead_letter_output", "source")
+		expected = append(expected, m)
+	}
+	require.NoError(t, dlq.Write())
+	testutil.RequireMetricsEqual(t, expected, dlqPlugin.Metrics())
+}
+
+func TestRunningOutputBatchBytes(t *testing.T) {
+	size := lineProtocolSize(first5[0])
+
+	var batches []int
+	plugin := &mockOutput{
+		preWriteHook: func(metrics []telegraf.Metric) error {
+			batches = append(batches, len(metrics))
+			return nil
+		},
+	}
+	model, err := NewRunningOutput(plugin, &OutputConfig{Name: "test", ID: "batch_bytes", MetricBatchBytes: int64(2 * size)}, 1000, 100)
+	require.NoError(t, err)
+	defer model.Close()
+
+	for _, mt := range first5 {
+		model.AddMetric(mt)
+	}
+	require.NoError(t, model.Write())
+	require.Equal(t, []int{2, 2, 1}, batches)
+	testutil.RequireMetricsEqual(t, first5, plugin.Metrics())
+	require.Equal(t, int64(size), model.bytesStat.Get())
+}
+
+func TestRunningOutputBufferBytes(t *testing.T) {
+	size := lineProtocolSize(first5[0])
+
+	plugin := &mockOutput{}
+	model, err := NewRunningOutput(plugin, &OutputConfig{Name: "test", ID: "buffer_bytes", MetricBufferBytes: int64(3 * size)}, 1000, 100)
+	require.NoError(t, err)
+	defer model.Close()
+	dropped := model.BufferStats().MetricsDropped.Get()
+
+	// The oldest metrics exceeding the byte limit are dropped
+	for _, mt := range first5 {
+		model.AddMetric(mt)
+	}
+	require.Equal(t, 3, model.BufferLength())
+	require.Equal(t, int64(2), model.BufferStats().MetricsDropped.Get()-dropped)
+	require.Equal(t, int64(3*size), model.buffer.(sizedBuffer).byteLen())
+
+	require.NoError(t, model.Write())
+	testutil.RequireMetricsEqual(t, first5[2:], plugin.Metrics())
+	require.Zero(t, model.buffer.(sizedBuffer).byteLen())
+}
+
+func TestRunningOutputBatchAutoTune(t *testing.T) {
+	var fail atomic.Bool
+	plugin := &mockOutput{
+		preWriteHook: func([]telegraf.Metric) error {
+			if fail.Load() {
+				return errors.New("request too large")
+			}
+			return nil
+		},
+	}
+	model, err := NewRunningOutput(plugin, &OutputConfig{Name: "test", ID: "auto_tune", BatchAutoTune: true}, 4, 100)
+	require.NoError(t, err)
+	defer model.Close()
+
+	for _, mt := range append(first5, next5...) {
+		model.AddMetric(mt)
+	}
+
+	// Failed writes shrink the batch
+	fail.Store(true)
+	require.Error(t, model.WriteBatch())
+	require.Equal(t, 2, model.batchSize())
+	require.Equal(t, int64(2), model.batchStat.Get())
+
+	// Fast writes of full batches grow the batch up to the configured size
+	fail.Store(false)
+	require.NoError(t, model.WriteBatch())
+	require.Equal(t, 3, model.batchSize())
+	require.NoError(t, model.WriteBatch())
+	require.Equal(t, 4, model.batchSize())
+	require.NoError(t, model.WriteBatch())
+	require.Equal(t, 4, model.batchSize())
+	testutil.RequireMetricsEqual(t, append(first5, next5[:4]...), plugin.Metrics())
+}
+
+func TestRunningOutputInFlightBatches(t *testing.T) {
+	// Block the first writes until all batches are in flight
+	var started atomic.Int32
+	var timeout atomic.Bool
+	release := make(chan struct{})
+	plugin := &mockOutput{}
+	plugin.preWriteHook = func([]telegraf.Metric) error {
+		// Writes of the mock output are serialized so unlock while blocking
+		plugin.Unlock()
+		defer plugin.Lock()
+
+		if started.Add(1) == 3 {
+			close(release)
+		}
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+			timeout.Store(true)
+		}
+		return nil
+	}
+
+	model, err := NewRunningOutput(plugin, &OutputConfig{Name: "test", ID: "inflight", InFlightBatches: 3}, 2, 100)
+	require.NoError(t, err)
+	defer model.Close()
+
+	expected := append(append([]telegraf.Metric{}, first5...), next5...)
+	for _, m := range expected {
+		model.AddMetric(m)
+	}
+	require.NoError(t, model.Write())
+	require.False(t, timeout.Load(), "batches not written concurrently")
+	require.Zero(t, model.BufferLength())
+	testutil.RequireMetricsEqual(t, expected, plugin.Metrics(), testutil.SortMetrics())
+}
+
+func TestRunningOutputStrictSeriesOrdering(t *testing.T) {
+	model, err := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "test", ID: "ordering", StrictSeriesOrdering: true}, 10, 100)
+	require.NoError(t, err)
+	defer model.Close()
+
+	a1 := testutil.TestMetric(1, "a")
+	a2 := testutil.TestMetric(2, "a")
+	b1 := testutil.TestMetric(1, "b")
+
+	// Metrics of series in flight are skipped until the series is released
+	inflight := []telegraf.Metric{a1}
+	require.Equal(t, []int{0}, model.selectMetrics(inflight))
+	batch := []telegraf.Metric{a2, b1}
+	require.Equal(t, []int{1}, model.selectMetrics(batch))
+	model.releaseSeries(batch, []int{1})
+	model.releaseSeries(inflight, []int{0})
+	require.Equal(t, []int{0, 1}, model.selectMetrics(batch))
+	model.releaseSeries(batch, []int{0, 1})
+	require.Empty(t, model.inflightSeries)
+}
+
+func TestRunningOutputStrictSeriesOrderingWrite(t *testing.T) {
+	var lock sync.Mutex
+	inflight := make(map[uint64]bool)
+	var violations int
+	plugin := &mockOutput{}
+	plugin.preWriteHook = func(metrics []telegraf.Metric) error {
+		plugin.Unlock()
+		defer plugin.Lock()
+
+		lock.Lock()
+		for _, m := range metrics {
+			if inflight[m.HashID()] {
+				violations++
+			}
+			inflight[m.HashID()] = true
+		}
+		lock.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		lock.Lock()
+		for _, m := range metrics {
+			delete(inflight, m.HashID())
+		}
+		lock.Unlock()
+		return nil
+	}
+
+	model, err := NewRunningOutput(plugin, &OutputConfig{
+		Name:                 "test",
+		ID:                   "ordering_write",
+		InFlightBatches:      4,
+		StrictSeriesOrdering: true,
+	}, 1, 100)
+	require.NoError(t, err)
+	defer model.Close()
+
+	// Interleave the metrics of two series
+	expected := make([]telegraf.Metric, 0, 20)
+	for i := range 10 {
+		expected = append(expected, testutil.TestMetric(i, "a"), testutil.TestMetric(i, "b"))
+	}
+	for _, m := range expected {
+		model.AddMetric(m)
+	}
+	for model.BufferLength() > 0 {
+		require.NoError(t, model.Write())
+	}
+	require.Zero(t, violations)
+
+	// The metrics of each series must be written in order
+	values := make(map[string][]interface{})
+	for _, m := range plugin.Metrics() {
+		v, _ := m.GetField("value")
+		values[m.Name()] = append(values[m.Name()], v)
+	}
+	for _, name := range []string{"a", "b"} {
+		require.Len(t, values[name], 10)
+		for i, v := range values[name] {
+			require.EqualValues(t, i, v)
+		}
+	}
+}
+
+func TestLinkDeadLetters(t *testing.T) {
+	newOutput := func(id, alias, deadLetter string) *RunningOutput {
+		ro, err := NewRunningOutput(&mockOutput{}, &OutputConfig{ID: id, Alias: alias, DeadLetter: deadLetter}, 5, 10)
+		require.NoError(t, err)
+		return ro
+	}
+
+	// Link by alias and ID
+	a := newOutput("a", "", "dlq")
+	b := newOutput("b", "", "c")
+	c := newOutput("c", "dlq", "")
+	require.NoError(t, LinkDeadLetters([]*RunningOutput{a, b, c}))
+	require.Same(t, c, a.DeadLetter())
+	require.Same(t, c, b.DeadLetter())
+	require.Nil(t, c.DeadLetter())
+
+	// Unknown output
+	a = newOutput("a", "", "unknown")
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{a}), "not found")
+	require.Nil(t, a.DeadLetter())
+
+	// Self-reference
+	a = newOutput("a", "", "a")
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{a}), "its own dead-letter output")
+
+	// Cycle
+	a = newOutput("a", "", "b")
+	b = newOutput("b", "", "a")
+	require.ErrorContains(t, LinkDeadLetters([]*RunningOutput{a, b}), "form a cycle")
+}
+
 func BenchmarkRunningOutputAddWrite(b *testing.B) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -1383,6 +1736,39 @@ func BenchmarkRunningOutputAddFailWrites(b *testing.B) {
 	}
 }
 
+func TestRunningOutputRefreshSecrets(t *testing.T) {
+	// Outputs are reconnected by default
+	o := &panickingOutput{}
+	ro, err := NewRunningOutput(o, &OutputConfig{Name: "panicking", ID: "refresh-reconnect"}, 10, 10)
+	require.NoError(t, err)
+	require.NoError(t, ro.Init())
+	require.NoError(t, ro.Connect())
+	require.NoError(t, ro.RefreshSecrets())
+	require.Equal(t, 2, o.connects)
+	require.Equal(t, 1, o.closes)
+
+	// Outputs implementing the refresher are not reconnected
+	r := &refreshingOutput{}
+	ro, err = NewRunningOutput(r, &OutputConfig{Name: "refreshing", ID: "refresh-refresher"}, 10, 10)
+	require.NoError(t, err)
+	require.NoError(t, ro.Init())
+	require.NoError(t, ro.Connect())
+	require.NoError(t, ro.RefreshSecrets())
+	require.Equal(t, 1, r.refreshes)
+	require.Equal(t, 1, r.connects)
+	require.Zero(t, r.closes)
+}
+
+type refreshingOutput struct {
+	panickingOutput
+	refreshes int
+}
+
+func (o *refreshingOutput) RefreshSecrets() error {
+	o.refreshes++
+	return nil
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -1468,6 +1854,11 @@ func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	return resultErr
 }
 
+// Writes of the mock output are serialized
+func (*mockOutput) SupportsConcurrentWrites() bool {
+	return true
+}
+
 func (m *mockOutput) Metrics() []telegraf.Metric {
 	m.Lock()
 	defer m.Unlock()
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	logging "github.com/influxdata/telegraf/logger"
@@ -13,6 +15,12 @@ type RunningProcessor struct {
 	log       telegraf.Logger
 	Processor telegraf.StreamingProcessor
 	Config    *ProcessorConfig
+
+	ProcessTime   selfstat.Stat
+	MetricsPassed selfstat.Stat
+
+	acc   telegraf.Accumulator
+	crash *crashGuard
 }
 
 type RunningProcessors []*RunningProcessor
@@ -25,11 +33,14 @@ func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp
 type ProcessorConfig struct {
 	Name     string
 	Source   string
+	Line     int
 	Alias    string
 	ID       string
+	Labels   map[string]string
 	Order    int64
 	Filter   Filter
 	LogLevel string
+	Pipeline string
 }
 
 func NewRunningProcessor(processor telegraf.StreamingProcessor, config *ProcessorConfig) *RunningProcessor {
@@ -52,11 +63,23 @@ func NewRunningProcessor(processor telegraf.StreamingProcessor, config *Processo
 	SetLoggerOnPlugin(processor, logger)
 	SetStatisticsOnPlugin(processor, logger, tags)
 
-	return &RunningProcessor{
+	rp := &RunningProcessor{
 		Processor: processor,
 		Config:    config,
-		log:       logger,
+		ProcessTime: selfstat.RegisterTiming(
+			"process",
+			"process_time_ns",
+			tags,
+		),
+		MetricsPassed: selfstat.Register(
+			"process",
+			"metrics_passed",
+			tags,
+		),
+		log: logger,
 	}
+	rp.crash = newCrashGuard(logger, rp.restart, selfstat.Register("process", "restarts", tags))
+	return rp
 }
 
 func (*RunningProcessor) metricFiltered(metric telegraf.Metric) {
@@ -73,6 +96,13 @@ func (rp *RunningProcessor) Init() error {
 	return nil
 }
 
+// StatefulPlugin returns the state of a stateful processor plugin, also for
+// processors upgraded to streaming ones. A checkpoint of the state is taken
+// between two processed metrics.
+func (rp *RunningProcessor) StatefulPlugin() (telegraf.StatefulPlugin, bool) {
+	return newGuardedState(rp.Processor, rp.crash)
+}
+
 func (rp *RunningProcessor) ID() string {
 	if p, ok := rp.Processor.(telegraf.PluginWithID); ok {
 		return p.ID()
@@ -93,6 +123,7 @@ func (*RunningProcessor) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 }
 
 func (rp *RunningProcessor) Start(acc telegraf.Accumulator) error {
+	rp.acc = acc
 	return rp.Processor.Start(acc)
 }
 
@@ -113,9 +144,37 @@ func (rp *RunningProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) err
 		return nil
 	}
 
-	return rp.Processor.Add(m, acc)
+	span := startSpan("process", "processors", rp.Config.Name, rp.ID(), rp.Config.Alias)
+	start := time.Now()
+	err = rp.crash.call(func() error { return rp.Processor.Add(m, acc) })
+	if rp.ProcessTime != nil {
+		rp.ProcessTime.Incr(time.Since(start).Nanoseconds())
+	}
+	endSpan(span, 1, err)
+
+	// Pass the metrics on unprocessed while the processor awaits its restart
+	// without logging an error for each metric
+	if errors.Is(err, ErrPluginCrashed) {
+		if rp.MetricsPassed != nil {
+			rp.MetricsPassed.Incr(1)
+		}
+		acc.AddMetric(m)
+		return nil
+	}
+	return err
 }
 
 func (rp *RunningProcessor) Stop() {
 	rp.Processor.Stop()
 }
+
+// restart stops, initializes and starts the processor again after a panic.
+func (rp *RunningProcessor) restart() error {
+	if err := protect(func() error { rp.Processor.Stop(); return nil }); err != nil {
+		rp.log.Debugf("Stopping plugin failed: %v", err)
+	}
+	if err := rp.Init(); err != nil {
+		return err
+	}
+	return rp.Processor.Start(rp.acc)
+}
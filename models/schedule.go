@@ -0,0 +1,35 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser parses cron expressions with five fields, an optional
+// leading seconds field, and descriptors such as "@daily".
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ParseSchedule parses the cron expression of an input schedule evaluated in
+// the given timezone. The local timezone is used if the timezone is empty.
+func ParseSchedule(spec, timezone string) (cron.Schedule, error) {
+	expr := spec
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid schedule timezone %q: %w", timezone, err)
+		}
+		expr = "CRON_TZ=" + timezone + " " + spec
+	}
+
+	schedule, err := scheduleParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q: never triggers", spec)
+	}
+	return schedule, nil
+}
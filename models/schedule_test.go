@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		spec     string
+		timezone string
+		now      time.Time
+		expected []time.Time
+	}{
+		{
+			name: "daily",
+			spec: "0 2 * * *",
+			now:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 3, 2, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "weekdays in timezone",
+			spec:     "30 2 * * 1-5",
+			timezone: "Europe/Amsterdam",
+			now:      time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), // Friday
+			expected: []time.Time{
+				time.Date(2024, 3, 4, 2, 30, 0, 0, amsterdam),
+				time.Date(2024, 3, 5, 2, 30, 0, 0, amsterdam),
+			},
+		},
+		{
+			name: "with seconds",
+			spec: "15 */10 * * * *",
+			now:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 1, 12, 0, 15, 0, time.UTC),
+				time.Date(2024, 3, 1, 12, 10, 15, 0, time.UTC),
+			},
+		},
+		{
+			name: "descriptor",
+			spec: "@hourly",
+			now:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec, tt.timezone)
+			require.NoError(t, err)
+
+			next := tt.now
+			for _, expected := range tt.expected {
+				next = schedule.Next(next)
+				require.True(t, expected.Equal(next), "expected %s, got %s", expected, next)
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	_, err := ParseSchedule("* * *", "")
+	require.ErrorContains(t, err, `invalid schedule "* * *"`)
+
+	_, err = ParseSchedule("0 0 30 2 *", "")
+	require.ErrorContains(t, err, "never triggers")
+
+	_, err = ParseSchedule("@daily", "Nowhere/Special")
+	require.ErrorContains(t, err, "invalid schedule timezone")
+}
+
+func TestRunningInputSchedule(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name:     "scheduled",
+		Schedule: "0 2 * * *",
+	})
+	require.False(t, ri.Scheduled())
+	require.NoError(t, ri.Init())
+	require.True(t, ri.Scheduled())
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	require.Equal(t, time.Date(2024, 3, 2, 2, 0, 0, 0, time.Local), ri.NextGather(now))
+}
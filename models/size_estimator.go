@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// sizeSampleInterval is the number of metrics after which the estimate is
+// recalibrated using the serializer of the output.
+const sizeSampleInterval = 100
+
+// sizeEstimator estimates the serialized size of metrics in bytes. The
+// estimate is based on the size of the metric in line protocol, scaled by
+// the ratio between the serializer output and the line-protocol size of
+// sampled metrics.
+type sizeEstimator struct {
+	serializer telegraf.Serializer
+
+	sync.Mutex
+	ratio   float64
+	counter uint64
+}
+
+func newSizeEstimator(serializer telegraf.Serializer) *sizeEstimator {
+	return &sizeEstimator{
+		serializer: serializer,
+		ratio:      1.0,
+	}
+}
+
+// size returns the estimated serialized size of the metric in bytes.
+func (e *sizeEstimator) size(m telegraf.Metric) int {
+	estimate := lineProtocolSize(m)
+	if e.serializer == nil {
+		return estimate
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if e.counter%sizeSampleInterval == 0 {
+		if buf, err := e.serializer.Serialize(m); err == nil && estimate > 0 {
+			ratio := float64(len(buf)) / float64(estimate)
+			if e.counter == 0 {
+				e.ratio = ratio
+			} else {
+				e.ratio = 0.8*e.ratio + 0.2*ratio
+			}
+		}
+	}
+	e.counter++
+
+	return int(float64(estimate)*e.ratio + 0.5)
+}
+
+// fit returns the number of metrics from the start of the batch fitting into
+// the given number of bytes and their estimated size. At least one metric is
+// returned for non-empty batches, a limit of zero fits all metrics.
+func (e *sizeEstimator) fit(batch []telegraf.Metric, limit int64) (int, int64) {
+	var total int64
+	for i, m := range batch {
+		size := int64(e.size(m))
+		if limit > 0 && i > 0 && total+size > limit {
+			return i, total
+		}
+		total += size
+	}
+	return len(batch), total
+}
+
+// lineProtocolSize returns the approximate size of the metric in line
+// protocol without escaping.
+func lineProtocolSize(m telegraf.Metric) int {
+	size := len(m.Name())
+	for _, tag := range m.TagList() {
+		size += len(tag.Key) + len(tag.Value) + 2
+	}
+	for i, field := range m.FieldList() {
+		if i > 0 {
+			size++
+		}
+		size += len(field.Key) + 1
+		switch v := field.Value.(type) {
+		case string:
+			size += len(v) + 2
+		case int64:
+			size += len(strconv.FormatInt(v, 10)) + 1
+		case uint64:
+			size += len(strconv.FormatUint(v, 10)) + 1
+		case float64:
+			size += len(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			size += 5
+		default:
+			size += 8
+		}
+	}
+	// Separators, timestamp and newline
+	return size + 2 + 19 + 1
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tidwall/wal"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// spoolSuffix is appended to the output ID to get the name of the spool of
+// the output within the spool directory
+const spoolSuffix = ".spool"
+
+// SpoolPath returns the location of the spool of the output with the given
+// ID within the spool directory.
+func SpoolPath(dir, id string) string {
+	return filepath.Join(dir, id+spoolSuffix)
+}
+
+// Spool moves the metrics remaining in the buffer to the spool in the given
+// directory and returns the number of spooled metrics. Outputs with a buffer
+// persisted on disk are not spooled and only the metrics in memory of hybrid
+// buffers are. Tracking metrics are rejected instead of spooled as their
+// source is expected to deliver them again.
+func (r *RunningOutput) Spool(dir string) (int, error) {
+	if _, ok := r.buffer.(*DiskBuffer); ok || r.buffer.Len() == 0 {
+		return 0, nil
+	}
+	begin, end := r.buffer.BeginTransaction, r.buffer.EndTransaction
+	if h, ok := r.buffer.(*HybridBuffer); ok {
+		begin, end = h.beginMemoryTransaction, h.endMemoryTransaction
+	}
+
+	file, err := wal.Open(SpoolPath(dir, r.ID()), &wal.Options{AllowEmpty: true})
+	if err != nil {
+		return 0, fmt.Errorf("opening spool failed: %w", err)
+	}
+	defer file.Close()
+
+	last, err := file.LastIndex()
+	if err != nil {
+		return 0, fmt.Errorf("reading spool failed: %w", err)
+	}
+	idx := last + 1
+
+	var spooled int
+	for {
+		tx := begin(r.MetricBatchSize)
+		if len(tx.Batch) == 0 {
+			return spooled, nil
+		}
+		tx.spooled = true
+
+		var batch wal.Batch
+		for i, m := range tx.Batch {
+			if _, ok := m.(telegraf.TrackingMetric); ok {
+				tx.Reject = append(tx.Reject, i)
+				continue
+			}
+			data, err := metric.ToBytes(m)
+			if err != nil {
+				r.log.Errorf("Serializing metric for spool failed: %v", err)
+				tx.Reject = append(tx.Reject, i)
+				continue
+			}
+			batch.Write(idx, data)
+			tx.Accept = append(tx.Accept, i)
+			idx++
+		}
+
+		if err := file.WriteBatch(&batch); err != nil {
+			// Keep the metrics in the buffer
+			tx.Accept, tx.Reject = nil, nil
+			end(tx)
+			return spooled, fmt.Errorf("writing spool failed: %w", err)
+		}
+		end(tx)
+		spooled += len(tx.Accept)
+	}
+}
+
+// Unspool re-queues the metrics spooled in the given directory on a previous
+// shutdown into the buffer and removes the spool. The number of re-queued
+// metrics is returned.
+func (r *RunningOutput) Unspool(dir string) (int, error) {
+	path := SpoolPath(dir, r.ID())
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	file, err := wal.Open(path, &wal.Options{AllowEmpty: true})
+	if err != nil {
+		return 0, fmt.Errorf("opening spool failed: %w", err)
+	}
+	metrics, err := readSpool(file)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading spool %q failed: %w", path, err)
+	}
+
+	if dropped := r.buffer.Add(metrics...); dropped > 0 {
+		r.log.Warnf("Dropped %d spooled metrics exceeding the buffer limit", dropped)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return len(metrics), fmt.Errorf("removing spool failed: %w", err)
+	}
+	return len(metrics), nil
+}
+
+func readSpool(file *wal.Log) ([]telegraf.Metric, error) {
+	first, err := file.FirstIndex()
+	if err != nil || first == 0 {
+		return nil, err
+	}
+	last, err := file.LastIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]telegraf.Metric, 0, last-first+1)
+	for idx := first; idx <= last; idx++ {
+		data, err := file.Read(idx)
+		if err != nil {
+			return nil, err
+		}
+		m, err := metric.FromBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
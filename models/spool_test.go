@@ -0,0 +1,116 @@
+package models
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRunningOutputSpool(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &OutputConfig{Name: "spooling", ID: "spooling-output"}
+
+	ro, err := NewRunningOutput(&mockOutput{}, cfg, 2, 10)
+	require.NoError(t, err)
+
+	// Tracking metrics are rejected instead of spooled
+	var delivered []bool
+	tracked, _ := metric.WithTracking(testutil.TestMetric(0, "tracked"), func(info telegraf.DeliveryInfo) {
+		delivered = append(delivered, info.Delivered())
+	})
+	expected := []telegraf.Metric{
+		testutil.TestMetric(1, "metric1"),
+		testutil.TestMetric(2, "metric2"),
+		testutil.TestMetric(3, "metric3"),
+	}
+	ro.AddMetric(expected[0])
+	ro.AddMetricNoCopy(tracked)
+	ro.AddMetric(expected[1])
+	ro.AddMetric(expected[2])
+
+	written := ro.BufferStats().MetricsWritten.Get()
+	agentWritten := AgentMetricsWritten.Get()
+	n, err := ro.Spool(dir)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Zero(t, ro.BufferLength())
+	require.Equal(t, []bool{false}, delivered)
+
+	// Spooled metrics are not counted as written
+	require.Equal(t, written, ro.BufferStats().MetricsWritten.Get())
+	require.Equal(t, agentWritten, AgentMetricsWritten.Get())
+	ro.Close()
+
+	// The spooled metrics are re-queued on the next start
+	output := &mockOutput{}
+	ro, err = NewRunningOutput(output, cfg, 2, 10)
+	require.NoError(t, err)
+	n, err = ro.Unspool(dir)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, 3, ro.BufferLength())
+	require.NoDirExists(t, SpoolPath(dir, cfg.ID))
+
+	require.NoError(t, ro.Write())
+	testutil.RequireMetricsEqual(t, expected, output.metrics)
+
+	// Nothing is re-queued without spool
+	n, err = ro.Unspool(dir)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRunningOutputSpoolDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+	ro, err := NewRunningOutput(&mockOutput{}, &OutputConfig{
+		Name:            "spooling",
+		ID:              "spooling-disk-output",
+		BufferStrategy:  "disk_write_through",
+		BufferDirectory: t.TempDir(),
+	}, 2, 10)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	// Disk buffers keep their metrics on their own
+	ro.AddMetric(metric.New("test", nil, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	n, err := ro.Spool(dir)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Equal(t, 1, ro.BufferLength())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestRunningOutputSpoolHybridBuffer(t *testing.T) {
+	dir := t.TempDir()
+	ro, err := NewRunningOutput(&mockOutput{}, &OutputConfig{
+		Name:            "spooling",
+		ID:              "spooling-hybrid-output",
+		BufferStrategy:  "hybrid",
+		BufferDirectory: t.TempDir(),
+	}, 2, 10)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	// Only the metrics in memory are spooled, the ones on disk are kept
+	metrics := hybridTestMetrics(3)
+	buf := ro.buffer.(*HybridBuffer)
+	buf.budget = &memoryBudget{}
+	buf.budget.setLimit(int64(lineProtocolSize(metrics[0])))
+	for _, m := range metrics {
+		ro.AddMetric(m)
+	}
+
+	n, err := ro.Spool(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 2, ro.BufferLength())
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of plugin operations
+const tracerName = "github.com/influxdata/telegraf/models"
+
+// tracer holds the tracer for the spans of plugin operations if tracing is
+// enabled.
+var tracer atomic.Pointer[trace.Tracer]
+
+// SetTracerProvider enables tracing the gather, process, push and write
+// operations of the plugins using the given provider. Passing nil disables
+// tracing.
+func SetTracerProvider(provider trace.TracerProvider) {
+	if provider == nil {
+		tracer.Store(nil)
+		return
+	}
+	t := provider.Tracer(tracerName)
+	tracer.Store(&t)
+}
+
+// startSpan starts a span for the operation of the given plugin. The returned
+// span does not record anything if tracing is disabled or the span is not
+// sampled.
+func startSpan(operation, pluginType, name, id, alias string) trace.Span {
+	t := tracer.Load()
+	if t == nil {
+		return trace.SpanFromContext(context.Background())
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("telegraf.plugin.type", pluginType),
+		attribute.String("telegraf.plugin.name", name),
+		attribute.String("telegraf.plugin.id", id),
+	}
+	if alias != "" {
+		attrs = append(attrs, attribute.String("telegraf.plugin.alias", alias))
+	}
+	_, span := (*t).Start(context.Background(), pluginType+"."+name+" "+operation,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	return span
+}
+
+// endSpan ends the span recording the number of metrics handled by the
+// operation and the error, if any.
+func endSpan(span trace.Span, metrics int64, err error) {
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attribute.Int64("telegraf.metrics.count", metrics))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestTracingOutputWrite(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer SetTracerProvider(nil)
+
+	m := &mockOutput{}
+	ro, err := NewRunningOutput(m, &OutputConfig{Name: "traced", ID: "traced-1", Alias: "alias"}, 10, 10)
+	require.NoError(t, err)
+	for _, x := range testutil.MockMetrics() {
+		ro.AddMetric(x)
+	}
+	require.NoError(t, ro.Write())
+
+	m.batchAcceptSize = -1
+	ro.AddMetric(testutil.TestMetric(1))
+	require.Error(t, ro.Write())
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "outputs.traced write", spans[0].Name)
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("telegraf.plugin.type", "outputs"),
+		attribute.String("telegraf.plugin.name", "traced"),
+		attribute.String("telegraf.plugin.id", "traced-1"),
+		attribute.String("telegraf.plugin.alias", "alias"),
+		attribute.Int64("telegraf.metrics.count", 1),
+	}, spans[0].Attributes)
+	require.Equal(t, codes.Unset, spans[0].Status.Code)
+
+	require.Equal(t, codes.Error, spans[1].Status.Code)
+	require.Len(t, spans[1].Events, 1)
+}
+
+func TestTracingInputGather(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer SetTracerProvider(nil)
+
+	ri := NewRunningInput(&gatherInput{err: errors.New("failed")}, &InputConfig{Name: "traced", ID: "traced-2"})
+	require.Error(t, ri.Gather(&makeMetricAccumulator{ri: ri}))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "inputs.traced gather", spans[0].Name)
+	require.Contains(t, spans[0].Attributes, attribute.Int64("telegraf.metrics.count", 2))
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "failed", spans[0].Status.Description)
+}
+
+func TestTracingDisabled(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	SetTracerProvider(provider)
+	SetTracerProvider(nil)
+
+	ro, err := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "untraced"}, 10, 10)
+	require.NoError(t, err)
+	ro.AddMetric(testutil.TestMetric(1))
+	require.NoError(t, ro.Write())
+	require.Empty(t, exporter.GetSpans())
+}
+
+// makeMetricAccumulator passes the metrics through the running input like the
+// agent's accumulator does.
+type makeMetricAccumulator struct {
+	testutil.Accumulator
+	ri *RunningInput
+}
+
+func (a *makeMetricAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, _ ...time.Time) {
+	if m := a.ri.MakeMetric(metric.New(measurement, tags, fields, time.Now())); m != nil {
+		a.AddMetric(m)
+	}
+}
+
+type gatherInput struct {
+	err error
+}
+
+func (*gatherInput) SampleConfig() string {
+	return ""
+}
+
+func (i *gatherInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("traced", map[string]interface{}{"value": 1}, nil)
+	acc.AddFields("traced", map[string]interface{}{"value": 2}, nil)
+	return i.err
+}
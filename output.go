@@ -14,6 +14,17 @@ type Output interface {
 	Write(metrics []Metric) error
 }
 
+// ConcurrentOutput is an Output that can be written from multiple goroutines
+// at the same time. Only outputs implementing the interface support writing
+// multiple batches in flight.
+type ConcurrentOutput interface {
+	Output
+
+	// SupportsConcurrentWrites returns true if Write may be called
+	// concurrently.
+	SupportsConcurrentWrites() bool
+}
+
 // AggregatingOutput adds aggregating functionality to an Output.  May be used
 // if the Output only accepts a fixed set of aggregations over a time period.
 // These functions may be called concurrently to the Write function.
@@ -2,9 +2,14 @@ package persister
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 )
@@ -12,7 +17,12 @@ import (
 type Persister struct {
 	Filename string
 
+	// Generations is the number of previous statefiles kept as fallback in
+	// case the current statefile is corrupt.
+	Generations int
+
 	register map[string]telegraf.StatefulPlugin
+	sync.Mutex
 }
 
 func (p *Persister) Init() error {
@@ -22,6 +32,9 @@ func (p *Persister) Init() error {
 }
 
 func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	p.Lock()
+	defer p.Unlock()
+
 	if _, found := p.register[id]; found {
 		return fmt.Errorf("plugin with ID %q already registered", id)
 	}
@@ -30,17 +43,44 @@ func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
 	return nil
 }
 
+func (p *Persister) Unregister(id string) {
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.register, id)
+}
+
+// Load restores the states of the registered plugins from the statefile. If
+// the statefile is corrupt, the previous generations are tried in order and
+// the first valid one is used.
 func (p *Persister) Load() error {
-	// Read the states from disk
-	in, err := os.ReadFile(p.Filename)
-	if err != nil {
-		return fmt.Errorf("reading states file failed: %w", err)
-	}
+	p.Lock()
+	defer p.Unlock()
 
-	// Unmarshal the id to serialized states map
 	var states map[string][]byte
-	if err := json.Unmarshal(in, &states); err != nil {
-		return fmt.Errorf("unmarshalling states failed: %w", err)
+	var errs []error
+	for i := 0; i <= p.Generations; i++ {
+		fn := p.generation(i)
+		s, err := readStates(fn)
+		if err == nil {
+			if i > 0 {
+				log.Printf("W! [persister] Restoring states from previous generation %q", fn)
+			}
+			states = s
+			break
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// Older generations cannot exist without a current statefile
+			if i == 0 {
+				return fmt.Errorf("reading states file failed: %w", err)
+			}
+			break
+		}
+		log.Printf("E! [persister] Statefile %q is corrupt: %v", fn, err)
+		errs = append(errs, err)
+	}
+	if states == nil {
+		return fmt.Errorf("no valid states file found: %w", errors.Join(errs...))
 	}
 
 	// Get the initialized state as blueprint for unmarshalling
@@ -69,7 +109,13 @@ func (p *Persister) Load() error {
 	return nil
 }
 
+// Store writes the states of the registered plugins to the statefile. The
+// file is replaced atomically and the previous statefile is kept as a
+// generation if configured.
 func (p *Persister) Store() error {
+	p.Lock()
+	defer p.Unlock()
+
 	states := make(map[string][]byte)
 
 	// Collect the states and serialize the individual data chunks
@@ -88,16 +134,113 @@ func (p *Persister) Store() error {
 		return fmt.Errorf("marshalling states failed: %w", err)
 	}
 
-	// Write the states to disk
-	f, err := os.Create(p.Filename)
+	// Write the states to a temporary file first to not corrupt the existing
+	// statefile if the write fails. The file is created with the same
+	// permissions as the statefile was created with before.
+	dir := filepath.Dir(p.Filename)
+	f, err := os.Create(p.Filename + ".tmp")
 	if err != nil {
-		return fmt.Errorf("creating states file %q failed: %w", p.Filename, err)
+		return fmt.Errorf("creating temporary states file in %q failed: %w", dir, err)
 	}
-	defer f.Close()
+	defer os.Remove(f.Name())
 
 	if _, err := f.Write(serialized); err != nil {
+		f.Close()
 		return fmt.Errorf("writing states failed: %w", err)
 	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing states failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing states file failed: %w", err)
+	}
+
+	// Shift the existing generations and replace the statefile
+	if err := p.rotate(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), p.Filename); err != nil {
+		return fmt.Errorf("replacing states file %q failed: %w", p.Filename, err)
+	}
+
+	return syncDir(dir)
+}
+
+// rotate moves the current statefile and its generations up by one,
+// dropping the oldest generation.
+func (p *Persister) rotate() error {
+	if p.Generations < 1 {
+		return nil
+	}
+
+	for i := p.Generations; i > 0; i-- {
+		src := p.generation(i - 1)
+		if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		// Keep a copy of the current statefile to always have a valid file
+		// in place in case we crash before replacing it.
+		var err error
+		if i == 1 {
+			err = copyFile(src, p.generation(i))
+		} else {
+			err = os.Rename(src, p.generation(i))
+		}
+		if err != nil {
+			return fmt.Errorf("rotating states file %q failed: %w", src, err)
+		}
+	}
 
 	return nil
 }
+
+// generation returns the filename of the given statefile generation where
+// zero is the current statefile.
+func (p *Persister) generation(i int) string {
+	if i == 0 {
+		return p.Filename
+	}
+	return p.Filename + "." + strconv.Itoa(i)
+}
+
+func readStates(fn string) (map[string][]byte, error) {
+	in, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the id to serialized states map
+	var states map[string][]byte
+	if err := json.Unmarshal(in, &states); err != nil {
+		return nil, fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+	return states, nil
+}
+
+// copyFile copies the source file keeping its permissions.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(in); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
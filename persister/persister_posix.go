@@ -0,0 +1,22 @@
+//go:build !windows
+
+package persister
+
+import (
+	"fmt"
+	"os"
+)
+
+// syncDir flushes the directory entry to make a rename durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("opening states directory failed: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing states directory failed: %w", err)
+	}
+	return nil
+}
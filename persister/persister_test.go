@@ -0,0 +1,118 @@
+package persister
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockupState struct {
+	Offset int `json:"offset"`
+}
+
+type mockupStatePlugin struct {
+	state mockupState
+}
+
+func (p *mockupStatePlugin) GetState() interface{} {
+	return p.state
+}
+
+func (p *mockupStatePlugin) SetState(state interface{}) error {
+	p.state = state.(mockupState)
+	return nil
+}
+
+func TestStoreGenerations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	plugin := &mockupStatePlugin{}
+	p := &Persister{Filename: filename, Generations: 2}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("mock", plugin))
+
+	for i := range 4 {
+		plugin.state.Offset = i
+		require.NoError(t, p.Store())
+	}
+
+	// Only the configured number of generations should be kept
+	expected := map[string]int{filename: 3, filename + ".1": 2, filename + ".2": 1}
+	for fn, offset := range expected {
+		states, err := readStates(fn)
+		require.NoError(t, err)
+		require.JSONEq(t, fmt.Sprintf(`{"offset":%d}`, offset), string(states["mock"]))
+	}
+	require.NoFileExists(t, filename+".3")
+
+	// No temporary files should be left behind
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+}
+
+func TestStorePermissions(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state.json")
+
+	p := &Persister{Filename: filename, Generations: 1}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("mock", &mockupStatePlugin{}))
+	require.NoError(t, p.Store())
+	require.NoError(t, p.Store())
+
+	// The statefile and its generations are created like any other file
+	reference, err := os.Create(filepath.Join(dir, "reference"))
+	require.NoError(t, err)
+	require.NoError(t, reference.Close())
+	expected, err := os.Stat(reference.Name())
+	require.NoError(t, err)
+	for _, fn := range []string{filename, filename + ".1"} {
+		info, err := os.Stat(fn)
+		require.NoError(t, err)
+		require.Equal(t, expected.Mode().Perm(), info.Mode().Perm(), fn)
+	}
+}
+
+func TestLoadCorruptFallback(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	plugin := &mockupStatePlugin{}
+	p := &Persister{Filename: filename, Generations: 2}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("mock", plugin))
+
+	plugin.state.Offset = 1
+	require.NoError(t, p.Store())
+	plugin.state.Offset = 2
+	require.NoError(t, p.Store())
+
+	// Corrupt the current statefile as a crash during a non-atomic write would
+	require.NoError(t, os.WriteFile(filename, []byte(`{"mock":"eyJv`), 0640))
+
+	restored := &mockupStatePlugin{}
+	p = &Persister{Filename: filename, Generations: 2}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("mock", restored))
+	require.NoError(t, p.Load())
+	require.Equal(t, 1, restored.state.Offset)
+}
+
+func TestLoadCorruptWithoutGenerations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"mock":`), 0640))
+
+	p := &Persister{Filename: filename}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("mock", &mockupStatePlugin{}))
+	require.ErrorContains(t, p.Load(), "no valid states file found")
+}
+
+func TestLoadNotExist(t *testing.T) {
+	p := &Persister{Filename: filepath.Join(t.TempDir(), "state.json"), Generations: 2}
+	require.NoError(t, p.Init())
+	require.ErrorIs(t, p.Load(), os.ErrNotExist)
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package persister
+
+// syncDir is a no-op on Windows as directories cannot be synced.
+func syncDir(string) error {
+	return nil
+}
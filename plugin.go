@@ -62,3 +62,14 @@ type StatefulPlugin interface {
 type ProbePlugin interface {
 	Probe() error
 }
+
+// SecretRefresher is an interface that input and output plugins caching
+// secrets, e.g. on Connect, can optionally implement to pick up changed
+// secrets. Telegraf calls the function if a secret referenced in the plugin's
+// settings changed. Outputs not implementing the interface are reconnected
+// and service inputs are restarted instead.
+type SecretRefresher interface {
+	// RefreshSecrets reads the secrets of the plugin again. The function is
+	// never called concurrently to gathering or writing.
+	RefreshSecrets() error
+}
@@ -1,6 +1,7 @@
 package basicstats
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 	"time"
@@ -824,3 +825,44 @@ func TestBasicStatsWithOnlyFirst(t *testing.T) {
 	}
 	acc.AssertContainsTaggedFields(t, "m1", expectedFields, expectedTags)
 }
+
+func TestBasicStatsRestoreState(t *testing.T) {
+	stats := []string{
+		"count", "min", "max", "mean", "s2", "stdev", "sum", "diff", "non_negative_diff",
+		"rate", "non_negative_rate", "percent_change", "interval", "last", "first",
+	}
+
+	// Aggregate without interruption as reference
+	reference := newBasicStats()
+	reference.Stats = stats
+	reference.Log = testutil.Logger{}
+	require.NoError(t, reference.Init())
+	reference.Add(m1)
+	reference.Add(m2)
+
+	var expected testutil.Accumulator
+	reference.Push(&expected)
+
+	// Interrupt the aggregation after the first metric
+	plugin := newBasicStats()
+	plugin.Stats = stats
+	plugin.Log = testutil.Logger{}
+	require.NoError(t, plugin.Init())
+	plugin.Add(m1)
+
+	serialized, err := json.Marshal(plugin.GetState())
+	require.NoError(t, err)
+	var state map[uint64]aggregateState
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	restored := newBasicStats()
+	restored.Stats = stats
+	restored.Log = testutil.Logger{}
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(state))
+	restored.Add(m2)
+
+	var acc testutil.Accumulator
+	restored.Push(&acc)
+	testutil.RequireMetricsEqual(t, expected.GetTelegrafMetrics(), acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
@@ -3,6 +3,7 @@ package derivative
 
 import (
 	_ "embed"
+	"fmt"
 	"strings"
 	"time"
 
@@ -36,6 +37,22 @@ type event struct {
 	time   time.Time
 }
 
+// aggregateState holds the first and last event of a series to compute the
+// derivative from, the last event is omitted if it is the same as the first
+type aggregateState struct {
+	Name     string            `json:"name"`
+	Tags     map[string]string `json:"tags"`
+	First    eventState        `json:"first"`
+	Last     *eventState       `json:"last,omitempty"`
+	RollOver uint              `json:"roll_over"`
+}
+
+// eventState is the serializable state of an event
+type eventState struct {
+	Fields map[string]float64 `json:"fields"`
+	Time   time.Time          `json:"time"`
+}
+
 func (d *Derivative) Init() error {
 	d.Suffix = strings.TrimSpace(d.Suffix)
 	d.Variable = strings.TrimSpace(d.Variable)
@@ -125,6 +142,53 @@ func (d *Derivative) Reset() {
 	}
 }
 
+func (d *Derivative) GetState() interface{} {
+	state := make(map[uint64]aggregateState, len(d.cache))
+	for id, aggregate := range d.cache {
+		s := aggregateState{
+			Name:     aggregate.name,
+			Tags:     aggregate.tags,
+			First:    eventState{Fields: aggregate.first.fields, Time: aggregate.first.time},
+			RollOver: aggregate.rollOver,
+		}
+		if aggregate.last != aggregate.first {
+			s.Last = &eventState{Fields: aggregate.last.fields, Time: aggregate.last.time}
+		}
+		state[id] = s
+	}
+	return state
+}
+
+func (d *Derivative) SetState(state interface{}) error {
+	s, ok := state.(map[uint64]aggregateState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	d.cache = make(map[uint64]*aggregate, len(s))
+	for id, as := range s {
+		first := &event{fields: as.First.Fields, time: as.First.Time}
+		if first.fields == nil {
+			first.fields = make(map[string]float64)
+		}
+		last := first
+		if as.Last != nil {
+			last = &event{fields: as.Last.Fields, time: as.Last.Time}
+			if last.fields == nil {
+				last.fields = make(map[string]float64)
+			}
+		}
+		d.cache[id] = &aggregate{
+			first:    first,
+			last:     last,
+			name:     as.Name,
+			tags:     as.Tags,
+			rollOver: as.RollOver,
+		}
+	}
+	return nil
+}
+
 func newAggregate(in telegraf.Metric) *aggregate {
 	event := newEvent(in)
 	return &aggregate{
@@ -1,6 +1,7 @@
 package derivative
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -416,3 +417,54 @@ func TestCalculatesCorrectDerivativeOnTwoConsecutivePeriods(t *testing.T) {
 		"value_rate": 2.0,
 	})
 }
+
+func TestRestoreState(t *testing.T) {
+	derivative := &Derivative{
+		Variable:    "parameter",
+		Suffix:      "_by_parameter",
+		MaxRollOver: 10,
+		Log:         testutil.Logger{},
+		cache:       make(map[uint64]*aggregate),
+	}
+	require.NoError(t, derivative.Init())
+
+	acc := testutil.Accumulator{}
+	derivative.Add(start)
+	derivative.Push(&acc)
+	derivative.Reset()
+
+	serialized, err := json.Marshal(derivative.GetState())
+	require.NoError(t, err)
+	var state map[uint64]aggregateState
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	restored := &Derivative{
+		Variable:    "parameter",
+		Suffix:      "_by_parameter",
+		MaxRollOver: 10,
+		Log:         testutil.Logger{},
+		cache:       make(map[uint64]*aggregate),
+	}
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(state))
+	for _, aggregate := range restored.cache {
+		require.Equal(t, uint(1), aggregate.rollOver)
+	}
+
+	// The restored first and last event must still be the same
+	restored.Push(&acc)
+	acc.AssertDoesNotContainMeasurement(t, "TestMetric")
+
+	restored.Add(finish)
+	restored.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"increasing_by_parameter": 100.0,
+		"decreasing_by_parameter": -10.0,
+		"unchanged_by_parameter":  0.0,
+	}
+	expectedTags := map[string]string{
+		"state": "full",
+	}
+	acc.AssertContainsTaggedFields(t, "TestMetric", expectedFields, expectedTags)
+}
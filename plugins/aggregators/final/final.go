@@ -9,6 +9,8 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializers_influx "github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
 //go:embed sample.conf
@@ -18,6 +20,7 @@ type Final struct {
 	OutputStrategy         string          `toml:"output_strategy"`
 	SeriesTimeout          config.Duration `toml:"series_timeout"`
 	KeepOriginalFieldNames bool            `toml:"keep_original_field_names"`
+	Log                    telegraf.Logger `toml:"-"`
 
 	// The last metric for all series which are active
 	metricCache map[uint64]telegraf.Metric
@@ -77,6 +80,38 @@ func (m *Final) Push(acc telegraf.Accumulator) {
 func (*Final) Reset() {
 }
 
+func (m *Final) GetState() interface{} {
+	s := &serializers_influx.Serializer{}
+	v := make([]telegraf.Metric, 0, len(m.metricCache))
+	for _, value := range m.metricCache {
+		v = append(v, value)
+	}
+	state, err := s.SerializeBatch(v)
+	if err != nil {
+		m.Log.Errorf("Failed to serialize metric batch: %v", err)
+	}
+	return state
+}
+
+func (m *Final) SetState(state interface{}) error {
+	p := &influx.Parser{}
+	if err := p.Init(); err != nil {
+		return err
+	}
+	data, ok := state.([]byte)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	metrics, err := p.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing state failed: %w", err)
+	}
+	for _, metric := range metrics {
+		m.Add(metric)
+	}
+	return nil
+}
+
 func newFinal() *Final {
 	return &Final{
 		SeriesTimeout: config.Duration(5 * time.Minute),
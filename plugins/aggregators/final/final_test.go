@@ -1,6 +1,7 @@
 package final
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -306,3 +307,55 @@ func TestKeepOriginalFieldNames(t *testing.T) {
 
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
 }
+
+func TestRestoreState(t *testing.T) {
+	final := &Final{
+		OutputStrategy: "timeout",
+		SeriesTimeout:  config.Duration(30 * time.Second),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, final.Init())
+
+	now := time.Now()
+	m1 := metric.New("m",
+		map[string]string{"foo": "bar"},
+		map[string]interface{}{"a": int64(1), "b": "value"},
+		now.Add(time.Second*-290))
+	m2 := metric.New("m",
+		map[string]string{"foo": "baz"},
+		map[string]interface{}{"a": 2.5, "b": true},
+		now.Add(time.Second*-20))
+	final.Add(m1)
+	final.Add(m2)
+
+	serialized, err := json.Marshal(final.GetState())
+	require.NoError(t, err)
+	var state []byte
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	restored := &Final{
+		OutputStrategy: "timeout",
+		SeriesTimeout:  config.Duration(30 * time.Second),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(state))
+
+	// Only the timed out series should be output
+	var acc testutil.Accumulator
+	restored.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"m",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{
+				"a_final": int64(1),
+				"b_final": "value",
+			},
+			now.Add(time.Second*-290),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, restored.metricCache, 1)
+}
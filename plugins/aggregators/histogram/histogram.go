@@ -3,6 +3,7 @@ package histogram
 
 import (
 	_ "embed"
+	"fmt"
 	"sort"
 	"strconv"
 	"time"
@@ -74,6 +75,15 @@ type groupedByCountFields struct {
 	fieldsWithCount map[string]int64
 }
 
+// histogramState holds the bucket counts of the fields of a series
+type histogramState struct {
+	Name       string             `json:"name"`
+	Tags       map[string]string  `json:"tags"`
+	Counts     map[string][]int64 `json:"counts"`
+	ExpireTime time.Time          `json:"expire_time"`
+	Updated    bool               `json:"updated"`
+}
+
 func (*Histogram) SampleConfig() string {
 	return sampleConfig
 }
@@ -156,6 +166,56 @@ func (h *Histogram) Reset() {
 	}
 }
 
+func (h *Histogram) GetState() interface{} {
+	state := make(map[uint64]histogramState, len(h.cache))
+	for id, agr := range h.cache {
+		c := make(map[string][]int64, len(agr.histogramCollection))
+		for field, counts := range agr.histogramCollection {
+			c[field] = counts
+		}
+		state[id] = histogramState{
+			Name:       agr.name,
+			Tags:       agr.tags,
+			Counts:     c,
+			ExpireTime: agr.expireTime,
+			Updated:    agr.updated,
+		}
+	}
+	return state
+}
+
+func (h *Histogram) SetState(state interface{}) error {
+	s, ok := state.(map[uint64]histogramState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	h.resetCache()
+	for id, agr := range s {
+		collection := make(map[string]counts, len(agr.Counts))
+		for field, c := range agr.Counts {
+			// Skip fields where the buckets changed as the counts cannot be
+			// mapped to the new buckets
+			buckets := h.getBuckets(agr.Name, field)
+			if buckets == nil || len(c) != len(buckets)+1 {
+				continue
+			}
+			collection[field] = c
+		}
+		if len(collection) == 0 {
+			continue
+		}
+		h.cache[id] = metricHistogramCollection{
+			histogramCollection: collection,
+			name:                agr.Name,
+			tags:                agr.Tags,
+			expireTime:          agr.ExpireTime,
+			updated:             agr.Updated,
+		}
+	}
+	return nil
+}
+
 // groupFieldsByBuckets groups fields by metric buckets which are represented as tags
 func (h *Histogram) groupFieldsByBuckets(
 	metricsWithGroupedFields *[]groupedByCountFields, name, field string, tags map[string]string, counts []int64,
@@ -1,6 +1,7 @@
 package histogram
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -435,6 +436,67 @@ func TestHistogramWithTwoPeriodsAndAllFields(t *testing.T) {
 	)
 }
 
+// TestHistogramRestoreState tests continuing the histogram with a restored state
+func TestHistogramRestoreState(t *testing.T) {
+	cfg := []bucketConfig{
+		{
+			Metric:  "first_metric_name",
+			Buckets: []float64{0.0, 10.0, 20.0, 30.0, 40.0},
+		},
+	}
+	histogram := newTestHistogram(cfg, false, true, false).(*Histogram)
+	histogram.Add(firstMetric1)
+
+	serialized, err := json.Marshal(histogram.GetState())
+	require.NoError(t, err)
+	var state map[uint64]histogramState
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	restored := newTestHistogram(cfg, false, true, false).(*Histogram)
+	require.NoError(t, restored.SetState(state))
+	restored.Add(firstMetric2)
+
+	acc := &testutil.Accumulator{}
+	restored.Push(acc)
+
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(0), "b_bucket": int64(0), "c_bucket": int64(0)}, tags{bucketRightTag: "0"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(0), "b_bucket": int64(0), "c_bucket": int64(0)}, tags{bucketRightTag: "10"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(2), "b_bucket": int64(0), "c_bucket": int64(0)}, tags{bucketRightTag: "20"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(2), "b_bucket": int64(0), "c_bucket": int64(0)}, tags{bucketRightTag: "30"})
+	assertContainsTaggedField(t, acc, "first_metric_name", fields{"a_bucket": int64(2), "b_bucket": int64(1), "c_bucket": int64(1)}, tags{bucketRightTag: "40"})
+	assertContainsTaggedField(
+		t,
+		acc,
+		"first_metric_name",
+		fields{"a_bucket": int64(2), "b_bucket": int64(1), "c_bucket": int64(1)},
+		tags{bucketRightTag: bucketPosInf},
+	)
+}
+
+// TestHistogramRestoreStateChangedBuckets tests discarding restored counts not matching the buckets
+func TestHistogramRestoreStateChangedBuckets(t *testing.T) {
+	histogram := newTestHistogram([]bucketConfig{{Metric: "first_metric_name", Buckets: []float64{0.0, 10.0}}}, false, true, false).(*Histogram)
+	histogram.Add(firstMetric1)
+
+	serialized, err := json.Marshal(histogram.GetState())
+	require.NoError(t, err)
+	var state map[uint64]histogramState
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	cfg := []bucketConfig{
+		{
+			Metric:  "first_metric_name",
+			Buckets: []float64{0.0, 10.0, 20.0, 30.0, 40.0},
+		},
+	}
+	restored := newTestHistogram(cfg, false, true, false).(*Histogram)
+	require.NoError(t, restored.SetState(state))
+	require.Empty(t, restored.cache)
+
+	// Adding metrics must not panic due to the changed number of buckets
+	restored.Add(firstMetric1)
+}
+
 // TestWrongBucketsOrder tests the calling panic with incorrect order of buckets
 func TestWrongBucketsOrder(t *testing.T) {
 	defer func() {
@@ -5,6 +5,8 @@ import (
 	_ "embed"
 	"fmt"
 
+	"github.com/caio/go-tdigest"
+
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 )
@@ -30,6 +32,20 @@ type aggregate struct {
 	tags   map[string]string
 }
 
+// aggregateState holds the quantile estimators of the fields of a series
+type aggregateState struct {
+	Name   string                `json:"name"`
+	Tags   map[string]string     `json:"tags"`
+	Fields map[string]fieldState `json:"fields"`
+}
+
+// fieldState is the serializable state of the algorithm of a field, either
+// the serialized t-digest or the values of the exact algorithms
+type fieldState struct {
+	Digest []byte    `json:"digest,omitempty"`
+	Values []float64 `json:"values,omitempty"`
+}
+
 type newAlgorithmFunc func(compression float64) (algorithm, error)
 
 func (*Quantile) SampleConfig() string {
@@ -128,6 +144,82 @@ func (q *Quantile) Reset() {
 	q.cache = make(map[uint64]aggregate)
 }
 
+func (q *Quantile) GetState() interface{} {
+	state := make(map[uint64]aggregateState, len(q.cache))
+	for id, aggregate := range q.cache {
+		fields := make(map[string]fieldState, len(aggregate.fields))
+		for k, algo := range aggregate.fields {
+			var fs fieldState
+			switch algo := algo.(type) {
+			case *tdigest.TDigest:
+				digest, err := algo.AsBytes()
+				if err != nil {
+					q.Log.Errorf("serializing field %s: %v", k, err)
+					continue
+				}
+				fs.Digest = digest
+			case *exactAlgorithmR7:
+				fs.Values = algo.xs
+			case *exactAlgorithmR8:
+				fs.Values = algo.xs
+			}
+			fields[k] = fs
+		}
+		state[id] = aggregateState{Name: aggregate.name, Tags: aggregate.tags, Fields: fields}
+	}
+	return state
+}
+
+func (q *Quantile) SetState(state interface{}) error {
+	s, ok := state.(map[uint64]aggregateState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	q.Reset()
+	for id, as := range s {
+		a := aggregate{
+			name:   as.Name,
+			tags:   as.Tags,
+			fields: make(map[string]algorithm, len(as.Fields)),
+		}
+		for k, fs := range as.Fields {
+			algo, err := q.newAlgorithm(q.Compression)
+			if err != nil {
+				return fmt.Errorf("generating algorithm %s: %w", k, err)
+			}
+
+			// Skip fields stored for a different algorithm type as the
+			// state cannot be converted
+			switch algo := algo.(type) {
+			case *tdigest.TDigest:
+				if fs.Digest == nil {
+					q.Log.Warnf("Discarding state of field %s created by a different algorithm", k)
+					continue
+				}
+				if err := algo.FromBytes(fs.Digest); err != nil {
+					return fmt.Errorf("restoring field %s: %w", k, err)
+				}
+			case *exactAlgorithmR7:
+				if fs.Digest != nil {
+					q.Log.Warnf("Discarding state of field %s created by a different algorithm", k)
+					continue
+				}
+				algo.xs = append(algo.xs, fs.Values...)
+			case *exactAlgorithmR8:
+				if fs.Digest != nil {
+					q.Log.Warnf("Discarding state of field %s created by a different algorithm", k)
+					continue
+				}
+				algo.xs = append(algo.xs, fs.Values...)
+			}
+			a.fields[k] = algo
+		}
+		q.cache[id] = a
+	}
+	return nil
+}
+
 func convert(in interface{}) (float64, bool) {
 	switch v := in.(type) {
 	case float64:
@@ -1,6 +1,7 @@
 package quantile
 
 import (
+	"encoding/json"
 	"math/rand"
 	"testing"
 	"time"
@@ -670,3 +671,73 @@ func BenchmarkDefaultExactR8100Q(b *testing.B) {
 		q.Push(&acc)
 	}
 }
+
+func TestRestoreState(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 100)
+	for i := 0; i < 100; i++ {
+		metrics = append(metrics, metric.New(
+			"test",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{
+				"a": int64(i),
+				"b": float64(i) / 100.0,
+			},
+			time.Now(),
+		))
+	}
+
+	for _, algorithm := range []string{"t-digest", "exact R7", "exact R8"} {
+		t.Run(algorithm, func(t *testing.T) {
+			// Aggregate without interruption as reference
+			reference := Quantile{Compression: 100, AlgorithmType: algorithm, Log: testutil.Logger{}}
+			require.NoError(t, reference.Init())
+			for _, m := range metrics {
+				reference.Add(m)
+			}
+			var expected testutil.Accumulator
+			reference.Push(&expected)
+
+			// Interrupt the aggregation after half of the metrics
+			q := Quantile{Compression: 100, AlgorithmType: algorithm, Log: testutil.Logger{}}
+			require.NoError(t, q.Init())
+			for _, m := range metrics[:50] {
+				q.Add(m)
+			}
+
+			serialized, err := json.Marshal(q.GetState())
+			require.NoError(t, err)
+			var state map[uint64]aggregateState
+			require.NoError(t, json.Unmarshal(serialized, &state))
+
+			restored := Quantile{Compression: 100, AlgorithmType: algorithm, Log: testutil.Logger{}}
+			require.NoError(t, restored.Init())
+			require.NoError(t, restored.SetState(state))
+			for _, m := range metrics[50:] {
+				restored.Add(m)
+			}
+			var acc testutil.Accumulator
+			restored.Push(&acc)
+
+			epsilon := cmpopts.EquateApprox(0, 1e-3)
+			testutil.RequireMetricsEqual(t, expected.GetTelegrafMetrics(), acc.GetTelegrafMetrics(), testutil.IgnoreTime(), epsilon)
+		})
+	}
+}
+
+func TestRestoreStateChangedAlgorithm(t *testing.T) {
+	q := Quantile{Compression: 100, AlgorithmType: "t-digest", Log: testutil.Logger{}}
+	require.NoError(t, q.Init())
+	q.Add(metric.New("test", map[string]string{}, map[string]interface{}{"a": 1.0}, time.Now()))
+
+	serialized, err := json.Marshal(q.GetState())
+	require.NoError(t, err)
+	var state map[uint64]aggregateState
+	require.NoError(t, json.Unmarshal(serialized, &state))
+
+	restored := Quantile{AlgorithmType: "exact R7", Log: testutil.Logger{}}
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(state))
+	for _, a := range restored.cache {
+		require.Empty(t, a.fields)
+	}
+}
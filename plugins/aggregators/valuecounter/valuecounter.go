@@ -24,6 +24,13 @@ type aggregate struct {
 	fieldCount map[string]int
 }
 
+// aggregateState holds the value counts of the fields of a series
+type aggregateState struct {
+	Name       string            `json:"name"`
+	Tags       map[string]string `json:"tags"`
+	FieldCount map[string]int    `json:"field_count"`
+}
+
 func (*ValueCounter) SampleConfig() string {
 	return sampleConfig
 }
@@ -68,6 +75,38 @@ func (vc *ValueCounter) Reset() {
 	vc.cache = make(map[uint64]aggregate)
 }
 
+func (vc *ValueCounter) GetState() interface{} {
+	state := make(map[uint64]aggregateState, len(vc.cache))
+	for id, agg := range vc.cache {
+		state[id] = aggregateState{
+			Name:       agg.name,
+			Tags:       agg.tags,
+			FieldCount: agg.fieldCount,
+		}
+	}
+	return state
+}
+
+func (vc *ValueCounter) SetState(state interface{}) error {
+	s, ok := state.(map[uint64]aggregateState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+
+	vc.Reset()
+	for id, agg := range s {
+		if agg.FieldCount == nil {
+			agg.FieldCount = make(map[string]int)
+		}
+		vc.cache[id] = aggregate{
+			name:       agg.Name,
+			tags:       agg.Tags,
+			fieldCount: agg.FieldCount,
+		}
+	}
+	return nil
+}
+
 func newValueCounter() telegraf.Aggregator {
 	vc := &ValueCounter{}
 	vc.Reset()
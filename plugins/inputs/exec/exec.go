@@ -4,6 +4,7 @@ package exec
 import (
 	"bufio"
 	"bytes"
+	"context"
 	_ "embed"
 	"errors"
 	"fmt"
@@ -52,7 +53,7 @@ type Exec struct {
 type exitCodeHandlerFunc func([]telegraf.Metric, error, []byte) []telegraf.Metric
 
 type runner interface {
-	run([]string) ([]byte, []byte, error)
+	run(context.Context, []string) ([]byte, []byte, error)
 }
 
 type commandRunner struct {
@@ -152,6 +153,12 @@ func (e *Exec) SetParser(parser telegraf.Parser) {
 }
 
 func (e *Exec) Gather(acc telegraf.Accumulator) error {
+	return e.GatherContext(context.Background(), acc)
+}
+
+// GatherContext runs the commands and terminates them once the context is
+// done, e.g. after exceeding the gather timeout of the input.
+func (e *Exec) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	commands := e.updateRunners()
 
 	var wg sync.WaitGroup
@@ -159,11 +166,11 @@ func (e *Exec) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(c []string) {
 			defer wg.Done()
-			acc.AddError(e.processCommand(acc, c))
+			acc.AddError(e.processCommand(ctx, acc, c))
 		}(item)
 	}
 	wg.Wait()
-	return nil
+	return ctx.Err()
 }
 
 func (e *Exec) updateRunners() [][]string {
@@ -195,8 +202,12 @@ func (e *Exec) updateRunners() [][]string {
 	return commands
 }
 
-func (e *Exec) processCommand(acc telegraf.Accumulator, cmd []string) error {
-	out, errBuf, runErr := e.runner.run(cmd)
+func (e *Exec) processCommand(ctx context.Context, acc telegraf.Accumulator, cmd []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	out, errBuf, runErr := e.runner.run(ctx, cmd)
 	if !e.IgnoreError && !e.parseDespiteError && runErr != nil {
 		return fmt.Errorf("exec: %w for command %q: %s", runErr, strings.Join(cmd, " "), string(errBuf))
 	}
@@ -252,6 +263,15 @@ func (e *Exec) processCommand(acc telegraf.Accumulator, cmd []string) error {
 	return nil
 }
 
+// timeoutFor returns the timeout of the command shortened to the deadline of
+// the context, if any.
+func (c *commandRunner) timeoutFor(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return min(c.timeout, time.Until(deadline))
+	}
+	return c.timeout
+}
+
 func truncate(buf *bytes.Buffer) {
 	// Limit the number of bytes.
 	didTruncate := false
@@ -7,6 +7,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -285,6 +286,34 @@ func TestExecCommandWithoutGlob(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
 }
 
+func TestGatherContextDeadline(t *testing.T) {
+	// Setup parser
+	parser := value.Parser{
+		MetricName: "metric",
+		DataType:   "string",
+	}
+	require.NoError(t, parser.Init())
+
+	// Setup plugin
+	plugin := &Exec{
+		Commands: []interface{}{"sleep 5"},
+		Timeout:  config.Duration(5 * time.Second),
+		Log:      testutil.Logger{},
+	}
+	plugin.SetParser(&parser)
+	require.NoError(t, plugin.Init())
+
+	// The command is killed once the deadline of the context passed
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	var acc testutil.Accumulator
+	start := time.Now()
+	require.ErrorIs(t, plugin.GatherContext(ctx, &acc), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
 func TestExecCommandWithoutGlobAndPath(t *testing.T) {
 	// Setup parser
 	parser := value.Parser{
@@ -888,6 +917,6 @@ type runnerMock struct {
 	err    error
 }
 
-func (r runnerMock) run([]string) (out, errout []byte, err error) {
+func (r runnerMock) run(context.Context, []string) (out, errout []byte, err error) {
 	return r.out, r.errout, r.err
 }
@@ -4,6 +4,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"os/exec"
 	"syscall"
@@ -11,7 +12,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 )
 
-func (c *commandRunner) run(splitCmd []string) (out, errout []byte, err error) {
+func (c *commandRunner) run(ctx context.Context, splitCmd []string) (out, errout []byte, err error) {
 	cmd := exec.Command(splitCmd[0], splitCmd[1:]...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
@@ -23,7 +24,7 @@ func (c *commandRunner) run(splitCmd []string) (out, errout []byte, err error) {
 	cmd.Stdout = &outbuf
 	cmd.Stderr = &stderr
 
-	runErr := internal.RunTimeout(cmd, c.timeout)
+	runErr := internal.RunTimeout(cmd, c.timeoutFor(ctx))
 
 	if stderr.Len() > 0 && !c.debug {
 		truncate(&stderr)
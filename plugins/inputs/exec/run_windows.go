@@ -4,6 +4,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -13,7 +14,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 )
 
-func (c *commandRunner) run(splitCmd []string) (out, errout []byte, err error) {
+func (c *commandRunner) run(ctx context.Context, splitCmd []string) (out, errout []byte, err error) {
 	cmd := exec.Command(splitCmd[0], splitCmd[1:]...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
@@ -27,7 +28,7 @@ func (c *commandRunner) run(splitCmd []string) (out, errout []byte, err error) {
 	cmd.Stdout = &outbuf
 	cmd.Stderr = &stderr
 
-	runErr := internal.RunTimeout(cmd, c.timeout)
+	runErr := internal.RunTimeout(cmd, c.timeoutFor(ctx))
 
 	outbuf = removeWindowsCarriageReturns(outbuf)
 	stderr = removeWindowsCarriageReturns(stderr)
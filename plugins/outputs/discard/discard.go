@@ -23,6 +23,10 @@ func (*Discard) Write([]telegraf.Metric) error {
 	return nil
 }
 
+func (*Discard) SupportsConcurrentWrites() bool {
+	return true
+}
+
 func init() {
 	outputs.Add("discard", func() telegraf.Output { return &Discard{} })
 }
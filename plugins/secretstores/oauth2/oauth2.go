@@ -167,6 +167,26 @@ func (o *OAuth2) Get(key string) ([]byte, error) {
 	return []byte(token.AccessToken), nil
 }
 
+// TTL returns the time until the token of the given key is renewed. Tokens
+// without expiry time report a zero TTL.
+func (o *OAuth2) TTL(key string) (time.Duration, error) {
+	src, found := o.sources[key]
+	if !found {
+		return 0, fmt.Errorf("token %q not found", key)
+	}
+
+	token, err := src.Token()
+	if err != nil {
+		return 0, err
+	}
+	if token.Expiry.IsZero() {
+		return 0, nil
+	}
+
+	// Expired tokens are renewed on the next access
+	return max(time.Until(token.Expiry)-time.Duration(o.ExpiryMargin), time.Second), nil
+}
+
 func (*OAuth2) Set(_, _ string) error {
 	return errors.New("not supported")
 }
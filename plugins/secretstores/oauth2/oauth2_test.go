@@ -362,3 +362,48 @@ func TestGetRefresh(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, expected[1], string(token))
 }
+
+func TestTTL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/expiring":
+				fmt.Fprint(w, `{"access_token":"MTQ0NjJkZmQ5OTM2NDE1ZTZjNGZmZjI3","token_type":"bearer","expires_in":299}`)
+			default:
+				fmt.Fprint(w, `{"access_token":"MTQ0NjJkZmQ5OTM2NDE1ZTZjNGZmZjI3","token_type":"bearer"}`)
+			}
+		}))
+	defer server.Close()
+
+	for _, path := range []string{"/expiring", "/unlimited"} {
+		plugin := &OAuth2{
+			Service:      "custom",
+			Endpoint:     server.URL + path,
+			ExpiryMargin: config.Duration(10 * time.Second),
+			TokenConfigs: []tokenConfig{
+				{
+					Key:          "test",
+					ClientID:     config.NewSecret([]byte("someone")),
+					ClientSecret: config.NewSecret([]byte("s3cr3t")),
+				},
+			},
+		}
+		require.NoError(t, plugin.Init())
+
+		ttl, err := plugin.TTL("test")
+		require.NoError(t, err)
+		if path == "/expiring" {
+			// The token is renewed the expiry margin before it expires
+			require.LessOrEqual(t, ttl, 289*time.Second)
+			require.Greater(t, ttl, 280*time.Second)
+		} else {
+			require.Zero(t, ttl)
+		}
+	}
+
+	plugin := &OAuth2{Service: "custom", Endpoint: server.URL}
+	require.NoError(t, plugin.Init())
+	_, err := plugin.TTL("foo")
+	require.ErrorContains(t, err, `token "foo" not found`)
+}
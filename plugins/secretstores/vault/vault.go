@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"time"
 
 	vault "github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/api/auth/approle"
@@ -104,6 +105,19 @@ func (v *Vault) Get(key string) ([]byte, error) {
 	return []byte(value), nil
 }
 
+// TTL returns the lease duration of the secret path. Secrets without lease,
+// e.g. of the kv-v2 engine, report a zero TTL.
+func (v *Vault) TTL(string) (time.Duration, error) {
+	secret, err := v.getSecret()
+	if err != nil {
+		return 0, fmt.Errorf("unable to read secret: %w", err)
+	}
+	if secret.Raw == nil {
+		return 0, nil
+	}
+	return time.Duration(secret.Raw.LeaseDuration) * time.Second, nil
+}
+
 func (v *Vault) List() ([]string, error) {
 	secret, err := v.getSecret()
 	if err != nil {
@@ -1,5 +1,7 @@
 package telegraf
 
+import "time"
+
 // SecretStore is an interface defining functions that a secret store plugin must satisfy
 type SecretStore interface {
 	Initializer
@@ -18,6 +20,23 @@ type SecretStore interface {
 	GetResolver(key string) (ResolveFunc, error)
 }
 
+// SecretStoreWithTTL is an optional interface for secret stores handing out
+// secrets expiring after some time, e.g. leased credentials or access tokens.
+// Telegraf checks the secret for changes once its lifetime elapsed.
+type SecretStoreWithTTL interface {
+	// TTL returns the remaining lifetime of the secret for the given key.
+	// A zero duration denotes a secret without known expiry.
+	TTL(key string) (time.Duration, error)
+}
+
+// SecretStoreNotifier is an optional interface for secret stores able to
+// detect changes of their secrets, e.g. via a watch on the backend.
+type SecretStoreNotifier interface {
+	// NotifyOnChange registers the callback to be called with the key of a
+	// secret whenever the secret changed. The callback does not block.
+	NotifyOnChange(callback func(key string))
+}
+
 // ResolveFunc is a function to resolve the secret.
 // The returned flag indicates if the resolver is static (false), i.e.
 // the secret will not change over time, or dynamic (true) to handle
@@ -0,0 +1,97 @@
+package selfstat
+
+import (
+	"slices"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in nanoseconds, of the histogram
+// buckets kept for timing stats.
+var LatencyBuckets = []int64{
+	int64(10 * time.Microsecond),
+	int64(50 * time.Microsecond),
+	int64(100 * time.Microsecond),
+	int64(500 * time.Microsecond),
+	int64(time.Millisecond),
+	int64(5 * time.Millisecond),
+	int64(10 * time.Millisecond),
+	int64(50 * time.Millisecond),
+	int64(100 * time.Millisecond),
+	int64(500 * time.Millisecond),
+	int64(time.Second),
+	int64(5 * time.Second),
+	int64(10 * time.Second),
+	int64(30 * time.Second),
+	int64(time.Minute),
+}
+
+// Histogram is the distribution of all values added to a timing stat since
+// its registration.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets and Counts the cumulative
+	// number of values less than or equal to the bound.
+	Bounds []int64
+	Counts []uint64
+
+	// Count is the total number of values and Sum the sum of all values.
+	Count uint64
+	Sum   int64
+}
+
+// HistogramStat is a stat keeping the distribution of its values in addition
+// to the value reported by Get. Reading the histogram does not reset the
+// stat.
+type HistogramStat interface {
+	Stat
+
+	// Histogram returns the distribution of the values added to the stat.
+	Histogram() Histogram
+}
+
+// Stats returns all registered stats.
+func Stats() []Stat {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	stats := make([]Stat, 0, len(registry.stats))
+	for _, fields := range registry.stats {
+		for _, s := range fields {
+			stats = append(stats, s)
+		}
+	}
+	return stats
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     int64
+}
+
+func (h *histogram) observe(v int64) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(LatencyBuckets))
+	}
+	if i, _ := slices.BinarySearch(LatencyBuckets, v); i < len(h.buckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) snapshot() Histogram {
+	counts := make([]uint64, len(LatencyBuckets))
+	var cumulative uint64
+	for i := range counts {
+		if i < len(h.buckets) {
+			cumulative += h.buckets[i]
+		}
+		counts[i] = cumulative
+	}
+	return Histogram{
+		Bounds: slices.Clone(LatencyBuckets),
+		Counts: counts,
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
@@ -2,6 +2,7 @@ package selfstat
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -95,6 +96,29 @@ func TestRegisterTimingAndIncrAndSet(t *testing.T) {
 	require.Equal(t, "internal_test", foo.Name())
 }
 
+func TestTimingHistogram(t *testing.T) {
+	defer testCleanup()
+	s := RegisterTiming("test", "test_field_ns", map[string]string{"test": "foo"})
+	s.Incr(int64(time.Millisecond))
+	s.Incr(int64(2 * time.Millisecond))
+	s.Incr(int64(2 * time.Minute))
+	require.Equal(t, int64(40001*time.Millisecond), s.Get())
+
+	// The histogram keeps all values independent of reading the stat
+	hs, ok := s.(HistogramStat)
+	require.True(t, ok)
+	h := hs.Histogram()
+	require.Equal(t, LatencyBuckets, h.Bounds)
+	require.Equal(t, uint64(3), h.Count)
+	require.Equal(t, int64(3*time.Millisecond+2*time.Minute), h.Sum)
+	require.Equal(t, uint64(0), h.Counts[3])
+	require.Equal(t, uint64(1), h.Counts[4])
+	require.Equal(t, uint64(2), h.Counts[5])
+	require.Equal(t, uint64(2), h.Counts[len(h.Counts)-1])
+
+	require.Len(t, Stats(), 1)
+}
+
 func TestStatKeyConsistency(t *testing.T) {
 	lhs := key("internal_stats", map[string]string{
 		"foo":   "bar",
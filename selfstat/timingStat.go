@@ -11,6 +11,7 @@ type timingStat struct {
 	v           int64
 	prev        int64
 	count       int64
+	hist        histogram
 	mu          sync.Mutex
 }
 
@@ -18,6 +19,7 @@ func (s *timingStat) Incr(v int64) {
 	s.mu.Lock()
 	s.v += v
 	s.count++
+	s.hist.observe(v)
 	s.mu.Unlock()
 }
 
@@ -39,6 +41,13 @@ func (s *timingStat) Get() int64 {
 	return avg
 }
 
+// Histogram returns the distribution of all timings received.
+func (s *timingStat) Histogram() Histogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hist.snapshot()
+}
+
 func (s *timingStat) Name() string {
 	return s.measurement
 }